@@ -0,0 +1,369 @@
+package circuit_rate
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/garyburd/redigo/redis"
+	"github.com/starMoonZhao/go_gateway/public"
+	"log"
+	"sync"
+	"time"
+)
+
+// 熔断器状态
+const (
+	CircuitStateClosed   = "closed"    //关闭：正常放行请求
+	CircuitStateOpen     = "open"      //打开：直接拒绝请求
+	CircuitStateHalfOpen = "half_open" //半开：放行少量探测请求 探测成功后关闭 失败后重新打开
+)
+
+// 熔断器配置
+type CircuitBreakerConf struct {
+	ErrorPercent        int           //窗口内错误率达到该百分比时熔断 0表示不按错误率熔断
+	ConsecutiveFailures int           //连续失败达到该次数时熔断 0表示不按连续失败熔断
+	MinRequests         int           //窗口内请求数达到该值后才按错误率判断
+	Window              time.Duration //错误率统计窗口
+	OpenTimeout         time.Duration //熔断持续时间 之后进入半开状态
+	HalfOpenRequests    int           //半开状态放行的探测请求数 全部成功后关闭熔断
+}
+
+// 是否开启熔断
+func (conf CircuitBreakerConf) Enabled() bool {
+	return conf.ErrorPercent > 0 || conf.ConsecutiveFailures > 0
+}
+
+// 熔断器状态快照 写入redis供管理后台展示
+type CircuitBreakerStat struct {
+	Node                string `json:"node"`
+	State               string `json:"state"`
+	Requests            int64  `json:"requests"`
+	Failures            int64  `json:"failures"`
+	ConsecutiveFailures int64  `json:"consecutive_failures"`
+	UpdateAt            int64  `json:"update_at"`
+}
+
+// 熔断器：按服务或上游节点统计请求结果 在closed、open、half_open三种状态间转换
+type CircuitBreaker struct {
+	ServiceName string
+	Node        string //上游节点地址 为空表示服务级熔断器
+
+	conf                CircuitBreakerConf
+	state               string
+	windowStart         time.Time
+	requests            int64
+	failures            int64
+	consecutiveFailures int64
+	stateAt             time.Time //进入当前状态的时间
+	probes              int       //半开状态已放行的探测请求数
+	probeSuccesses      int       //半开状态探测成功数
+	forced              bool      //手动熔断 保持打开状态直到手动恢复 不随熔断持续时间进入半开状态
+	locker              sync.Mutex
+}
+
+func newCircuitBreaker(serviceName, node string, conf CircuitBreakerConf) *CircuitBreaker {
+	now := time.Now()
+	return &CircuitBreaker{
+		ServiceName: serviceName,
+		Node:        node,
+		conf:        conf,
+		state:       CircuitStateClosed,
+		windowStart: now,
+		stateAt:     now,
+	}
+}
+
+// 是否放行请求
+func (breaker *CircuitBreaker) Allow() bool {
+	breaker.locker.Lock()
+	defer breaker.locker.Unlock()
+	if breaker.forced {
+		return false
+	}
+	now := time.Now()
+	switch breaker.state {
+	case CircuitStateOpen:
+		if now.Sub(breaker.stateAt) < breaker.conf.OpenTimeout {
+			return false
+		}
+		breaker.setState(CircuitStateHalfOpen, now)
+		breaker.probes = 1
+		return true
+	case CircuitStateHalfOpen:
+		//探测请求未上报结果(如请求未发出)时 超过熔断持续时间后重新放行探测
+		if breaker.probes < breaker.conf.HalfOpenRequests || now.Sub(breaker.stateAt) >= breaker.conf.OpenTimeout {
+			if breaker.probes >= breaker.conf.HalfOpenRequests {
+				breaker.stateAt = now
+				breaker.probes = 0
+			}
+			breaker.probes++
+			return true
+		}
+		return false
+	}
+	return true
+}
+
+// 上报请求结果
+func (breaker *CircuitBreaker) Report(success bool) {
+	breaker.locker.Lock()
+	defer breaker.locker.Unlock()
+	if breaker.forced {
+		return
+	}
+	now := time.Now()
+	switch breaker.state {
+	case CircuitStateOpen:
+		//熔断前已放行的请求 结果不再统计
+		return
+	case CircuitStateHalfOpen:
+		if !success {
+			breaker.setState(CircuitStateOpen, now)
+			return
+		}
+		breaker.probeSuccesses++
+		if breaker.probeSuccesses >= breaker.conf.HalfOpenRequests {
+			breaker.setState(CircuitStateClosed, now)
+		}
+		return
+	}
+
+	//关闭状态 按统计窗口计数
+	if now.Sub(breaker.windowStart) >= breaker.conf.Window {
+		breaker.windowStart = now
+		breaker.requests = 0
+		breaker.failures = 0
+	}
+	breaker.requests++
+	if success {
+		breaker.consecutiveFailures = 0
+		return
+	}
+	breaker.failures++
+	breaker.consecutiveFailures++
+	if breaker.conf.ConsecutiveFailures > 0 && breaker.consecutiveFailures >= int64(breaker.conf.ConsecutiveFailures) {
+		breaker.setState(CircuitStateOpen, now)
+		return
+	}
+	if breaker.conf.ErrorPercent > 0 && breaker.requests >= int64(breaker.conf.MinRequests) &&
+		breaker.failures*100 >= int64(breaker.conf.ErrorPercent)*breaker.requests {
+		breaker.setState(CircuitStateOpen, now)
+	}
+}
+
+// 当前状态
+func (breaker *CircuitBreaker) State() string {
+	breaker.locker.Lock()
+	defer breaker.locker.Unlock()
+	if breaker.state == CircuitStateOpen && !breaker.forced && time.Since(breaker.stateAt) >= breaker.conf.OpenTimeout {
+		return CircuitStateHalfOpen
+	}
+	return breaker.state
+}
+
+// 强制设置熔断器状态 用于管理后台手动熔断或恢复
+// 手动熔断后保持打开状态 直到手动恢复为closed
+func (breaker *CircuitBreaker) ForceState(state string) {
+	breaker.locker.Lock()
+	defer breaker.locker.Unlock()
+	breaker.forced = state == CircuitStateOpen
+	breaker.setState(state, time.Now())
+}
+
+// 切换状态并重置计数 状态快照异步写入redis
+func (breaker *CircuitBreaker) setState(state string, now time.Time) {
+	stat := &CircuitBreakerStat{
+		Node:                breaker.Node,
+		State:               state,
+		Requests:            breaker.requests,
+		Failures:            breaker.failures,
+		ConsecutiveFailures: breaker.consecutiveFailures,
+		UpdateAt:            now.Unix(),
+	}
+	breaker.state = state
+	breaker.stateAt = now
+	breaker.probes = 0
+	breaker.probeSuccesses = 0
+	if state == CircuitStateClosed {
+		breaker.windowStart = now
+		breaker.requests = 0
+		breaker.failures = 0
+		breaker.consecutiveFailures = 0
+	}
+	go saveCircuitBreakerStat(breaker.ServiceName, stat)
+}
+
+// 熔断器在redis中的状态快照key 每个服务一个hash 字段为节点地址 服务级熔断器字段为service
+func CircuitBreakerStatKey(serviceName string) string {
+	return fmt.Sprintf("%s_%s", public.RedisCircuitBreakerKey, serviceName)
+}
+
+func saveCircuitBreakerStat(serviceName string, stat *CircuitBreakerStat) {
+	field := stat.Node
+	if field == "" {
+		field = public.CircuitBreakerServiceField
+	}
+	data, _ := json.Marshal(stat)
+	if err := RedisConfPipeline(func(c redis.Conn) {
+		c.Send("HSET", CircuitBreakerStatKey(serviceName), field, data)
+		c.Send("EXPIRE", CircuitBreakerStatKey(serviceName), 60*60*24*2)
+	}); err != nil {
+		log.Println("save circuit breaker stat err:", err)
+	}
+}
+
+// 读取服务所有熔断器的状态快照
+func GetCircuitBreakerStats(serviceName string) ([]*CircuitBreakerStat, error) {
+	values, err := redis.StringMap(RedisConfDo("HGETALL", CircuitBreakerStatKey(serviceName)))
+	if err != nil {
+		return nil, err
+	}
+	statList := []*CircuitBreakerStat{}
+	for _, value := range values {
+		stat := &CircuitBreakerStat{}
+		if err := json.Unmarshal([]byte(value), stat); err != nil {
+			continue
+		}
+		statList = append(statList, stat)
+	}
+	return statList, nil
+}
+
+var CircuitBreakerHandler *CircuitBreakerManager
+
+// 存储所有服务及上游节点的熔断器 serviceName_node->CircuitBreaker
+type CircuitBreakerManager struct {
+	CircuitBreakerMap map[string]*CircuitBreaker
+	ForcedMap         map[string]bool //手动熔断的服务 之后创建的熔断器同样保持打开状态
+	Locker            sync.RWMutex
+}
+
+func NewCircuitBreakerManager() *CircuitBreakerManager {
+	return &CircuitBreakerManager{
+		CircuitBreakerMap: map[string]*CircuitBreaker{},
+		ForcedMap:         map[string]bool{},
+		Locker:            sync.RWMutex{},
+	}
+}
+
+func init() {
+	//启动时初始化CircuitBreakerHandler
+	CircuitBreakerHandler = NewCircuitBreakerManager()
+}
+
+// 获取服务或上游节点的熔断器 node为空表示服务级熔断器
+func (m *CircuitBreakerManager) GetCircuitBreaker(serviceName, node string, conf CircuitBreakerConf) *CircuitBreaker {
+	conf = fillCircuitBreakerConf(conf)
+	id := serviceName + "_" + node
+	m.Locker.RLock()
+	breaker, ok := m.CircuitBreakerMap[id]
+	m.Locker.RUnlock()
+	if ok {
+		//服务熔断配置热加载后 同步更新熔断器的配置
+		breaker.locker.Lock()
+		breaker.conf = conf
+		breaker.locker.Unlock()
+		return breaker
+	}
+
+	m.Locker.Lock()
+	defer m.Locker.Unlock()
+	//并发创建时以先存入的熔断器为准
+	if breaker, ok := m.CircuitBreakerMap[id]; ok {
+		return breaker
+	}
+	breaker = newCircuitBreaker(serviceName, node, conf)
+	if m.ForcedMap[serviceName] {
+		breaker.forced = true
+		breaker.state = CircuitStateOpen
+	}
+	m.CircuitBreakerMap[id] = breaker
+	return breaker
+}
+
+// 强制设置服务及其所有上游节点熔断器的状态 只能设置为closed或open
+// 设置为open时保持手动熔断 直到设置为closed
+func (m *CircuitBreakerManager) ForceState(serviceName, state string) {
+	if state != CircuitStateClosed && state != CircuitStateOpen {
+		return
+	}
+	m.Locker.Lock()
+	defer m.Locker.Unlock()
+	if state == CircuitStateOpen {
+		m.ForcedMap[serviceName] = true
+	} else {
+		delete(m.ForcedMap, serviceName)
+	}
+	for _, breaker := range m.CircuitBreakerMap {
+		if breaker.ServiceName == serviceName {
+			breaker.ForceState(state)
+		}
+	}
+}
+
+// 从redis同步手动熔断的服务 代理节点启动及定时调用 补齐重启或未收到的配置变更通知
+func (m *CircuitBreakerManager) SyncForcedState() error {
+	forcedList, err := GetForcedServices()
+	if err != nil {
+		return err
+	}
+	forcedMap := map[string]bool{}
+	for _, serviceName := range forcedList {
+		forcedMap[serviceName] = true
+	}
+	m.Locker.RLock()
+	changed := map[string]string{}
+	for serviceName := range forcedMap {
+		if !m.ForcedMap[serviceName] {
+			changed[serviceName] = CircuitStateOpen
+		}
+	}
+	for serviceName := range m.ForcedMap {
+		if !forcedMap[serviceName] {
+			changed[serviceName] = CircuitStateClosed
+		}
+	}
+	m.Locker.RUnlock()
+	for serviceName, state := range changed {
+		m.ForceState(serviceName, state)
+	}
+	return nil
+}
+
+// 保存服务的手动熔断状态 open时记录 closed时清除 由管理后台调用
+func SaveForcedState(serviceName, state string) error {
+	if state == CircuitStateOpen {
+		_, err := RedisConfDo("HSET", public.RedisCircuitForcedKey, serviceName, state)
+		return err
+	}
+	_, err := RedisConfDo("HDEL", public.RedisCircuitForcedKey, serviceName)
+	return err
+}
+
+// 读取所有手动熔断的服务
+func GetForcedServices() ([]string, error) {
+	values, err := redis.StringMap(RedisConfDo("HGETALL", public.RedisCircuitForcedKey))
+	if err != nil {
+		return nil, err
+	}
+	serviceList := []string{}
+	for serviceName, state := range values {
+		if state == CircuitStateOpen {
+			serviceList = append(serviceList, serviceName)
+		}
+	}
+	return serviceList, nil
+}
+
+// 填充熔断器配置默认值
+func fillCircuitBreakerConf(conf CircuitBreakerConf) CircuitBreakerConf {
+	if conf.Window <= 0 {
+		conf.Window = public.CircuitDefaultWindow
+	}
+	if conf.OpenTimeout <= 0 {
+		conf.OpenTimeout = public.CircuitDefaultOpenTimeout
+	}
+	if conf.HalfOpenRequests <= 0 {
+		conf.HalfOpenRequests = 1
+	}
+	return conf
+}
@@ -37,6 +37,11 @@ func (f *FlowLimiter) GetFlowLimiter(id string, qps int) (*rate.Limiter, error)
 	//step1:查询FlowLimiterSlice中是否已存在对应对象的限流器
 	for _, flItem := range f.FlowLimiterSlice {
 		if flItem.ID == id {
+			//服务限流配置热加载后 同步更新限流器的速率
+			if flItem.Limiter.Limit() != rate.Limit(qps) {
+				flItem.Limiter.SetLimit(rate.Limit(qps))
+				flItem.Limiter.SetBurst(qps * 3)
+			}
 			return flItem.Limiter, nil
 		}
 	}
@@ -130,6 +130,9 @@ func (appController *APPController) APPDelete(c *gin.Context) {
 		return
 	}
 
+	//通知所有代理节点同步租户变更
+	publishConfigChange(c, public.ConfigChangeTypeApp, app.APPID)
+
 	middleware.ResponseSuccess(c, "")
 }
 
@@ -192,6 +195,9 @@ func (appController *APPController) APPAdd(c *gin.Context) {
 	//提交事务
 	tx.Commit()
 
+	//通知所有代理节点同步租户变更
+	publishConfigChange(c, public.ConfigChangeTypeApp, app.APPID)
+
 	middleware.ResponseSuccess(c, app.ID)
 }
 
@@ -254,6 +260,9 @@ func (appController *APPController) APPUpdate(c *gin.Context) {
 	//提交事务
 	tx.Commit()
 
+	//通知所有代理节点同步租户变更
+	publishConfigChange(c, public.ConfigChangeTypeApp, app.APPID)
+
 	middleware.ResponseSuccess(c, app.ID)
 }
 
@@ -0,0 +1,147 @@
+package controller
+
+import (
+	"encoding/json"
+	"github.com/e421083458/golang_common/lib"
+	"github.com/gin-gonic/gin"
+	"github.com/pkg/errors"
+	"github.com/starMoonZhao/go_gateway/dao"
+	"github.com/starMoonZhao/go_gateway/dto"
+	"github.com/starMoonZhao/go_gateway/middleware"
+	"github.com/starMoonZhao/go_gateway/public"
+)
+
+type ConfigController struct {
+}
+
+func ConfigRegister(group *gin.RouterGroup) {
+	configController := &ConfigController{}
+	//注册路由
+	group.GET("/config_export", configController.ConfigExport)
+	group.POST("/config_import", configController.ConfigImport)
+}
+
+// ConfigExport godoc
+// @Summary 配置导出
+// @Description 导出全部服务及租户为配置包
+// @Tags 配置管理
+// @ID /config/config_export
+// @Accept  json
+// @Produce  json
+// @Success 200 {object} middleware.Response{data=dao.ConfigBundle} "success"
+// @Router /config/config_export [get]
+func (configController *ConfigController) ConfigExport(c *gin.Context) {
+	//获取数据库连接池
+	tx, err := lib.GetGormPool("default")
+	if err != nil {
+		middleware.ResponseError(c, 7001, err)
+		return
+	}
+
+	//查询全部服务及租户
+	bundle, err := dao.ExportConfigBundle(c, tx)
+	if err != nil {
+		middleware.ResponseError(c, 7002, err)
+		return
+	}
+
+	middleware.ResponseSuccess(c, bundle)
+}
+
+// ConfigImport godoc
+// @Summary 配置导入
+// @Description 导入配置包 按service_name/app_id新增或更新 试运行时只输出变更不保存
+// @Tags 配置管理
+// @ID /config/config_import
+// @Accept  json
+// @Produce  json
+// @Param body body dto.ConfigImportInput true "body"
+// @Success 200 {object} middleware.Response{data=dto.ConfigImportOutput} "success"
+// @Router /config/config_import [post]
+func (configController *ConfigController) ConfigImport(c *gin.Context) {
+	configImportInput := &dto.ConfigImportInput{}
+	if err := configImportInput.BindValidParam(c); err != nil {
+		middleware.ResponseError(c, 7011, err)
+		return
+	}
+
+	//解析并校验配置包
+	bundle := &dao.ConfigBundle{}
+	if err := json.Unmarshal(configImportInput.Bundle, bundle); err != nil {
+		middleware.ResponseError(c, 7012, err)
+		return
+	}
+	if err := bundle.Valid(); err != nil {
+		middleware.ResponseError(c, 7013, err)
+		return
+	}
+
+	//获取数据库连接池
+	tx, err := lib.GetGormPool("default")
+	if err != nil {
+		middleware.ResponseError(c, 7014, err)
+		return
+	}
+
+	//开启事务 试运行时同样执行全部变更 以便发现冲突 最后回滚事务
+	tx = tx.Begin()
+	out, err := dao.ImportConfigBundle(c, tx, bundle, configImportInput.Prune == 1, adminUserName(c))
+	if err != nil {
+		tx.Rollback()
+		middleware.ResponseError(c, 7015, err)
+		return
+	}
+
+	//导入后灰度路由规则的目标服务或分组需存在 目标服务可以在同一个配置包中导入
+	for _, serviceDetail := range bundle.Service {
+		if serviceDetail.Info.LoadType != public.LoadTypeHTTP {
+			continue
+		}
+		if err := dao.ValidCanaryTarget(c, tx, serviceDetail.Info.ServiceName, serviceDetail.HTTPRule.CanaryRule, serviceDetail.LoadBalance.GroupWeight); err != nil {
+			tx.Rollback()
+			middleware.ResponseError(c, 7017, errors.Wrapf(err, "服务%s", serviceDetail.Info.ServiceName))
+			return
+		}
+	}
+
+	//查询新增或更新的http服务与其他服务的路由冲突 随导入结果一起返回
+	out.RouteConflict = map[string][]dto.HTTPRouteConflictItem{}
+	for _, serviceName := range append(append([]string{}, out.Service.Create...), out.Service.Update...) {
+		serviceInfo := &dao.ServiceInfo{ServiceName: serviceName}
+		if err := serviceInfo.Find(c, tx); err != nil {
+			tx.Rollback()
+			middleware.ResponseError(c, 7016, err)
+			return
+		}
+		conflicts, err := serviceInfo.HTTPRouteConflicts(c, tx)
+		if err != nil {
+			tx.Rollback()
+			middleware.ResponseError(c, 7016, err)
+			return
+		}
+		if len(conflicts) > 0 {
+			out.RouteConflict[serviceName] = conflicts
+		}
+	}
+	out.DryRun = configImportInput.DryRun == 1
+	if out.DryRun {
+		tx.Rollback()
+		middleware.ResponseSuccess(c, out)
+		return
+	}
+
+	//提交事务
+	tx.Commit()
+
+	//通知所有代理节点同步服务及租户变更
+	serviceNames := append(append(append([]string{}, out.Service.Create...), out.Service.Update...), out.Service.Delete...)
+	for _, serviceName := range serviceNames {
+		publishConfigChange(c, public.ConfigChangeTypeService, serviceName)
+	}
+	appIDs := append(append(append([]string{}, out.App.Create...), out.App.Update...), out.App.Delete...)
+	for _, appID := range appIDs {
+		publishConfigChange(c, public.ConfigChangeTypeApp, appID)
+	}
+
+	middleware.ResponseSuccess(c, out)
+}
@@ -1,8 +1,10 @@
 package controller
 
 import (
+	"encoding/json"
 	"fmt"
 	"github.com/e421083458/golang_common/lib"
+	"github.com/gin-gonic/contrib/sessions"
 	"github.com/gin-gonic/gin"
 	"github.com/pkg/errors"
 	"github.com/starMoonZhao/go_gateway/circuit_rate"
@@ -10,6 +12,8 @@ import (
 	"github.com/starMoonZhao/go_gateway/dto"
 	"github.com/starMoonZhao/go_gateway/middleware"
 	"github.com/starMoonZhao/go_gateway/public"
+	"github.com/starMoonZhao/go_gateway/reverse_proxy/load_balance"
+	"sort"
 	"strings"
 	"time"
 )
@@ -24,6 +28,17 @@ func ServiceRegister(group *gin.RouterGroup) {
 	group.DELETE("/service_delete", serviceController.ServiceDelete)
 	group.GET("/service_detail", serviceController.ServiceDetail)
 	group.GET("/service_stat", serviceController.ServiceStat)
+	group.POST("/service_group_weight", serviceController.ServiceGroupWeight)
+	group.GET("/service_circuit", serviceController.ServiceCircuit)
+	group.POST("/service_circuit_update", serviceController.ServiceCircuitUpdate)
+	group.POST("/service_circuit_state", serviceController.ServiceCircuitState)
+	group.GET("/service_health_check", serviceController.ServiceHealthCheck)
+	group.POST("/service_health_check_update", serviceController.ServiceHealthCheckUpdate)
+	group.GET("/service_balance_score", serviceController.ServiceBalanceScore)
+	group.GET("/service_health", serviceController.ServiceHealth)
+	group.GET("/service_route_conflict", serviceController.ServiceRouteConflict)
+	group.POST("/service_node_drain", serviceController.ServiceNodeDrain)
+	group.POST("/service_node_enable", serviceController.ServiceNodeEnable)
 
 	group.POST("/service_add_http", serviceController.ServiceAddHTTP)
 	group.PUT("/service_update_http", serviceController.ServiceUpdateHTTP)
@@ -33,6 +48,10 @@ func ServiceRegister(group *gin.RouterGroup) {
 
 	group.POST("/service_add_grpc", serviceController.ServiceAddGRPC)
 	group.PUT("/service_update_grpc", serviceController.ServiceUpdateGRPC)
+
+	group.GET("/service_version_list", serviceController.ServiceVersionList)
+	group.GET("/service_version_diff", serviceController.ServiceVersionDiff)
+	group.POST("/service_version_rollback", serviceController.ServiceVersionRollback)
 }
 
 // ServiceList godoc
@@ -101,9 +120,15 @@ func (serviceController *ServiceController) ServiceList(c *gin.Context) {
 			serviceAddr = fmt.Sprintf("%s:%s%s", clusterIP, clusterSSLPort, serviceDetail.HTTPRule.Rule)
 		}
 		if serviceDetail.Info.LoadType == public.LoadTypeHTTP &&
-			serviceDetail.HTTPRule.RuleType == public.HTTPRuleTypeDomain {
+			(serviceDetail.HTTPRule.RuleType == public.HTTPRuleTypeDomain ||
+				serviceDetail.HTTPRule.RuleType == public.HTTPRuleTypeHostPrefix ||
+				serviceDetail.HTTPRule.RuleType == public.HTTPRuleTypeWildcardDomain) {
 			serviceAddr = serviceDetail.HTTPRule.Rule
 		}
+		if serviceDetail.Info.LoadType == public.LoadTypeHTTP &&
+			serviceDetail.HTTPRule.RuleType == public.HTTPRuleTypeRegexURL {
+			serviceAddr = fmt.Sprintf("%s:%s ~ %s", clusterIP, clusterPort, serviceDetail.HTTPRule.Rule)
+		}
 		if serviceDetail.Info.LoadType == public.LoadTypeTCP {
 			serviceAddr = fmt.Sprintf("%s:%d", clusterIP, serviceDetail.TCPRule.Port)
 		}
@@ -173,12 +198,27 @@ func (serviceController *ServiceController) ServiceDelete(c *gin.Context) {
 		return
 	}
 
-	//删除服务
+	//开启事务
+	tx = tx.Begin()
+
+	//删除服务 同时递增服务配置修订号
 	serviceInfo.IsDelete = 1
 	if err := serviceInfo.Save(c, tx); err != nil {
+		tx.Rollback()
 		middleware.ResponseError(c, 3024, err)
 		return
 	}
+	if err := dao.BumpServiceRevision(c, tx); err != nil {
+		tx.Rollback()
+		middleware.ResponseError(c, 3025, err)
+		return
+	}
+
+	//提交事务
+	tx.Commit()
+
+	//通知所有代理节点同步服务变更
+	publishConfigChange(c, public.ConfigChangeTypeService, serviceInfo.ServiceName)
 
 	middleware.ResponseSuccess(c, "")
 }
@@ -230,6 +270,13 @@ func (serviceController *ServiceController) ServiceAddHTTP(c *gin.Context) {
 		return
 	}
 
+	//灰度路由规则的目标服务或分组需存在
+	if err := dao.ValidCanaryTarget(c, tx, serviceAddHTTPInput.ServiceName, serviceAddHTTPInput.CanaryRule, serviceAddHTTPInput.GroupWeight); err != nil {
+		tx.Rollback()
+		middleware.ResponseError(c, 3030, err)
+		return
+	}
+
 	//保存服务基本信息
 	serviceInfo.ServiceDesc = serviceAddHTTPInput.ServiceDesc
 	if err := serviceInfo.Save(c, tx); err != nil {
@@ -246,6 +293,13 @@ func (serviceController *ServiceController) ServiceAddHTTP(c *gin.Context) {
 	httpRule.NeedWebsocket = serviceAddHTTPInput.NeedWebsocket
 	httpRule.UrlRewrite = serviceAddHTTPInput.UrlRewrite
 	httpRule.HeaderTransfer = serviceAddHTTPInput.HeaderTransfer
+	httpRule.Priority = serviceAddHTTPInput.Priority
+	httpRule.Methods = serviceAddHTTPInput.Methods
+	httpRule.CanaryRule = serviceAddHTTPInput.CanaryRule
+	httpRule.MirrorService = serviceAddHTTPInput.MirrorService
+	httpRule.MirrorPercent = serviceAddHTTPInput.MirrorPercent
+	httpRule.MirrorTimeout = serviceAddHTTPInput.MirrorTimeout
+	httpRule.MirrorBodyLimit = serviceAddHTTPInput.MirrorBodyLimit
 	if err := httpRule.Save(c, tx); err != nil {
 		tx.Rollback()
 		middleware.ResponseError(c, 3036, err)
@@ -277,6 +331,21 @@ func (serviceController *ServiceController) ServiceAddHTTP(c *gin.Context) {
 		UpstreamHeaderTimeout:  serviceAddHTTPInput.UpstreamHeaderTimeout,
 		UpstreamIdleTimeout:    serviceAddHTTPInput.UpstreamIdleTimeout,
 		UpstreamMaxIdle:        serviceAddHTTPInput.UpstreamMaxIdle,
+		GroupList:              serviceAddHTTPInput.GroupList,
+		GroupWeight:            serviceAddHTTPInput.GroupWeight,
+		GroupSticky:            serviceAddHTTPInput.GroupSticky,
+		RetryCount:             serviceAddHTTPInput.RetryCount,
+		RetryBackoff:           serviceAddHTTPInput.RetryBackoff,
+		RetryBudget:            serviceAddHTTPInput.RetryBudget,
+		OutlierFailures:        serviceAddHTTPInput.OutlierFailures,
+		OutlierEjectTime:       serviceAddHTTPInput.OutlierEjectTime,
+		OutlierMaxPercent:      serviceAddHTTPInput.OutlierMaxPercent,
+		HashKey:                serviceAddHTTPInput.HashKey,
+		HashReplicas:           serviceAddHTTPInput.HashReplicas,
+		HashLoadFactor:         serviceAddHTTPInput.HashLoadFactor,
+		SlowStart:              serviceAddHTTPInput.SlowStart,
+		StickyCookie:           serviceAddHTTPInput.StickyCookie,
+		StickyMaxAge:           serviceAddHTTPInput.StickyMaxAge,
 	}
 	if err := loadBalance.Save(c, tx); err != nil {
 		tx.Rollback()
@@ -284,9 +353,19 @@ func (serviceController *ServiceController) ServiceAddHTTP(c *gin.Context) {
 		return
 	}
 
+	//保存服务配置版本快照
+	if _, err := dao.SaveServiceVersion(c, tx, serviceInfo, adminUserName(c), ""); err != nil {
+		tx.Rollback()
+		middleware.ResponseError(c, 3039, err)
+		return
+	}
+
 	//提交事务
 	tx.Commit()
 
+	//通知所有代理节点同步服务变更
+	publishConfigChange(c, public.ConfigChangeTypeService, serviceInfo.ServiceName)
+
 	middleware.ResponseSuccess(c, serviceInfo.ID)
 }
 
@@ -337,6 +416,13 @@ func (serviceController *ServiceController) ServiceUpdateHTTP(c *gin.Context) {
 		return
 	}
 
+	//灰度路由规则的目标服务或分组需存在
+	if err := dao.ValidCanaryTarget(c, tx, serviceInfo.ServiceName, serviceUpdateHTTPInput.CanaryRule, serviceUpdateHTTPInput.GroupWeight); err != nil {
+		tx.Rollback()
+		middleware.ResponseError(c, 3040, err)
+		return
+	}
+
 	//更新服务基本信息
 	serviceInfo.ServiceDesc = serviceUpdateHTTPInput.ServiceDesc
 	if err := serviceInfo.Save(c, tx); err != nil {
@@ -352,6 +438,13 @@ func (serviceController *ServiceController) ServiceUpdateHTTP(c *gin.Context) {
 	httpRule.NeedWebsocket = serviceUpdateHTTPInput.NeedWebsocket
 	httpRule.UrlRewrite = serviceUpdateHTTPInput.UrlRewrite
 	httpRule.HeaderTransfer = serviceUpdateHTTPInput.HeaderTransfer
+	httpRule.Priority = serviceUpdateHTTPInput.Priority
+	httpRule.Methods = serviceUpdateHTTPInput.Methods
+	httpRule.CanaryRule = serviceUpdateHTTPInput.CanaryRule
+	httpRule.MirrorService = serviceUpdateHTTPInput.MirrorService
+	httpRule.MirrorPercent = serviceUpdateHTTPInput.MirrorPercent
+	httpRule.MirrorTimeout = serviceUpdateHTTPInput.MirrorTimeout
+	httpRule.MirrorBodyLimit = serviceUpdateHTTPInput.MirrorBodyLimit
 	if err := httpRule.Save(c, tx); err != nil {
 		tx.Rollback()
 		middleware.ResponseError(c, 3046, err)
@@ -380,15 +473,40 @@ func (serviceController *ServiceController) ServiceUpdateHTTP(c *gin.Context) {
 	loadBalance.UpstreamHeaderTimeout = serviceUpdateHTTPInput.UpstreamHeaderTimeout
 	loadBalance.UpstreamIdleTimeout = serviceUpdateHTTPInput.UpstreamIdleTimeout
 	loadBalance.UpstreamMaxIdle = serviceUpdateHTTPInput.UpstreamMaxIdle
+	loadBalance.GroupList = serviceUpdateHTTPInput.GroupList
+	loadBalance.GroupWeight = serviceUpdateHTTPInput.GroupWeight
+	loadBalance.GroupSticky = serviceUpdateHTTPInput.GroupSticky
+	loadBalance.RetryCount = serviceUpdateHTTPInput.RetryCount
+	loadBalance.RetryBackoff = serviceUpdateHTTPInput.RetryBackoff
+	loadBalance.RetryBudget = serviceUpdateHTTPInput.RetryBudget
+	loadBalance.OutlierFailures = serviceUpdateHTTPInput.OutlierFailures
+	loadBalance.OutlierEjectTime = serviceUpdateHTTPInput.OutlierEjectTime
+	loadBalance.OutlierMaxPercent = serviceUpdateHTTPInput.OutlierMaxPercent
+	loadBalance.HashKey = serviceUpdateHTTPInput.HashKey
+	loadBalance.HashReplicas = serviceUpdateHTTPInput.HashReplicas
+	loadBalance.HashLoadFactor = serviceUpdateHTTPInput.HashLoadFactor
+	loadBalance.SlowStart = serviceUpdateHTTPInput.SlowStart
+	loadBalance.StickyCookie = serviceUpdateHTTPInput.StickyCookie
+	loadBalance.StickyMaxAge = serviceUpdateHTTPInput.StickyMaxAge
 	if err := loadBalance.Save(c, tx); err != nil {
 		tx.Rollback()
 		middleware.ResponseError(c, 3048, err)
 		return
 	}
 
+	//保存服务配置版本快照
+	if _, err := dao.SaveServiceVersion(c, tx, serviceInfo, adminUserName(c), ""); err != nil {
+		tx.Rollback()
+		middleware.ResponseError(c, 3049, err)
+		return
+	}
+
 	//提交事务
 	tx.Commit()
 
+	//通知所有代理节点同步服务变更
+	publishConfigChange(c, public.ConfigChangeTypeService, serviceInfo.ServiceName)
+
 	middleware.ResponseSuccess(c, serviceInfo.ID)
 }
 
@@ -486,145 +604,230 @@ func (serviceController *ServiceController) ServiceStat(c *gin.Context) {
 		yesterdayList = append(yesterdayList, hourData)
 	}
 
+	//查询各上游分组的流量 未配置分组时为空
+	groupStatList := []dto.ServiceGroupStatItem{}
+	loadBalance := &dao.LoadBalance{ServiceID: serviceInfo.ID}
+	if err := loadBalance.Find(c, tx); err == nil {
+		groupWeights, _ := public.ParseUpstreamGroupWeight(loadBalance.GroupWeight)
+		for _, groupWeight := range groupWeights {
+			groupFlowCount, err := circuit_rate.FlowCounterHandler.GetFlowCounter(fmt.Sprintf("%s_%s_%s", public.FlowGroup, serviceInfo.ServiceName, groupWeight.Name))
+			if err != nil {
+				continue
+			}
+			todayCount, _ := groupFlowCount.GetDayData(currentTime)
+			groupStatList = append(groupStatList, dto.ServiceGroupStatItem{
+				Name:       groupWeight.Name,
+				Percent:    groupWeight.Percent,
+				Qps:        groupFlowCount.QPS,
+				TodayCount: todayCount,
+			})
+		}
+	}
+
+	//查询流量镜像今日统计
+	mirrorStat := dto.ServiceMirrorStatItem{}
+	for result, count := range map[string]*int64{
+		public.MirrorResultTotal:    &mirrorStat.Total,
+		public.MirrorResultSkip:     &mirrorStat.Skip,
+		public.MirrorResultError:    &mirrorStat.Error,
+		public.MirrorResultMatch:    &mirrorStat.Match,
+		public.MirrorResultMismatch: &mirrorStat.Mismatch,
+	} {
+		mirrorFlowCount, err := circuit_rate.FlowCounterHandler.GetFlowCounter(fmt.Sprintf("%s_%s_%s", public.FlowMirror, serviceInfo.ServiceName, result))
+		if err != nil {
+			continue
+		}
+		*count, _ = mirrorFlowCount.GetDayData(currentTime)
+	}
+
+	//查询今日失败重试数据
+	retryList := []int64{}
+	retryFlowCount, err := circuit_rate.FlowCounterHandler.GetFlowCounter(fmt.Sprintf("%s_%s", public.FlowRetry, serviceInfo.ServiceName))
+	if err == nil {
+		for i := 0; i <= currentTime.Hour(); i++ {
+			dateTime := time.Date(currentTime.Year(), currentTime.Month(), currentTime.Day(), i, 0, 0, 0, lib.TimeLocation)
+			hourData, _ := retryFlowCount.GetHourData(dateTime)
+			retryList = append(retryList, hourData)
+		}
+	}
+
 	middleware.ResponseSuccess(c, &dto.ServiceStatOutput{
 		Today:     todayList,
 		Yesterday: yesterdayList,
+		Group:     groupStatList,
+		Mirror:    mirrorStat,
+		Retry:     retryList,
 	})
 }
 
-// ServiceAddTCP godoc
-// @Summary TCP服务新增
-// @Description TCP服务新增
+// ServiceGroupWeight godoc
+// @Summary 上游分组流量比例调整
+// @Description 上游分组流量比例调整
 // @Tags 服务管理
-// @ID /service/service_add_tcp
+// @ID /service/service_group_weight
 // @Accept  json
 // @Produce  json
-// @Param body body dto.ServiceAddTCPInput true "body"
+// @Param body body dto.ServiceGroupWeightInput true "body"
 // @Success 200 {object} middleware.Response{data=string} "success"
-// @Router /service/service_add_tcp [post]
-func (serviceController *ServiceController) ServiceAddTCP(c *gin.Context) {
-	serviceAddTCPInput := &dto.ServiceAddTCPInput{}
-	if err := serviceAddTCPInput.BindValidParam(c); err != nil {
-		middleware.ResponseError(c, 3071, err)
+// @Router /service/service_group_weight [post]
+func (serviceController *ServiceController) ServiceGroupWeight(c *gin.Context) {
+	serviceGroupWeightInput := &dto.ServiceGroupWeightInput{}
+	if err := serviceGroupWeightInput.BindValidParam(c); err != nil {
+		middleware.ResponseError(c, 3151, err)
 		return
 	}
 
 	//获取数据库连接池
 	tx, err := lib.GetGormPool("default")
 	if err != nil {
-		middleware.ResponseError(c, 3072, err)
+		middleware.ResponseError(c, 3152, err)
 		return
 	}
 
 	//开启事务
 	tx = tx.Begin()
 
-	//查看服务名称是否被占用
-	serviceInfo := &dao.ServiceInfo{
-		ServiceName: serviceAddTCPInput.ServiceName,
-	}
-	serviceInfo.Find(c, tx)
-	if serviceInfo.ID != 0 {
+	//查看服务是否存在
+	serviceInfo := &dao.ServiceInfo{ID: serviceGroupWeightInput.ID}
+	if err := serviceInfo.Find(c, tx); err != nil || serviceInfo.ServiceName == "" {
 		tx.Rollback()
-		middleware.ResponseError(c, 3073, errors.New("服务已经存在"))
+		middleware.ResponseError(c, 3153, errors.New("服务不存在"))
 		return
 	}
-
-	//验证端口是否被tcp占用
-	tcpRule := &dao.TcpRule{
-		Port: serviceAddTCPInput.Port,
-	}
-	if tcpRule.Find(c, tx); tcpRule.ID != 0 {
+	if serviceInfo.LoadType != public.LoadTypeHTTP {
 		tx.Rollback()
-		middleware.ResponseError(c, 3074, errors.New("服务端口被占用，请重新输入"))
+		middleware.ResponseError(c, 3154, errors.New("仅http服务支持上游分组"))
 		return
 	}
 
-	//验证端口是否被grpc占用
-	grpcRule := &dao.GrpcRule{
-		Port: serviceAddTCPInput.Port,
+	//分组流量比例中的分组必须在节点分组列表中存在
+	loadBalance := &dao.LoadBalance{ServiceID: serviceInfo.ID}
+	if err := loadBalance.Find(c, tx); err != nil {
+		tx.Rollback()
+		middleware.ResponseError(c, 3155, err)
+		return
 	}
-	if grpcRule.Find(c, tx); grpcRule.ID != 0 {
+	if !public.ValidUpstreamGroup(loadBalance.IpList, loadBalance.WeightList, loadBalance.GroupList, serviceGroupWeightInput.GroupWeight) {
 		tx.Rollback()
-		middleware.ResponseError(c, 3075, errors.New("服务端口被占用，请重新输入"))
+		middleware.ResponseError(c, 3156, errors.New("分组流量比例与节点分组不匹配"))
 		return
 	}
 
-	//保存服务基本信息
-	serviceInfo.ServiceDesc = serviceAddTCPInput.ServiceDesc
-	serviceInfo.LoadType = public.LoadTypeTCP
-	if err := serviceInfo.Save(c, tx); err != nil {
+	//更新分组流量比例
+	loadBalance.GroupWeight = serviceGroupWeightInput.GroupWeight
+	loadBalance.GroupSticky = serviceGroupWeightInput.GroupSticky
+	if err := loadBalance.Save(c, tx); err != nil {
 		tx.Rollback()
-		middleware.ResponseError(c, 3076, err)
+		middleware.ResponseError(c, 3157, err)
 		return
 	}
 
-	serviceId := serviceInfo.ID
-	//保存grpc服务的规则信息
-	tcpRule.ServiceID = serviceId
-	if err := tcpRule.Save(c, tx); err != nil {
+	//保存服务配置版本快照
+	if _, err := dao.SaveServiceVersion(c, tx, serviceInfo, adminUserName(c), "调整分组流量比例"); err != nil {
 		tx.Rollback()
-		middleware.ResponseError(c, 3077, err)
+		middleware.ResponseError(c, 3158, err)
 		return
 	}
 
-	//保存grpc服务的权限控制信息
-	accessControl := &dao.AccessControl{
-		ServiceID:         serviceId,
-		OpenAuth:          serviceAddTCPInput.OpenAuth,
-		BlackList:         serviceAddTCPInput.BlackList,
-		WhiteList:         serviceAddTCPInput.WhiteList,
-		WhiteHostName:     serviceAddTCPInput.WhiteHostName,
-		ClientIPFlowLimit: serviceAddTCPInput.ClientIPFlowLimit,
-		ServiceFlowLimit:  serviceAddTCPInput.ServiceFlowLimit,
+	//提交事务
+	tx.Commit()
+
+	//通知所有代理节点同步服务变更
+	publishConfigChange(c, public.ConfigChangeTypeService, serviceInfo.ServiceName)
+
+	middleware.ResponseSuccess(c, "")
+}
+
+// ServiceCircuit godoc
+// @Summary 服务熔断配置及状态查询
+// @Description 服务熔断配置及状态查询
+// @Tags 服务管理
+// @ID /service/service_circuit
+// @Accept  json
+// @Produce  json
+// @Param id query dto.ServiceCircuitInput true "服务id"
+// @Success 200 {object} middleware.Response{data=dto.ServiceCircuitOutput} "success"
+// @Router /service/service_circuit [get]
+func (serviceController *ServiceController) ServiceCircuit(c *gin.Context) {
+	serviceCircuitInput := &dto.ServiceCircuitInput{}
+	if err := serviceCircuitInput.BindValidParam(c); err != nil {
+		middleware.ResponseError(c, 3161, err)
+		return
 	}
-	if err := accessControl.Save(c, tx); err != nil {
-		tx.Rollback()
-		middleware.ResponseError(c, 3078, err)
+
+	//获取数据库连接池
+	tx, err := lib.GetGormPool("default")
+	if err != nil {
+		middleware.ResponseError(c, 3162, err)
 		return
 	}
 
-	//保存服务负载均衡信息
-	loadBalance := &dao.LoadBalance{
-		ServiceID:  serviceId,
-		RoundType:  serviceAddTCPInput.RoundType,
-		IpList:     serviceAddTCPInput.IpList,
-		WeightList: serviceAddTCPInput.WeightList,
-		ForbidList: serviceAddTCPInput.ForbidList,
+	//查询服务基本信息及负载均衡信息
+	serviceInfo := &dao.ServiceInfo{ID: serviceCircuitInput.ID}
+	if err := serviceInfo.Find(c, tx); err != nil || serviceInfo.ServiceName == "" {
+		middleware.ResponseError(c, 3163, errors.New("服务不存在"))
+		return
 	}
-	if err := loadBalance.Save(c, tx); err != nil {
-		tx.Rollback()
-		middleware.ResponseError(c, 3079, err)
+	loadBalance := &dao.LoadBalance{ServiceID: serviceInfo.ID}
+	if err := loadBalance.Find(c, tx); err != nil {
+		middleware.ResponseError(c, 3164, err)
 		return
 	}
 
-	//提交事务
-	tx.Commit()
+	//熔断器状态由各代理节点在状态切换时写入redis
+	statList := []dto.ServiceCircuitStatItem{}
+	circuitStatList, err := circuit_rate.GetCircuitBreakerStats(serviceInfo.ServiceName)
+	if err != nil {
+		middleware.ResponseError(c, 3165, err)
+		return
+	}
+	for _, circuitStat := range circuitStatList {
+		statList = append(statList, dto.ServiceCircuitStatItem{
+			Node:                circuitStat.Node,
+			State:               circuitStat.State,
+			Requests:            circuitStat.Requests,
+			Failures:            circuitStat.Failures,
+			ConsecutiveFailures: circuitStat.ConsecutiveFailures,
+			UpdateAt:            circuitStat.UpdateAt,
+		})
+	}
+	sort.Slice(statList, func(i, j int) bool {
+		return statList[i].Node < statList[j].Node
+	})
 
-	middleware.ResponseSuccess(c, serviceInfo.ID)
+	middleware.ResponseSuccess(c, &dto.ServiceCircuitOutput{
+		ErrorPercent: loadBalance.CircuitErrorPercent,
+		Failures:     loadBalance.CircuitFailures,
+		MinRequests:  loadBalance.CircuitMinRequests,
+		Window:       loadBalance.CircuitWindow,
+		OpenTimeout:  loadBalance.CircuitOpenTimeout,
+		HalfOpen:     loadBalance.CircuitHalfOpen,
+		Fallback:     loadBalance.CircuitFallback,
+		State:        statList,
+	})
 }
 
-// ServiceUpdateTCP godoc
-// @Summary TCP服务更新
-// @Description TCP服务更新
+// ServiceCircuitUpdate godoc
+// @Summary 服务熔断配置修改
+// @Description 服务熔断配置修改
 // @Tags 服务管理
-// @ID /service/service_update_tcp
+// @ID /service/service_circuit_update
 // @Accept  json
 // @Produce  json
-// @Param body body dto.ServiceUpdateTCPInput true "body"
+// @Param body body dto.ServiceCircuitUpdateInput true "body"
 // @Success 200 {object} middleware.Response{data=string} "success"
-// @Router /service/service_update_tcp [put]
-func (serviceController *ServiceController) ServiceUpdateTCP(c *gin.Context) {
-	serviceUpdateTCPInput := &dto.ServiceUpdateTCPInput{}
-	if err := serviceUpdateTCPInput.BindValidParam(c); err != nil {
-		middleware.ResponseError(c, 3081, err)
+// @Router /service/service_circuit_update [post]
+func (serviceController *ServiceController) ServiceCircuitUpdate(c *gin.Context) {
+	serviceCircuitUpdateInput := &dto.ServiceCircuitUpdateInput{}
+	if err := serviceCircuitUpdateInput.BindValidParam(c); err != nil {
+		middleware.ResponseError(c, 3171, err)
 		return
 	}
 
 	//获取数据库连接池
 	tx, err := lib.GetGormPool("default")
 	if err != nil {
-		middleware.ResponseError(c, 3082, err)
+		middleware.ResponseError(c, 3172, err)
 		return
 	}
 
@@ -632,160 +835,922 @@ func (serviceController *ServiceController) ServiceUpdateTCP(c *gin.Context) {
 	tx = tx.Begin()
 
 	//查看服务是否存在
-	serviceInfo := &dao.ServiceInfo{
-		ID:          serviceUpdateTCPInput.ID,
-		ServiceName: serviceUpdateTCPInput.ServiceName,
-		IsDelete:    0,
-	}
-
-	if err := serviceInfo.Find(c, tx); err != nil {
+	serviceInfo := &dao.ServiceInfo{ID: serviceCircuitUpdateInput.ID}
+	if err := serviceInfo.Find(c, tx); err != nil || serviceInfo.ServiceName == "" {
 		tx.Rollback()
-		middleware.ResponseError(c, 3083, errors.New("服务不存在"))
+		middleware.ResponseError(c, 3173, errors.New("服务不存在"))
 		return
 	}
-
-	//查询服务详情
-	serviceDetail, err := serviceInfo.ServiceDetail(c, tx)
-	if err != nil {
+	loadBalance := &dao.LoadBalance{ServiceID: serviceInfo.ID}
+	if err := loadBalance.Find(c, tx); err != nil {
 		tx.Rollback()
-		middleware.ResponseError(c, 3084, errors.New("服务不存在"))
+		middleware.ResponseError(c, 3174, err)
 		return
 	}
 
-	//更新服务基本信息
-	serviceInfo.ServiceDesc = serviceUpdateTCPInput.ServiceDesc
-	if err := serviceInfo.Save(c, tx); err != nil {
+	//更新熔断配置
+	loadBalance.CircuitErrorPercent = serviceCircuitUpdateInput.ErrorPercent
+	loadBalance.CircuitFailures = serviceCircuitUpdateInput.Failures
+	loadBalance.CircuitMinRequests = serviceCircuitUpdateInput.MinRequests
+	loadBalance.CircuitWindow = serviceCircuitUpdateInput.Window
+	loadBalance.CircuitOpenTimeout = serviceCircuitUpdateInput.OpenTimeout
+	loadBalance.CircuitHalfOpen = serviceCircuitUpdateInput.HalfOpen
+	loadBalance.CircuitFallback = serviceCircuitUpdateInput.Fallback
+	if err := loadBalance.Save(c, tx); err != nil {
 		tx.Rollback()
-		middleware.ResponseError(c, 3085, err)
+		middleware.ResponseError(c, 3175, err)
 		return
 	}
 
-	//更新grpc服务的规则信息
-	tcpRule := serviceDetail.TCPRule
-	tcpRule.Port = serviceUpdateTCPInput.Port
-	if err := tcpRule.Save(c, tx); err != nil {
+	//保存服务配置版本快照
+	if _, err := dao.SaveServiceVersion(c, tx, serviceInfo, adminUserName(c), "修改熔断配置"); err != nil {
 		tx.Rollback()
-		middleware.ResponseError(c, 3086, err)
+		middleware.ResponseError(c, 3176, err)
 		return
 	}
 
-	//更新grpc服务的权限控制信息
-	accessControl := serviceDetail.AccessControl
-	accessControl.OpenAuth = serviceUpdateTCPInput.OpenAuth
-	accessControl.BlackList = serviceUpdateTCPInput.BlackList
-	accessControl.WhiteList = serviceUpdateTCPInput.WhiteList
-	accessControl.WhiteHostName = serviceUpdateTCPInput.WhiteHostName
-	accessControl.ClientIPFlowLimit = serviceUpdateTCPInput.ClientIPFlowLimit
-	accessControl.ServiceFlowLimit = serviceUpdateTCPInput.ServiceFlowLimit
-	if err := accessControl.Save(c, tx); err != nil {
-		tx.Rollback()
-		middleware.ResponseError(c, 3087, err)
+	//提交事务
+	tx.Commit()
+
+	//通知所有代理节点同步服务变更
+	publishConfigChange(c, public.ConfigChangeTypeService, serviceInfo.ServiceName)
+
+	middleware.ResponseSuccess(c, "")
+}
+
+// ServiceHealthCheck godoc
+// @Summary 服务主动健康检查配置查询
+// @Description 服务主动健康检查配置查询
+// @Tags 服务管理
+// @ID /service/service_health_check
+// @Accept  json
+// @Produce  json
+// @Param id query dto.ServiceHealthCheckInput true "服务id"
+// @Success 200 {object} middleware.Response{data=dto.ServiceHealthCheckOutput} "success"
+// @Router /service/service_health_check [get]
+func (serviceController *ServiceController) ServiceHealthCheck(c *gin.Context) {
+	serviceHealthCheckInput := &dto.ServiceHealthCheckInput{}
+	if err := serviceHealthCheckInput.BindValidParam(c); err != nil {
+		middleware.ResponseError(c, 3191, err)
 		return
 	}
 
-	//更新服务负载均衡信息
-	loadBalance := serviceDetail.LoadBalance
-	loadBalance.RoundType = serviceUpdateTCPInput.RoundType
-	loadBalance.IpList = serviceUpdateTCPInput.IpList
-	loadBalance.WeightList = serviceUpdateTCPInput.WeightList
-	loadBalance.ForbidList = serviceUpdateTCPInput.ForbidList
-	if err := loadBalance.Save(c, tx); err != nil {
-		tx.Rollback()
-		middleware.ResponseError(c, 3088, err)
+	//获取数据库连接池
+	tx, err := lib.GetGormPool("default")
+	if err != nil {
+		middleware.ResponseError(c, 3192, err)
 		return
 	}
 
-	//提交事务
-	tx.Commit()
+	//查询服务基本信息及负载均衡信息
+	serviceInfo := &dao.ServiceInfo{ID: serviceHealthCheckInput.ID}
+	if err := serviceInfo.Find(c, tx); err != nil || serviceInfo.ServiceName == "" {
+		middleware.ResponseError(c, 3193, errors.New("服务不存在"))
+		return
+	}
+	loadBalance := &dao.LoadBalance{ServiceID: serviceInfo.ID}
+	if err := loadBalance.Find(c, tx); err != nil {
+		middleware.ResponseError(c, 3194, err)
+		return
+	}
 
-	middleware.ResponseSuccess(c, serviceInfo.ID)
+	middleware.ResponseSuccess(c, &dto.ServiceHealthCheckOutput{
+		Method:    loadBalance.CheckMethod,
+		Timeout:   loadBalance.CheckTimeout,
+		Interval:  loadBalance.CheckInterval,
+		Path:      loadBalance.CheckPath,
+		Host:      loadBalance.CheckHost,
+		Status:    loadBalance.CheckStatus,
+		Body:      loadBalance.CheckBody,
+		Service:   loadBalance.CheckService,
+		Healthy:   loadBalance.CheckHealthy,
+		Unhealthy: loadBalance.CheckUnhealthy,
+	})
 }
 
-// ServiceAddGRPC godoc
-// @Summary GRPC服务新增
-// @Description GRPC服务新增
+// ServiceHealthCheckUpdate godoc
+// @Summary 服务主动健康检查配置修改
+// @Description 服务主动健康检查配置修改
 // @Tags 服务管理
-// @ID /service/service_add_grpc
+// @ID /service/service_health_check_update
 // @Accept  json
 // @Produce  json
-// @Param body body dto.ServiceAddGRPCInput true "body"
+// @Param body body dto.ServiceHealthCheckUpdateInput true "body"
 // @Success 200 {object} middleware.Response{data=string} "success"
-// @Router /service/service_add_grpc [post]
-func (serviceController *ServiceController) ServiceAddGRPC(c *gin.Context) {
-	serviceAddGRPCInput := &dto.ServiceAddGRPCInput{}
-	if err := serviceAddGRPCInput.BindValidParam(c); err != nil {
-		middleware.ResponseError(c, 3091, err)
+// @Router /service/service_health_check_update [post]
+func (serviceController *ServiceController) ServiceHealthCheckUpdate(c *gin.Context) {
+	serviceHealthCheckUpdateInput := &dto.ServiceHealthCheckUpdateInput{}
+	if err := serviceHealthCheckUpdateInput.BindValidParam(c); err != nil {
+		middleware.ResponseError(c, 3201, err)
 		return
 	}
 
 	//获取数据库连接池
 	tx, err := lib.GetGormPool("default")
 	if err != nil {
-		middleware.ResponseError(c, 3092, err)
+		middleware.ResponseError(c, 3202, err)
 		return
 	}
 
 	//开启事务
 	tx = tx.Begin()
 
-	//查看服务名称是否被占用
-	serviceInfo := &dao.ServiceInfo{
-		ServiceName: serviceAddGRPCInput.ServiceName,
-	}
-	serviceInfo.Find(c, tx)
-	if serviceInfo.ID != 0 {
+	//查看服务是否存在
+	serviceInfo := &dao.ServiceInfo{ID: serviceHealthCheckUpdateInput.ID}
+	if err := serviceInfo.Find(c, tx); err != nil || serviceInfo.ServiceName == "" {
 		tx.Rollback()
-		middleware.ResponseError(c, 3093, errors.New("服务已经存在"))
+		middleware.ResponseError(c, 3203, errors.New("服务不存在"))
 		return
 	}
-
-	//验证端口是否被tcp占用
-	tcpRule := &dao.TcpRule{
-		Port: serviceAddGRPCInput.Port,
-	}
-	if err := tcpRule.Find(c, tx); err != nil {
+	loadBalance := &dao.LoadBalance{ServiceID: serviceInfo.ID}
+	if err := loadBalance.Find(c, tx); err != nil {
 		tx.Rollback()
-		middleware.ResponseError(c, 3094, errors.New("服务端口被占用，请重新输入"))
+		middleware.ResponseError(c, 3204, err)
 		return
 	}
-
-	//验证端口是否被grpc占用
-	grpcRule := &dao.GrpcRule{
-		Port: serviceAddGRPCInput.Port,
-	}
-	if err := grpcRule.Find(c, tx); err != nil {
+	//grpc健康检查协议仅grpc服务支持
+	if serviceHealthCheckUpdateInput.Method == load_balance.CheckMethodGRPC && serviceInfo.LoadType != public.LoadTypeGRPC {
 		tx.Rollback()
-		middleware.ResponseError(c, 3095, errors.New("服务端口被占用，请重新输入"))
+		middleware.ResponseError(c, 3207, errors.New("仅grpc服务支持grpc健康检查"))
 		return
 	}
 
-	//保存服务基本信息
-	serviceInfo.ServiceDesc = serviceAddGRPCInput.ServiceDesc
-	serviceInfo.LoadType = public.LoadTypeGRPC
-	if err := serviceInfo.Save(c, tx); err != nil {
+	//更新主动健康检查配置
+	loadBalance.CheckMethod = serviceHealthCheckUpdateInput.Method
+	loadBalance.CheckTimeout = serviceHealthCheckUpdateInput.Timeout
+	loadBalance.CheckInterval = serviceHealthCheckUpdateInput.Interval
+	loadBalance.CheckPath = serviceHealthCheckUpdateInput.Path
+	loadBalance.CheckHost = serviceHealthCheckUpdateInput.Host
+	loadBalance.CheckStatus = serviceHealthCheckUpdateInput.Status
+	loadBalance.CheckBody = serviceHealthCheckUpdateInput.Body
+	loadBalance.CheckService = serviceHealthCheckUpdateInput.Service
+	loadBalance.CheckHealthy = serviceHealthCheckUpdateInput.Healthy
+	loadBalance.CheckUnhealthy = serviceHealthCheckUpdateInput.Unhealthy
+	if err := loadBalance.Save(c, tx); err != nil {
 		tx.Rollback()
-		middleware.ResponseError(c, 3096, err)
+		middleware.ResponseError(c, 3205, err)
 		return
 	}
 
-	serviceId := serviceInfo.ID
-	//保存grpc服务的规则信息
-	grpcRule.ServiceID = serviceId
-	grpcRule.HeaderTransfer = serviceAddGRPCInput.HeaderTransfer
-	if err := grpcRule.Save(c, tx); err != nil {
+	//保存服务配置版本快照
+	if _, err := dao.SaveServiceVersion(c, tx, serviceInfo, adminUserName(c), "修改健康检查配置"); err != nil {
 		tx.Rollback()
-		middleware.ResponseError(c, 3097, err)
+		middleware.ResponseError(c, 3206, err)
 		return
 	}
 
-	//保存grpc服务的权限控制信息
-	accessControl := &dao.AccessControl{
-		ServiceID:         serviceId,
-		OpenAuth:          serviceAddGRPCInput.OpenAuth,
-		BlackList:         serviceAddGRPCInput.BlackList,
-		WhiteList:         serviceAddGRPCInput.WhiteList,
-		WhiteHostName:     serviceAddGRPCInput.WhiteHostName,
-		ClientIPFlowLimit: serviceAddGRPCInput.ClientIPFlowLimit,
+	//提交事务
+	tx.Commit()
+
+	//通知所有代理节点同步服务变更
+	publishConfigChange(c, public.ConfigChangeTypeService, serviceInfo.ServiceName)
+
+	middleware.ResponseSuccess(c, "")
+}
+
+// ServiceBalanceScore godoc
+// @Summary 服务负载均衡节点评分查询
+// @Description 服务负载均衡节点评分查询 仅p2c轮询方式有节点评分
+// @Tags 服务管理
+// @ID /service/service_balance_score
+// @Accept  json
+// @Produce  json
+// @Param id query dto.ServiceBalanceScoreInput true "服务id"
+// @Success 200 {object} middleware.Response{data=dto.ServiceBalanceScoreOutput} "success"
+// @Router /service/service_balance_score [get]
+func (serviceController *ServiceController) ServiceBalanceScore(c *gin.Context) {
+	serviceBalanceScoreInput := &dto.ServiceBalanceScoreInput{}
+	if err := serviceBalanceScoreInput.BindValidParam(c); err != nil {
+		middleware.ResponseError(c, 3211, err)
+		return
+	}
+
+	//获取数据库连接池
+	tx, err := lib.GetGormPool("default")
+	if err != nil {
+		middleware.ResponseError(c, 3212, err)
+		return
+	}
+
+	//查询服务基本信息及负载均衡信息
+	serviceInfo := &dao.ServiceInfo{ID: serviceBalanceScoreInput.ID}
+	if err := serviceInfo.Find(c, tx); err != nil || serviceInfo.ServiceName == "" {
+		middleware.ResponseError(c, 3213, errors.New("服务不存在"))
+		return
+	}
+	loadBalance := &dao.LoadBalance{ServiceID: serviceInfo.ID}
+	if err := loadBalance.Find(c, tx); err != nil {
+		middleware.ResponseError(c, 3214, err)
+		return
+	}
+
+	//节点评分由代理节点定时写入redis
+	scoreList, err := dao.GetBalanceScores(serviceInfo.ServiceName)
+	if err != nil {
+		middleware.ResponseError(c, 3215, err)
+		return
+	}
+	nodes := []dto.ServiceBalanceScoreItem{}
+	for _, score := range scoreList {
+		nodes = append(nodes, dto.ServiceBalanceScoreItem{
+			Addr:     score.Addr,
+			Latency:  score.Latency,
+			Inflight: score.Inflight,
+			Score:    score.Score,
+			UpdateAt: score.UpdateAt,
+		})
+	}
+
+	middleware.ResponseSuccess(c, &dto.ServiceBalanceScoreOutput{
+		RoundType: loadBalance.RoundType,
+		Nodes:     nodes,
+	})
+}
+
+// ServiceHealth godoc
+// @Summary 服务上游节点健康状态查询
+// @Description 服务上游节点健康状态查询 状态由代理节点定时上报
+// @Tags 服务管理
+// @ID /service/service_health
+// @Accept  json
+// @Produce  json
+// @Param id query dto.ServiceHealthInput true "服务id"
+// @Success 200 {object} middleware.Response{data=dto.ServiceHealthOutput} "success"
+// @Router /service/service_health [get]
+func (serviceController *ServiceController) ServiceHealth(c *gin.Context) {
+	serviceHealthInput := &dto.ServiceHealthInput{}
+	if err := serviceHealthInput.BindValidParam(c); err != nil {
+		middleware.ResponseError(c, 3241, err)
+		return
+	}
+
+	//获取数据库连接池
+	tx, err := lib.GetGormPool("default")
+	if err != nil {
+		middleware.ResponseError(c, 3242, err)
+		return
+	}
+
+	//查询服务基本信息及负载均衡信息
+	serviceInfo := &dao.ServiceInfo{ID: serviceHealthInput.ID}
+	if err := serviceInfo.Find(c, tx); err != nil || serviceInfo.ServiceName == "" {
+		middleware.ResponseError(c, 3243, errors.New("服务不存在"))
+		return
+	}
+	loadBalance := &dao.LoadBalance{ServiceID: serviceInfo.ID}
+	if err := loadBalance.Find(c, tx); err != nil {
+		middleware.ResponseError(c, 3244, err)
+		return
+	}
+
+	//节点状态由各代理节点定时写入redis 读取时合并
+	statusList, err := dao.GetNodeStatus(serviceInfo.ServiceName)
+	if err != nil {
+		middleware.ResponseError(c, 3245, err)
+		return
+	}
+	statusMap := map[string]*dao.NodeStatus{}
+	for _, status := range statusList {
+		statusMap[status.Addr] = status
+	}
+	//按服务配置的节点列表输出 代理节点未上报的节点只能确定是否被手动摘除
+	nodes := []dto.ServiceHealthItem{}
+	for _, ip := range loadBalance.GetIPListByModel() {
+		status, ok := statusMap[ip]
+		if !ok {
+			state := load_balance.NodeStateUnknown
+			if loadBalance.NodeForbidden(ip) {
+				state = load_balance.NodeStateDrained
+			}
+			nodes = append(nodes, dto.ServiceHealthItem{Addr: ip, State: state})
+			continue
+		}
+		nodes = append(nodes, dto.ServiceHealthItem{
+			Addr:                status.Addr,
+			State:               status.State,
+			LastProbeTime:       status.LastProbeTime,
+			LastError:           status.LastError,
+			ConsecutiveFailures: status.ConsecutiveFailures,
+			StateChangeTime:     status.StateChangeTime,
+			Proxy:               status.Proxy,
+			UpdateAt:            status.UpdateAt,
+		})
+	}
+
+	middleware.ResponseSuccess(c, &dto.ServiceHealthOutput{
+		Nodes: nodes,
+	})
+}
+
+// ServiceRouteConflict godoc
+// @Summary http服务路由冲突查询
+// @Description 查询与该服务接入规则重叠的其他http服务 并说明请求实际会路由到哪个服务 可在保存、回滚或导入后调用
+// @Tags 服务管理
+// @ID /service/service_route_conflict
+// @Accept  json
+// @Produce  json
+// @Param id query dto.ServiceRouteConflictInput true "服务id"
+// @Success 200 {object} middleware.Response{data=dto.ServiceRouteConflictOutput} "success"
+// @Router /service/service_route_conflict [get]
+func (serviceController *ServiceController) ServiceRouteConflict(c *gin.Context) {
+	serviceRouteConflictInput := &dto.ServiceRouteConflictInput{}
+	if err := serviceRouteConflictInput.BindValidParam(c); err != nil {
+		middleware.ResponseError(c, 3251, err)
+		return
+	}
+
+	//获取数据库连接池
+	tx, err := lib.GetGormPool("default")
+	if err != nil {
+		middleware.ResponseError(c, 3252, err)
+		return
+	}
+
+	//查询服务基本信息
+	serviceInfo := &dao.ServiceInfo{ID: serviceRouteConflictInput.ID}
+	if err := serviceInfo.Find(c, tx); err != nil || serviceInfo.ServiceName == "" {
+		middleware.ResponseError(c, 3253, errors.New("服务不存在"))
+		return
+	}
+
+	//查询与该服务存在路由冲突的其他http服务 非http服务没有路由冲突
+	conflicts, err := serviceInfo.HTTPRouteConflicts(c, tx)
+	if err != nil {
+		middleware.ResponseError(c, 3254, err)
+		return
+	}
+
+	middleware.ResponseSuccess(c, &dto.ServiceRouteConflictOutput{
+		List: conflicts,
+	})
+}
+
+// ServiceNodeDrain godoc
+// @Summary 服务节点摘除
+// @Description 手动摘除上游节点 节点不再分配新请求 进行中的请求及tcp连接不受影响 节点记录在禁用ip列表中
+// @Tags 服务管理
+// @ID /service/service_node_drain
+// @Accept  json
+// @Produce  json
+// @Param body body dto.ServiceNodeInput true "body"
+// @Success 200 {object} middleware.Response{data=string} "success"
+// @Router /service/service_node_drain [post]
+func (serviceController *ServiceController) ServiceNodeDrain(c *gin.Context) {
+	serviceNodeInput := &dto.ServiceNodeInput{}
+	if err := serviceNodeInput.BindValidParam(c); err != nil {
+		middleware.ResponseError(c, 3221, err)
+		return
+	}
+
+	//获取数据库连接池
+	tx, err := lib.GetGormPool("default")
+	if err != nil {
+		middleware.ResponseError(c, 3222, err)
+		return
+	}
+
+	//开启事务
+	tx = tx.Begin()
+
+	//查看服务是否存在
+	serviceInfo := &dao.ServiceInfo{ID: serviceNodeInput.ID}
+	if err := serviceInfo.Find(c, tx); err != nil || serviceInfo.ServiceName == "" {
+		tx.Rollback()
+		middleware.ResponseError(c, 3223, errors.New("服务不存在"))
+		return
+	}
+	//锁定负载均衡配置 避免并发摘除或恢复节点时基于旧的禁用列表校验及保存
+	loadBalance := &dao.LoadBalance{ServiceID: serviceInfo.ID}
+	if err := loadBalance.FindForUpdate(c, tx); err != nil {
+		tx.Rollback()
+		middleware.ResponseError(c, 3224, err)
+		return
+	}
+	//节点需在服务的ip列表中
+	nodeExist := false
+	for _, ip := range loadBalance.GetIPListByModel() {
+		if ip == serviceNodeInput.Node {
+			nodeExist = true
+			break
+		}
+	}
+	if !nodeExist {
+		tx.Rollback()
+		middleware.ResponseError(c, 3225, errors.New("节点不在服务的ip列表中"))
+		return
+	}
+	//至少保留一个可用节点
+	enableNum := 0
+	for _, ip := range loadBalance.GetIPListByModel() {
+		if ip == serviceNodeInput.Node {
+			continue
+		}
+		if !loadBalance.NodeForbidden(ip) {
+			enableNum++
+		}
+	}
+	if enableNum == 0 {
+		tx.Rollback()
+		middleware.ResponseError(c, 3226, errors.New("至少保留一个可用节点"))
+		return
+	}
+
+	//节点已被禁用时直接返回
+	if !loadBalance.SetNodeForbidden(serviceNodeInput.Node, true) {
+		tx.Rollback()
+		middleware.ResponseSuccess(c, "")
+		return
+	}
+	if err := loadBalance.Save(c, tx); err != nil {
+		tx.Rollback()
+		middleware.ResponseError(c, 3227, err)
+		return
+	}
+
+	//保存服务配置版本快照
+	if _, err := dao.SaveServiceVersion(c, tx, serviceInfo, adminUserName(c), "摘除节点 "+serviceNodeInput.Node); err != nil {
+		tx.Rollback()
+		middleware.ResponseError(c, 3228, err)
+		return
+	}
+
+	//提交事务
+	tx.Commit()
+
+	//通知所有代理节点同步服务变更 仅禁用列表变化 代理节点在原负载均衡器上更新 不丢失节点的运行时状态
+	publishConfigChange(c, public.ConfigChangeTypeService, serviceInfo.ServiceName)
+
+	middleware.ResponseSuccess(c, "")
+}
+
+// ServiceNodeEnable godoc
+// @Summary 服务节点恢复
+// @Description 恢复手动摘除的上游节点 节点从禁用ip列表中移除
+// @Tags 服务管理
+// @ID /service/service_node_enable
+// @Accept  json
+// @Produce  json
+// @Param body body dto.ServiceNodeInput true "body"
+// @Success 200 {object} middleware.Response{data=string} "success"
+// @Router /service/service_node_enable [post]
+func (serviceController *ServiceController) ServiceNodeEnable(c *gin.Context) {
+	serviceNodeInput := &dto.ServiceNodeInput{}
+	if err := serviceNodeInput.BindValidParam(c); err != nil {
+		middleware.ResponseError(c, 3231, err)
+		return
+	}
+
+	//获取数据库连接池
+	tx, err := lib.GetGormPool("default")
+	if err != nil {
+		middleware.ResponseError(c, 3232, err)
+		return
+	}
+
+	//开启事务
+	tx = tx.Begin()
+
+	//查看服务是否存在
+	serviceInfo := &dao.ServiceInfo{ID: serviceNodeInput.ID}
+	if err := serviceInfo.Find(c, tx); err != nil || serviceInfo.ServiceName == "" {
+		tx.Rollback()
+		middleware.ResponseError(c, 3233, errors.New("服务不存在"))
+		return
+	}
+	//锁定负载均衡配置 避免并发摘除或恢复节点时基于旧的禁用列表校验及保存
+	loadBalance := &dao.LoadBalance{ServiceID: serviceInfo.ID}
+	if err := loadBalance.FindForUpdate(c, tx); err != nil {
+		tx.Rollback()
+		middleware.ResponseError(c, 3234, err)
+		return
+	}
+	//节点需在服务的ip列表中
+	nodeExist := false
+	for _, ip := range loadBalance.GetIPListByModel() {
+		if ip == serviceNodeInput.Node {
+			nodeExist = true
+			break
+		}
+	}
+	if !nodeExist {
+		tx.Rollback()
+		middleware.ResponseError(c, 3235, errors.New("节点不在服务的ip列表中"))
+		return
+	}
+
+	//按ip整体禁用的节点需修改禁用ip列表恢复
+	forbidList := []string{}
+	for _, item := range loadBalance.GetForbidListByModel() {
+		if item != serviceNodeInput.Node {
+			forbidList = append(forbidList, item)
+		}
+	}
+	if load_balance.NodeForbidden(forbidList, serviceNodeInput.Node) {
+		tx.Rollback()
+		middleware.ResponseError(c, 3236, errors.New("节点所在ip被整体禁用 请修改禁用ip列表"))
+		return
+	}
+
+	//节点已恢复时直接返回
+	if !loadBalance.SetNodeForbidden(serviceNodeInput.Node, false) {
+		tx.Rollback()
+		middleware.ResponseSuccess(c, "")
+		return
+	}
+	if err := loadBalance.Save(c, tx); err != nil {
+		tx.Rollback()
+		middleware.ResponseError(c, 3237, err)
+		return
+	}
+
+	//保存服务配置版本快照
+	if _, err := dao.SaveServiceVersion(c, tx, serviceInfo, adminUserName(c), "恢复节点 "+serviceNodeInput.Node); err != nil {
+		tx.Rollback()
+		middleware.ResponseError(c, 3238, err)
+		return
+	}
+
+	//提交事务
+	tx.Commit()
+
+	//通知所有代理节点同步服务变更 仅禁用列表变化 代理节点在原负载均衡器上更新 不丢失节点的运行时状态
+	publishConfigChange(c, public.ConfigChangeTypeService, serviceInfo.ServiceName)
+
+	middleware.ResponseSuccess(c, "")
+}
+
+// ServiceCircuitState godoc
+// @Summary 服务熔断状态修改
+// @Description 手动熔断或恢复服务及其所有上游节点 手动熔断不随熔断持续时间恢复 代理节点重启后保持 直到手动恢复
+// @Tags 服务管理
+// @ID /service/service_circuit_state
+// @Accept  json
+// @Produce  json
+// @Param body body dto.ServiceCircuitStateInput true "body"
+// @Success 200 {object} middleware.Response{data=string} "success"
+// @Router /service/service_circuit_state [post]
+func (serviceController *ServiceController) ServiceCircuitState(c *gin.Context) {
+	serviceCircuitStateInput := &dto.ServiceCircuitStateInput{}
+	if err := serviceCircuitStateInput.BindValidParam(c); err != nil {
+		middleware.ResponseError(c, 3181, err)
+		return
+	}
+
+	//获取数据库连接池
+	tx, err := lib.GetGormPool("default")
+	if err != nil {
+		middleware.ResponseError(c, 3182, err)
+		return
+	}
+
+	//查看服务是否存在
+	serviceInfo := &dao.ServiceInfo{ID: serviceCircuitStateInput.ID}
+	if err := serviceInfo.Find(c, tx); err != nil || serviceInfo.ServiceName == "" {
+		middleware.ResponseError(c, 3183, errors.New("服务不存在"))
+		return
+	}
+
+	//手动熔断保存在redis中 代理节点启动及定时同步时恢复 直到手动恢复
+	if err := circuit_rate.SaveForcedState(serviceInfo.ServiceName, serviceCircuitStateInput.State); err != nil {
+		middleware.ResponseError(c, 3184, err)
+		return
+	}
+
+	//熔断器状态保存在各代理节点内存中 通过配置变更频道通知所有代理节点立即生效
+	if err := dao.PublishConfigChangeData(public.ConfigChangeTypeCircuit, serviceInfo.ServiceName, serviceCircuitStateInput.State); err != nil {
+		middleware.ResponseError(c, 3185, err)
+		return
+	}
+
+	middleware.ResponseSuccess(c, "")
+}
+
+// ServiceAddTCP godoc
+// @Summary TCP服务新增
+// @Description TCP服务新增
+// @Tags 服务管理
+// @ID /service/service_add_tcp
+// @Accept  json
+// @Produce  json
+// @Param body body dto.ServiceAddTCPInput true "body"
+// @Success 200 {object} middleware.Response{data=string} "success"
+// @Router /service/service_add_tcp [post]
+func (serviceController *ServiceController) ServiceAddTCP(c *gin.Context) {
+	serviceAddTCPInput := &dto.ServiceAddTCPInput{}
+	if err := serviceAddTCPInput.BindValidParam(c); err != nil {
+		middleware.ResponseError(c, 3071, err)
+		return
+	}
+
+	//获取数据库连接池
+	tx, err := lib.GetGormPool("default")
+	if err != nil {
+		middleware.ResponseError(c, 3072, err)
+		return
+	}
+
+	//开启事务
+	tx = tx.Begin()
+
+	//查看服务名称是否被占用
+	serviceInfo := &dao.ServiceInfo{
+		ServiceName: serviceAddTCPInput.ServiceName,
+	}
+	serviceInfo.Find(c, tx)
+	if serviceInfo.ID != 0 {
+		tx.Rollback()
+		middleware.ResponseError(c, 3073, errors.New("服务已经存在"))
+		return
+	}
+
+	//验证端口是否被tcp占用
+	tcpRule := &dao.TcpRule{
+		Port: serviceAddTCPInput.Port,
+	}
+	if tcpRule.Find(c, tx); tcpRule.ID != 0 {
+		tx.Rollback()
+		middleware.ResponseError(c, 3074, errors.New("服务端口被占用，请重新输入"))
+		return
+	}
+
+	//验证端口是否被grpc占用
+	grpcRule := &dao.GrpcRule{
+		Port: serviceAddTCPInput.Port,
+	}
+	if grpcRule.Find(c, tx); grpcRule.ID != 0 {
+		tx.Rollback()
+		middleware.ResponseError(c, 3075, errors.New("服务端口被占用，请重新输入"))
+		return
+	}
+
+	//保存服务基本信息
+	serviceInfo.ServiceDesc = serviceAddTCPInput.ServiceDesc
+	serviceInfo.LoadType = public.LoadTypeTCP
+	if err := serviceInfo.Save(c, tx); err != nil {
+		tx.Rollback()
+		middleware.ResponseError(c, 3076, err)
+		return
+	}
+
+	serviceId := serviceInfo.ID
+	//保存grpc服务的规则信息
+	tcpRule.ServiceID = serviceId
+	if err := tcpRule.Save(c, tx); err != nil {
+		tx.Rollback()
+		middleware.ResponseError(c, 3077, err)
+		return
+	}
+
+	//保存grpc服务的权限控制信息
+	accessControl := &dao.AccessControl{
+		ServiceID:         serviceId,
+		OpenAuth:          serviceAddTCPInput.OpenAuth,
+		BlackList:         serviceAddTCPInput.BlackList,
+		WhiteList:         serviceAddTCPInput.WhiteList,
+		WhiteHostName:     serviceAddTCPInput.WhiteHostName,
+		ClientIPFlowLimit: serviceAddTCPInput.ClientIPFlowLimit,
+		ServiceFlowLimit:  serviceAddTCPInput.ServiceFlowLimit,
+	}
+	if err := accessControl.Save(c, tx); err != nil {
+		tx.Rollback()
+		middleware.ResponseError(c, 3078, err)
+		return
+	}
+
+	//保存服务负载均衡信息
+	loadBalance := &dao.LoadBalance{
+		ServiceID:         serviceId,
+		RoundType:         serviceAddTCPInput.RoundType,
+		IpList:            serviceAddTCPInput.IpList,
+		WeightList:        serviceAddTCPInput.WeightList,
+		ForbidList:        serviceAddTCPInput.ForbidList,
+		OutlierFailures:   serviceAddTCPInput.OutlierFailures,
+		OutlierEjectTime:  serviceAddTCPInput.OutlierEjectTime,
+		OutlierMaxPercent: serviceAddTCPInput.OutlierMaxPercent,
+		HashReplicas:      serviceAddTCPInput.HashReplicas,
+		HashLoadFactor:    serviceAddTCPInput.HashLoadFactor,
+		SlowStart:         serviceAddTCPInput.SlowStart,
+	}
+	if err := loadBalance.Save(c, tx); err != nil {
+		tx.Rollback()
+		middleware.ResponseError(c, 3079, err)
+		return
+	}
+
+	//保存服务配置版本快照
+	if _, err := dao.SaveServiceVersion(c, tx, serviceInfo, adminUserName(c), ""); err != nil {
+		tx.Rollback()
+		middleware.ResponseError(c, 3080, err)
+		return
+	}
+
+	//提交事务
+	tx.Commit()
+
+	//通知所有代理节点同步服务变更
+	publishConfigChange(c, public.ConfigChangeTypeService, serviceInfo.ServiceName)
+
+	middleware.ResponseSuccess(c, serviceInfo.ID)
+}
+
+// ServiceUpdateTCP godoc
+// @Summary TCP服务更新
+// @Description TCP服务更新
+// @Tags 服务管理
+// @ID /service/service_update_tcp
+// @Accept  json
+// @Produce  json
+// @Param body body dto.ServiceUpdateTCPInput true "body"
+// @Success 200 {object} middleware.Response{data=string} "success"
+// @Router /service/service_update_tcp [put]
+func (serviceController *ServiceController) ServiceUpdateTCP(c *gin.Context) {
+	serviceUpdateTCPInput := &dto.ServiceUpdateTCPInput{}
+	if err := serviceUpdateTCPInput.BindValidParam(c); err != nil {
+		middleware.ResponseError(c, 3081, err)
+		return
+	}
+
+	//获取数据库连接池
+	tx, err := lib.GetGormPool("default")
+	if err != nil {
+		middleware.ResponseError(c, 3082, err)
+		return
+	}
+
+	//开启事务
+	tx = tx.Begin()
+
+	//查看服务是否存在
+	serviceInfo := &dao.ServiceInfo{
+		ID:          serviceUpdateTCPInput.ID,
+		ServiceName: serviceUpdateTCPInput.ServiceName,
+		IsDelete:    0,
+	}
+
+	if err := serviceInfo.Find(c, tx); err != nil {
+		tx.Rollback()
+		middleware.ResponseError(c, 3083, errors.New("服务不存在"))
+		return
+	}
+
+	//查询服务详情
+	serviceDetail, err := serviceInfo.ServiceDetail(c, tx)
+	if err != nil {
+		tx.Rollback()
+		middleware.ResponseError(c, 3084, errors.New("服务不存在"))
+		return
+	}
+
+	//更新服务基本信息
+	serviceInfo.ServiceDesc = serviceUpdateTCPInput.ServiceDesc
+	if err := serviceInfo.Save(c, tx); err != nil {
+		tx.Rollback()
+		middleware.ResponseError(c, 3085, err)
+		return
+	}
+
+	//更新grpc服务的规则信息
+	tcpRule := serviceDetail.TCPRule
+	tcpRule.Port = serviceUpdateTCPInput.Port
+	if err := tcpRule.Save(c, tx); err != nil {
+		tx.Rollback()
+		middleware.ResponseError(c, 3086, err)
+		return
+	}
+
+	//更新grpc服务的权限控制信息
+	accessControl := serviceDetail.AccessControl
+	accessControl.OpenAuth = serviceUpdateTCPInput.OpenAuth
+	accessControl.BlackList = serviceUpdateTCPInput.BlackList
+	accessControl.WhiteList = serviceUpdateTCPInput.WhiteList
+	accessControl.WhiteHostName = serviceUpdateTCPInput.WhiteHostName
+	accessControl.ClientIPFlowLimit = serviceUpdateTCPInput.ClientIPFlowLimit
+	accessControl.ServiceFlowLimit = serviceUpdateTCPInput.ServiceFlowLimit
+	if err := accessControl.Save(c, tx); err != nil {
+		tx.Rollback()
+		middleware.ResponseError(c, 3087, err)
+		return
+	}
+
+	//更新服务负载均衡信息
+	loadBalance := serviceDetail.LoadBalance
+	loadBalance.RoundType = serviceUpdateTCPInput.RoundType
+	loadBalance.IpList = serviceUpdateTCPInput.IpList
+	loadBalance.WeightList = serviceUpdateTCPInput.WeightList
+	loadBalance.ForbidList = serviceUpdateTCPInput.ForbidList
+	loadBalance.OutlierFailures = serviceUpdateTCPInput.OutlierFailures
+	loadBalance.OutlierEjectTime = serviceUpdateTCPInput.OutlierEjectTime
+	loadBalance.OutlierMaxPercent = serviceUpdateTCPInput.OutlierMaxPercent
+	loadBalance.HashReplicas = serviceUpdateTCPInput.HashReplicas
+	loadBalance.HashLoadFactor = serviceUpdateTCPInput.HashLoadFactor
+	loadBalance.SlowStart = serviceUpdateTCPInput.SlowStart
+	if err := loadBalance.Save(c, tx); err != nil {
+		tx.Rollback()
+		middleware.ResponseError(c, 3088, err)
+		return
+	}
+
+	//保存服务配置版本快照
+	if _, err := dao.SaveServiceVersion(c, tx, serviceInfo, adminUserName(c), ""); err != nil {
+		tx.Rollback()
+		middleware.ResponseError(c, 3089, err)
+		return
+	}
+
+	//提交事务
+	tx.Commit()
+
+	//通知所有代理节点同步服务变更
+	publishConfigChange(c, public.ConfigChangeTypeService, serviceInfo.ServiceName)
+
+	middleware.ResponseSuccess(c, serviceInfo.ID)
+}
+
+// ServiceAddGRPC godoc
+// @Summary GRPC服务新增
+// @Description GRPC服务新增
+// @Tags 服务管理
+// @ID /service/service_add_grpc
+// @Accept  json
+// @Produce  json
+// @Param body body dto.ServiceAddGRPCInput true "body"
+// @Success 200 {object} middleware.Response{data=string} "success"
+// @Router /service/service_add_grpc [post]
+func (serviceController *ServiceController) ServiceAddGRPC(c *gin.Context) {
+	serviceAddGRPCInput := &dto.ServiceAddGRPCInput{}
+	if err := serviceAddGRPCInput.BindValidParam(c); err != nil {
+		middleware.ResponseError(c, 3091, err)
+		return
+	}
+
+	//获取数据库连接池
+	tx, err := lib.GetGormPool("default")
+	if err != nil {
+		middleware.ResponseError(c, 3092, err)
+		return
+	}
+
+	//开启事务
+	tx = tx.Begin()
+
+	//查看服务名称是否被占用
+	serviceInfo := &dao.ServiceInfo{
+		ServiceName: serviceAddGRPCInput.ServiceName,
+	}
+	serviceInfo.Find(c, tx)
+	if serviceInfo.ID != 0 {
+		tx.Rollback()
+		middleware.ResponseError(c, 3093, errors.New("服务已经存在"))
+		return
+	}
+
+	//验证端口是否被tcp占用
+	tcpRule := &dao.TcpRule{
+		Port: serviceAddGRPCInput.Port,
+	}
+	if err := tcpRule.Find(c, tx); err != nil {
+		tx.Rollback()
+		middleware.ResponseError(c, 3094, errors.New("服务端口被占用，请重新输入"))
+		return
+	}
+
+	//验证端口是否被grpc占用
+	grpcRule := &dao.GrpcRule{
+		Port: serviceAddGRPCInput.Port,
+	}
+	if err := grpcRule.Find(c, tx); err != nil {
+		tx.Rollback()
+		middleware.ResponseError(c, 3095, errors.New("服务端口被占用，请重新输入"))
+		return
+	}
+
+	//保存服务基本信息
+	serviceInfo.ServiceDesc = serviceAddGRPCInput.ServiceDesc
+	serviceInfo.LoadType = public.LoadTypeGRPC
+	if err := serviceInfo.Save(c, tx); err != nil {
+		tx.Rollback()
+		middleware.ResponseError(c, 3096, err)
+		return
+	}
+
+	serviceId := serviceInfo.ID
+	//保存grpc服务的规则信息
+	grpcRule.ServiceID = serviceId
+	grpcRule.HeaderTransfer = serviceAddGRPCInput.HeaderTransfer
+	if err := grpcRule.Save(c, tx); err != nil {
+		tx.Rollback()
+		middleware.ResponseError(c, 3097, err)
+		return
+	}
+
+	//保存grpc服务的权限控制信息
+	accessControl := &dao.AccessControl{
+		ServiceID:         serviceId,
+		OpenAuth:          serviceAddGRPCInput.OpenAuth,
+		BlackList:         serviceAddGRPCInput.BlackList,
+		WhiteList:         serviceAddGRPCInput.WhiteList,
+		WhiteHostName:     serviceAddGRPCInput.WhiteHostName,
+		ClientIPFlowLimit: serviceAddGRPCInput.ClientIPFlowLimit,
 		ServiceFlowLimit:  serviceAddGRPCInput.ServiceFlowLimit,
 	}
 	if err := accessControl.Save(c, tx); err != nil {
@@ -796,11 +1761,18 @@ func (serviceController *ServiceController) ServiceAddGRPC(c *gin.Context) {
 
 	//保存服务负载均衡信息
 	loadBalance := &dao.LoadBalance{
-		ServiceID:  serviceId,
-		RoundType:  serviceAddGRPCInput.RoundType,
-		IpList:     serviceAddGRPCInput.IpList,
-		WeightList: serviceAddGRPCInput.WeightList,
-		ForbidList: serviceAddGRPCInput.ForbidList,
+		ServiceID:         serviceId,
+		RoundType:         serviceAddGRPCInput.RoundType,
+		IpList:            serviceAddGRPCInput.IpList,
+		WeightList:        serviceAddGRPCInput.WeightList,
+		ForbidList:        serviceAddGRPCInput.ForbidList,
+		OutlierFailures:   serviceAddGRPCInput.OutlierFailures,
+		OutlierEjectTime:  serviceAddGRPCInput.OutlierEjectTime,
+		OutlierMaxPercent: serviceAddGRPCInput.OutlierMaxPercent,
+		HashKey:           serviceAddGRPCInput.HashKey,
+		HashReplicas:      serviceAddGRPCInput.HashReplicas,
+		HashLoadFactor:    serviceAddGRPCInput.HashLoadFactor,
+		SlowStart:         serviceAddGRPCInput.SlowStart,
 	}
 	if err := loadBalance.Save(c, tx); err != nil {
 		tx.Rollback()
@@ -808,9 +1780,19 @@ func (serviceController *ServiceController) ServiceAddGRPC(c *gin.Context) {
 		return
 	}
 
+	//保存服务配置版本快照
+	if _, err := dao.SaveServiceVersion(c, tx, serviceInfo, adminUserName(c), ""); err != nil {
+		tx.Rollback()
+		middleware.ResponseError(c, 3100, err)
+		return
+	}
+
 	//提交事务
 	tx.Commit()
 
+	//通知所有代理节点同步服务变更
+	publishConfigChange(c, public.ConfigChangeTypeService, serviceInfo.ServiceName)
+
 	middleware.ResponseSuccess(c, serviceInfo.ID)
 }
 
@@ -900,14 +1882,325 @@ func (serviceController *ServiceController) ServiceUpdateGRPC(c *gin.Context) {
 	loadBalance.IpList = serviceUpdateGRPCInput.IpList
 	loadBalance.WeightList = serviceUpdateGRPCInput.WeightList
 	loadBalance.ForbidList = serviceUpdateGRPCInput.ForbidList
+	loadBalance.OutlierFailures = serviceUpdateGRPCInput.OutlierFailures
+	loadBalance.OutlierEjectTime = serviceUpdateGRPCInput.OutlierEjectTime
+	loadBalance.OutlierMaxPercent = serviceUpdateGRPCInput.OutlierMaxPercent
+	loadBalance.HashKey = serviceUpdateGRPCInput.HashKey
+	loadBalance.HashReplicas = serviceUpdateGRPCInput.HashReplicas
+	loadBalance.HashLoadFactor = serviceUpdateGRPCInput.HashLoadFactor
+	loadBalance.SlowStart = serviceUpdateGRPCInput.SlowStart
 	if err := loadBalance.Save(c, tx); err != nil {
 		tx.Rollback()
 		middleware.ResponseError(c, 3108, err)
 		return
 	}
 
+	//保存服务配置版本快照
+	if _, err := dao.SaveServiceVersion(c, tx, serviceInfo, adminUserName(c), ""); err != nil {
+		tx.Rollback()
+		middleware.ResponseError(c, 3109, err)
+		return
+	}
+
 	//提交事务
 	tx.Commit()
 
+	//通知所有代理节点同步服务变更
+	publishConfigChange(c, public.ConfigChangeTypeService, serviceInfo.ServiceName)
+
 	middleware.ResponseSuccess(c, serviceInfo.ID)
 }
+
+// ServiceVersionList godoc
+// @Summary 服务配置版本列表查询
+// @Description 服务配置版本列表查询
+// @Tags 服务管理
+// @ID /service/service_version_list
+// @Accept  json
+// @Produce  json
+// @Param id query int64 true "服务id"
+// @Param page_num query int64 true "页码"
+// @Param page_size query int64 true "条数"
+// @Success 200 {object} middleware.Response{data=dto.ServiceVersionListOutput} "success"
+// @Router /service/service_version_list [get]
+func (serviceController *ServiceController) ServiceVersionList(c *gin.Context) {
+	serviceVersionListInput := &dto.ServiceVersionListInput{}
+	if err := serviceVersionListInput.BindValidParam(c); err != nil {
+		middleware.ResponseError(c, 3111, err)
+		return
+	}
+
+	//获取数据库连接池
+	tx, err := lib.GetGormPool("default")
+	if err != nil {
+		middleware.ResponseError(c, 3112, err)
+		return
+	}
+
+	//分页查询服务的版本信息
+	serviceVersion := &dao.ServiceVersion{}
+	serviceVersionList, total, err := serviceVersion.PageList(c, tx, serviceVersionListInput)
+	if err != nil {
+		middleware.ResponseError(c, 3113, err)
+		return
+	}
+
+	serviceVersionOutList := []dto.ServiceVersionListItemOutput{}
+	for _, serviceVersionItem := range serviceVersionList {
+		serviceVersionOutList = append(serviceVersionOutList, dto.ServiceVersionListItemOutput{
+			Id:        serviceVersionItem.ID,
+			Version:   serviceVersionItem.Version,
+			Author:    serviceVersionItem.Author,
+			Remark:    serviceVersionItem.Remark,
+			CreatedAt: serviceVersionItem.CreatedAt,
+		})
+	}
+
+	//封装输出信息
+	out := &dto.ServiceVersionListOutput{
+		Total: total,
+		List:  serviceVersionOutList,
+	}
+	middleware.ResponseSuccess(c, out)
+}
+
+// ServiceVersionDiff godoc
+// @Summary 服务配置版本对比
+// @Description 服务配置版本对比
+// @Tags 服务管理
+// @ID /service/service_version_diff
+// @Accept  json
+// @Produce  json
+// @Param id query int64 true "服务id"
+// @Param from_version query int true "对比的起始版本"
+// @Param to_version query int true "对比的目标版本"
+// @Success 200 {object} middleware.Response{data=dto.ServiceVersionDiffOutput} "success"
+// @Router /service/service_version_diff [get]
+func (serviceController *ServiceController) ServiceVersionDiff(c *gin.Context) {
+	serviceVersionDiffInput := &dto.ServiceVersionDiffInput{}
+	if err := serviceVersionDiffInput.BindValidParam(c); err != nil {
+		middleware.ResponseError(c, 3121, err)
+		return
+	}
+
+	//获取数据库连接池
+	tx, err := lib.GetGormPool("default")
+	if err != nil {
+		middleware.ResponseError(c, 3122, err)
+		return
+	}
+
+	//查询两个版本的服务详情快照
+	serviceDetails := []*dao.ServiceDetail{}
+	for _, version := range []int{serviceVersionDiffInput.FromVersion, serviceVersionDiffInput.ToVersion} {
+		serviceVersion := &dao.ServiceVersion{ServiceID: serviceVersionDiffInput.ID, Version: version}
+		if err := serviceVersion.Find(c, tx); err != nil {
+			middleware.ResponseError(c, 3123, err)
+			return
+		}
+		if serviceVersion.ID == 0 {
+			middleware.ResponseError(c, 3124, errors.Errorf("版本%d不存在", version))
+			return
+		}
+		serviceDetail, err := serviceVersion.ServiceDetail()
+		if err != nil {
+			middleware.ResponseError(c, 3125, err)
+			return
+		}
+		serviceDetails = append(serviceDetails, serviceDetail)
+	}
+
+	//对比两个版本
+	diffList, err := dao.DiffServiceDetail(serviceDetails[0], serviceDetails[1])
+	if err != nil {
+		middleware.ResponseError(c, 3126, err)
+		return
+	}
+
+	//封装输出信息
+	out := &dto.ServiceVersionDiffOutput{
+		FromVersion: serviceVersionDiffInput.FromVersion,
+		ToVersion:   serviceVersionDiffInput.ToVersion,
+		List:        diffList,
+	}
+	middleware.ResponseSuccess(c, out)
+}
+
+// ServiceVersionRollback godoc
+// @Summary 服务配置版本回滚
+// @Description 服务配置版本回滚 回滚后生成一个新的版本
+// @Tags 服务管理
+// @ID /service/service_version_rollback
+// @Accept  json
+// @Produce  json
+// @Param body body dto.ServiceVersionRollbackInput true "body"
+// @Success 200 {object} middleware.Response{data=int} "success"
+// @Router /service/service_version_rollback [post]
+func (serviceController *ServiceController) ServiceVersionRollback(c *gin.Context) {
+	serviceVersionRollbackInput := &dto.ServiceVersionRollbackInput{}
+	if err := serviceVersionRollbackInput.BindValidParam(c); err != nil {
+		middleware.ResponseError(c, 3131, err)
+		return
+	}
+
+	//获取数据库连接池
+	tx, err := lib.GetGormPool("default")
+	if err != nil {
+		middleware.ResponseError(c, 3132, err)
+		return
+	}
+
+	//开启事务
+	tx = tx.Begin()
+
+	//查看服务是否存在
+	serviceInfo := &dao.ServiceInfo{ID: serviceVersionRollbackInput.ID}
+	if err := serviceInfo.Find(c, tx); err != nil || serviceInfo.ServiceName == "" {
+		tx.Rollback()
+		middleware.ResponseError(c, 3133, errors.New("服务不存在"))
+		return
+	}
+
+	//查询回滚的目标版本
+	serviceVersion := &dao.ServiceVersion{ServiceID: serviceInfo.ID, Version: serviceVersionRollbackInput.Version}
+	if err := serviceVersion.Find(c, tx); err != nil || serviceVersion.ID == 0 {
+		tx.Rollback()
+		middleware.ResponseError(c, 3134, errors.New("版本不存在"))
+		return
+	}
+	targetDetail, err := serviceVersion.ServiceDetail()
+	if err != nil {
+		tx.Rollback()
+		middleware.ResponseError(c, 3135, err)
+		return
+	}
+	if targetDetail.Info.LoadType != serviceInfo.LoadType {
+		tx.Rollback()
+		middleware.ResponseError(c, 3136, errors.New("版本的服务类型与当前服务不一致"))
+		return
+	}
+
+	//查询服务当前的详情 回滚时沿用当前各规则记录的主键
+	serviceDetail, err := serviceInfo.ServiceDetail(c, tx)
+	if err != nil {
+		tx.Rollback()
+		middleware.ResponseError(c, 3137, err)
+		return
+	}
+
+	//更新服务基本信息
+	serviceInfo.ServiceDesc = targetDetail.Info.ServiceDesc
+	if err := serviceInfo.Save(c, tx); err != nil {
+		tx.Rollback()
+		middleware.ResponseError(c, 3138, err)
+		return
+	}
+
+	//按服务类型回滚对应的规则信息 接入前缀、域名或端口已被其他服务占用时不允许回滚
+	switch serviceInfo.LoadType {
+	case public.LoadTypeHTTP:
+		httpRule := &dao.HttpRule{RuleType: targetDetail.HTTPRule.RuleType, Rule: targetDetail.HTTPRule.Rule}
+		httpRule.Find(c, tx)
+		if httpRule.ID != 0 && httpRule.ServiceID != serviceInfo.ID {
+			tx.Rollback()
+			middleware.ResponseError(c, 3139, errors.New("服务接入前缀或域名已存在"))
+			return
+		}
+		//版本中灰度路由规则的目标服务或分组需仍然存在
+		if err := dao.ValidCanaryTarget(c, tx, serviceInfo.ServiceName, targetDetail.HTTPRule.CanaryRule, targetDetail.LoadBalance.GroupWeight); err != nil {
+			tx.Rollback()
+			middleware.ResponseError(c, 3144, err)
+			return
+		}
+		httpRule = targetDetail.HTTPRule
+		httpRule.ID = serviceDetail.HTTPRule.ID
+		httpRule.ServiceID = serviceInfo.ID
+		err = httpRule.Save(c, tx)
+	case public.LoadTypeTCP, public.LoadTypeGRPC:
+		port := targetDetail.TCPRule.Port
+		if serviceInfo.LoadType == public.LoadTypeGRPC {
+			port = targetDetail.GRPCRule.Port
+		}
+		tcpRule := &dao.TcpRule{Port: port}
+		tcpRule.Find(c, tx)
+		grpcRule := &dao.GrpcRule{Port: port}
+		grpcRule.Find(c, tx)
+		if (tcpRule.ID != 0 && tcpRule.ServiceID != serviceInfo.ID) || (grpcRule.ID != 0 && grpcRule.ServiceID != serviceInfo.ID) {
+			tx.Rollback()
+			middleware.ResponseError(c, 3139, errors.New("服务端口被占用"))
+			return
+		}
+		if serviceInfo.LoadType == public.LoadTypeTCP {
+			tcpRule = targetDetail.TCPRule
+			tcpRule.ID = serviceDetail.TCPRule.ID
+			tcpRule.ServiceID = serviceInfo.ID
+			err = tcpRule.Save(c, tx)
+		} else {
+			grpcRule = targetDetail.GRPCRule
+			grpcRule.ID = serviceDetail.GRPCRule.ID
+			grpcRule.ServiceID = serviceInfo.ID
+			err = grpcRule.Save(c, tx)
+		}
+	}
+	if err != nil {
+		tx.Rollback()
+		middleware.ResponseError(c, 3140, err)
+		return
+	}
+
+	//回滚服务的权限控制信息
+	accessControl := targetDetail.AccessControl
+	accessControl.ID = serviceDetail.AccessControl.ID
+	accessControl.ServiceID = serviceInfo.ID
+	if err := accessControl.Save(c, tx); err != nil {
+		tx.Rollback()
+		middleware.ResponseError(c, 3141, err)
+		return
+	}
+
+	//回滚服务负载均衡信息
+	loadBalance := targetDetail.LoadBalance
+	loadBalance.ID = serviceDetail.LoadBalance.ID
+	loadBalance.ServiceID = serviceInfo.ID
+	if err := loadBalance.Save(c, tx); err != nil {
+		tx.Rollback()
+		middleware.ResponseError(c, 3142, err)
+		return
+	}
+
+	//回滚后的配置作为一个新的版本保存
+	newServiceVersion, err := dao.SaveServiceVersion(c, tx, serviceInfo, adminUserName(c), fmt.Sprintf("回滚至版本%d", serviceVersion.Version))
+	if err != nil {
+		tx.Rollback()
+		middleware.ResponseError(c, 3143, err)
+		return
+	}
+
+	//提交事务
+	tx.Commit()
+
+	//通知所有代理节点同步服务变更
+	publishConfigChange(c, public.ConfigChangeTypeService, serviceInfo.ServiceName)
+
+	middleware.ResponseSuccess(c, newServiceVersion.Version)
+}
+
+// 获取当前登录的管理员用户名 作为配置版本的操作人
+func adminUserName(c *gin.Context) string {
+	adminSessionInfo := &dto.AdminSessionInfo{}
+	if err := json.Unmarshal([]byte(fmt.Sprint(sessions.Default(c).Get(public.AdminSessionInfoKey))), adminSessionInfo); err != nil {
+		return ""
+	}
+	return adminSessionInfo.UserName
+}
+
+// 发布配置变更事件 发布失败时仅记录日志 代理节点会在下次版本检查时同步
+func publishConfigChange(c *gin.Context, changeType, name string) {
+	if err := dao.PublishConfigChange(changeType, name); err != nil {
+		public.ComLogWarning(c, "_com_config_change_publish_failure", map[string]interface{}{
+			"type": changeType,
+			"name": name,
+			"err":  err.Error(),
+		})
+	}
+}
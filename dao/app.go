@@ -1,10 +1,13 @@
 package dao
 
 import (
+	"database/sql"
+	"fmt"
 	"github.com/e421083458/golang_common/lib"
 	"github.com/gin-gonic/gin"
 	"github.com/starMoonZhao/go_gateway/dto"
 	"gorm.io/gorm"
+	"log"
 	"net/http/httptest"
 	"sync"
 	"time"
@@ -76,6 +79,18 @@ func (app *APP) PageList(c *gin.Context, tx *gorm.DB, param *dto.APPListInput) (
 	return list, total, nil
 }
 
+// 租户信息版本查询：以记录总数及最近更新时间标识租户信息是否发生变化（删除为软删除 同样会刷新更新时间）
+func (app *APP) Version(c *gin.Context, tx *gorm.DB) (string, error) {
+	version := &struct {
+		Total    int64
+		UpdateAt sql.NullTime
+	}{}
+	if err := tx.WithContext(c).Table(app.TableName()).Select("count(*) as total", "max(update_at) as update_at").Scan(version).Error; err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("%d_%d", version.Total, version.UpdateAt.Time.UnixNano()), nil
+}
+
 var AppManegerHandler *AppManger
 
 func init() {
@@ -83,11 +98,13 @@ func init() {
 }
 
 type AppManger struct {
-	AppMap   map[string]*APP
-	AppSlice []*APP
-	Locker   sync.RWMutex
-	init     sync.Once
-	err      error
+	AppMap       map[string]*APP
+	AppSlice     []*APP
+	Locker       sync.RWMutex
+	init         sync.Once
+	err          error
+	version      string     //当前已加载的租户信息版本
+	reloadLocker sync.Mutex //保证同一时刻只有一个加载任务
 }
 
 func NewAppManager() *AppManger {
@@ -102,35 +119,124 @@ func NewAppManager() *AppManger {
 // 系统初始化时加载租户信息
 func (appManger *AppManger) LoadOnce() error {
 	appManger.init.Do(func() {
-		//查询所有的租户信息
-		c, _ := gin.CreateTestContext(httptest.NewRecorder())
-		tx, err := lib.GetGormPool("default")
-		if err != nil {
-			appManger.err = err
-			return
+		appManger.err = appManger.ReLoad()
+	})
+	return appManger.err
+}
+
+// 重新加载租户信息：从配置来源查询出全部租户后构建新的AppMap、AppSlice 再整体替换
+func (appManger *AppManger) ReLoad() error {
+	appManger.reloadLocker.Lock()
+	defer appManger.reloadLocker.Unlock()
+
+	//先记录版本 加载期间发生的变更会在下次版本检查时再次加载
+	version, err := ConfigSourceHandler.AppVersion()
+	if err != nil {
+		return err
+	}
+	//从配置来源查询所有的租户信息
+	list, err := ConfigSourceHandler.LoadAppList()
+	if err != nil {
+		return err
+	}
+
+	//将查询出的所有租户填充到新的AppMap、AppSlice中
+	appMap := map[string]*APP{}
+	appSlice := []*APP{}
+	for _, appItem := range list {
+		appMap[appItem.APPID] = appItem
+		appSlice = append(appSlice, appItem)
+	}
+
+	//整体替换
+	appManger.Locker.Lock()
+	appManger.AppMap = appMap
+	appManger.AppSlice = appSlice
+	appManger.version = version
+	appManger.Locker.Unlock()
+	log.Printf(" [INFO] AppManger ReLoad version:%s apps:%d\n", version, len(appSlice))
+	return nil
+}
+
+// 重新加载单个租户：租户已删除时将其移除 否则新增或替换该租户
+func (appManger *AppManger) ReLoadApp(appID string) error {
+	appManger.reloadLocker.Lock()
+	defer appManger.reloadLocker.Unlock()
+
+	c, _ := gin.CreateTestContext(httptest.NewRecorder())
+	tx, err := lib.GetGormPool("default")
+	if err != nil {
+		return err
+	}
+	//查询未删除的租户信息
+	app := &APP{}
+	if err := tx.WithContext(c).Where("app_id = ? and is_delete = 0", appID).Find(app).Error; err != nil && err != gorm.ErrRecordNotFound {
+		return err
+	}
+
+	//复制当前的AppMap、AppSlice后替换该租户 保持按id倒序排列
+	appManger.Locker.RLock()
+	oldAppSlice := appManger.AppSlice
+	appManger.Locker.RUnlock()
+	appMap := map[string]*APP{}
+	appSlice := []*APP{}
+	for _, appItem := range oldAppSlice {
+		if appItem.APPID == appID {
+			continue
 		}
-		//分页条件
-		params := &dto.APPListInput{PageNum: 1, PageSize: 99999}
-		app := &APP{}
-		list, _, err := app.PageList(c, tx, params)
-		if err != nil {
-			appManger.err = err
-			return
+		if app.ID != 0 && appItem.ID < app.ID && appMap[appID] == nil {
+			appMap[appID] = app
+			appSlice = append(appSlice, app)
 		}
+		appMap[appItem.APPID] = appItem
+		appSlice = append(appSlice, appItem)
+	}
+	if app.ID != 0 && appMap[appID] == nil {
+		appMap[appID] = app
+		appSlice = append(appSlice, app)
+	}
 
-		appManger.Locker.Lock()
-		defer appManger.Locker.Unlock()
-		//将查询出的所有租户填充到AppManger中的AppMap、AppSlice
-		for _, appItem := range list {
-			tmpAppItem := appItem
-			appManger.AppMap[tmpAppItem.APPID] = &tmpAppItem
-			appManger.AppSlice = append(appManger.AppSlice, &tmpAppItem)
+	appManger.Locker.Lock()
+	appManger.AppMap = appMap
+	appManger.AppSlice = appSlice
+	appManger.Locker.Unlock()
+	log.Printf(" [INFO] AppManger ReLoadApp app:%s exist:%v\n", appID, app.ID != 0)
+	return nil
+}
+
+// 定时检查租户信息版本 版本发生变化时重新加载
+func (appManger *AppManger) WatchReLoad(interval time.Duration) {
+	if interval <= 0 {
+		return
+	}
+	go func() {
+		for {
+			time.Sleep(interval)
+			version, err := ConfigSourceHandler.AppVersion()
+			if err != nil {
+				log.Printf(" [ERROR] AppManger WatchReLoad err:%v\n", err)
+				continue
+			}
+			if version == appManger.GetVersion() {
+				continue
+			}
+			if err := appManger.ReLoad(); err != nil {
+				log.Printf(" [ERROR] AppManger ReLoad err:%v\n", err)
+			}
 		}
-	})
-	return appManger.err
+	}()
+}
+
+// 获取当前已加载的租户信息版本
+func (appManger *AppManger) GetVersion() string {
+	appManger.Locker.RLock()
+	defer appManger.Locker.RUnlock()
+	return appManger.version
 }
 
 // 获取全部租户
 func (appManger *AppManger) GetAppList() []*APP {
+	appManger.Locker.RLock()
+	defer appManger.Locker.RUnlock()
 	return appManger.AppSlice
 }
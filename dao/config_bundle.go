@@ -0,0 +1,371 @@
+package dao
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/pkg/errors"
+	"github.com/starMoonZhao/go_gateway/dto"
+	"github.com/starMoonZhao/go_gateway/public"
+	"github.com/starMoonZhao/go_gateway/reverse_proxy/load_balance"
+	"gorm.io/gorm"
+	"time"
+)
+
+// 配置包格式版本 导入时只接受相同版本的配置包
+const ConfigBundleVersion = 1
+
+// 配置包：包含全部服务(基本信息、各类规则、负载均衡及权限控制)及租户
+// 字段与配置文件来源一致 导出的配置包可以直接作为配置文件使用
+type ConfigBundle struct {
+	Version  int              `json:"version"`
+	ExportAt time.Time        `json:"export_at"`
+	Service  []*ServiceDetail `json:"service"`
+	App      []*APP           `json:"app"`
+}
+
+// 导出全部服务及租户
+func ExportConfigBundle(c *gin.Context, tx *gorm.DB) (*ConfigBundle, error) {
+	bundle := &ConfigBundle{
+		Version:  ConfigBundleVersion,
+		ExportAt: time.Now(),
+		Service:  []*ServiceDetail{},
+		App:      []*APP{},
+	}
+
+	serviceInfo := &ServiceInfo{}
+	serviceList, _, err := serviceInfo.PageList(c, tx, &dto.ServiceListInput{PageNum: 1, PageSize: 99999})
+	if err != nil {
+		return nil, err
+	}
+	for _, serviceItem := range serviceList {
+		tmpServiceItem := serviceItem
+		serviceDetail, err := tmpServiceItem.ServiceDetail(c, tx)
+		if err != nil {
+			return nil, err
+		}
+		bundle.Service = append(bundle.Service, serviceDetail)
+	}
+
+	app := &APP{}
+	appList, _, err := app.PageList(c, tx, &dto.APPListInput{PageNum: 1, PageSize: 99999})
+	if err != nil {
+		return nil, err
+	}
+	for _, appItem := range appList {
+		tmpAppItem := appItem
+		bundle.App = append(bundle.App, &tmpAppItem)
+	}
+	return bundle, nil
+}
+
+// 校验配置包内容
+func (bundle *ConfigBundle) Valid() error {
+	if bundle.Version != ConfigBundleVersion {
+		return errors.Errorf("不支持的配置包版本:%d", bundle.Version)
+	}
+	serviceNames := map[string]bool{}
+	for _, serviceDetail := range bundle.Service {
+		if serviceDetail == nil || serviceDetail.Info == nil || serviceDetail.LoadBalance == nil || serviceDetail.AccessControl == nil {
+			return errors.New("服务缺少基本信息、负载均衡或权限控制配置")
+		}
+		serviceName := serviceDetail.Info.ServiceName
+		if serviceName == "" {
+			return errors.New("服务名称不能为空")
+		}
+		if serviceNames[serviceName] {
+			return errors.Errorf("服务%s重复", serviceName)
+		}
+		serviceNames[serviceName] = true
+		if !public.ValidIPWeightList(serviceDetail.LoadBalance.IpList, serviceDetail.LoadBalance.WeightList) {
+			return errors.Errorf("服务%s的ip列表与权重列表不匹配", serviceName)
+		}
+		if _, _, err := public.ParseStatusRange(serviceDetail.LoadBalance.CheckStatus); err != nil {
+			return errors.Errorf("服务%s的健康检查状态码范围格式不正确", serviceName)
+		}
+		if serviceDetail.LoadBalance.CheckMethod == load_balance.CheckMethodGRPC && serviceDetail.Info.LoadType != public.LoadTypeGRPC {
+			return errors.Errorf("服务%s不支持grpc健康检查", serviceName)
+		}
+		if _, err := public.ParseHashKey(serviceDetail.LoadBalance.HashKey); err != nil {
+			return errors.Errorf("服务%s的一致性hash key格式不正确", serviceName)
+		}
+		switch serviceDetail.Info.LoadType {
+		case public.LoadTypeHTTP:
+			if serviceDetail.HTTPRule == nil || serviceDetail.HTTPRule.Rule == "" {
+				return errors.Errorf("服务%s缺少http规则", serviceName)
+			}
+			if !public.ValidHTTPRule(serviceDetail.HTTPRule.RuleType, serviceDetail.HTTPRule.Rule) ||
+				!public.ValidHTTPMethods(serviceDetail.HTTPRule.Methods) {
+				return errors.Errorf("服务%s的http规则格式不正确", serviceName)
+			}
+			if _, err := public.ParseCanaryRules(serviceDetail.HTTPRule.CanaryRule); err != nil {
+				return errors.Errorf("服务%s的灰度路由规则格式不正确", serviceName)
+			}
+			if !public.ValidUpstreamGroup(serviceDetail.LoadBalance.IpList, serviceDetail.LoadBalance.WeightList, serviceDetail.LoadBalance.GroupList, serviceDetail.LoadBalance.GroupWeight) {
+				return errors.Errorf("服务%s的上游分组配置不正确", serviceName)
+			}
+		case public.LoadTypeTCP:
+			if serviceDetail.TCPRule == nil || serviceDetail.TCPRule.Port == 0 {
+				return errors.Errorf("服务%s缺少tcp规则", serviceName)
+			}
+		case public.LoadTypeGRPC:
+			if serviceDetail.GRPCRule == nil || serviceDetail.GRPCRule.Port == 0 {
+				return errors.Errorf("服务%s缺少grpc规则", serviceName)
+			}
+		default:
+			return errors.Errorf("服务%s的负载类型不正确", serviceName)
+		}
+		//补齐其他类型的规则 便于与已有服务对比
+		if serviceDetail.HTTPRule == nil {
+			serviceDetail.HTTPRule = &HttpRule{}
+		}
+		if serviceDetail.TCPRule == nil {
+			serviceDetail.TCPRule = &TcpRule{}
+		}
+		if serviceDetail.GRPCRule == nil {
+			serviceDetail.GRPCRule = &GrpcRule{}
+		}
+	}
+	appIDs := map[string]bool{}
+	for _, app := range bundle.App {
+		if app == nil || app.APPID == "" {
+			return errors.New("租户id不能为空")
+		}
+		if appIDs[app.APPID] {
+			return errors.Errorf("租户%s重复", app.APPID)
+		}
+		appIDs[app.APPID] = true
+	}
+	return nil
+}
+
+// 导入配置包：按service_name/app_id新增或更新 prune为true时删除配置包中不存在的服务及租户
+// 需要在事务内调用 由调用方决定提交或回滚(试运行)
+func ImportConfigBundle(c *gin.Context, tx *gorm.DB, bundle *ConfigBundle, prune bool, author string) (*dto.ConfigImportOutput, error) {
+	out := &dto.ConfigImportOutput{
+		Service: dto.ConfigImportResult{Create: []string{}, Update: []string{}, Delete: []string{}, Unchanged: []string{}},
+		App:     dto.ConfigImportResult{Create: []string{}, Update: []string{}, Delete: []string{}, Unchanged: []string{}},
+	}
+
+	//导入服务
+	serviceNames := map[string]bool{}
+	for _, serviceDetail := range bundle.Service {
+		serviceName := serviceDetail.Info.ServiceName
+		serviceNames[serviceName] = true
+		result, err := importService(c, tx, serviceDetail, author)
+		if err != nil {
+			return nil, errors.Wrapf(err, "导入服务%s失败", serviceName)
+		}
+		switch result {
+		case importCreate:
+			out.Service.Create = append(out.Service.Create, serviceName)
+		case importUpdate:
+			out.Service.Update = append(out.Service.Update, serviceName)
+		default:
+			out.Service.Unchanged = append(out.Service.Unchanged, serviceName)
+		}
+	}
+
+	//导入租户
+	appIDs := map[string]bool{}
+	for _, app := range bundle.App {
+		appIDs[app.APPID] = true
+		result, err := importApp(c, tx, app)
+		if err != nil {
+			return nil, errors.Wrapf(err, "导入租户%s失败", app.APPID)
+		}
+		switch result {
+		case importCreate:
+			out.App.Create = append(out.App.Create, app.APPID)
+		case importUpdate:
+			out.App.Update = append(out.App.Update, app.APPID)
+		default:
+			out.App.Unchanged = append(out.App.Unchanged, app.APPID)
+		}
+	}
+
+	if !prune {
+		return out, nil
+	}
+
+	//删除配置包中不存在的服务
+	serviceInfo := &ServiceInfo{}
+	serviceList, _, err := serviceInfo.PageList(c, tx, &dto.ServiceListInput{PageNum: 1, PageSize: 99999})
+	if err != nil {
+		return nil, err
+	}
+	for _, serviceItem := range serviceList {
+		if serviceNames[serviceItem.ServiceName] {
+			continue
+		}
+		tmpServiceItem := serviceItem
+		tmpServiceItem.IsDelete = 1
+		if err := tmpServiceItem.Save(c, tx); err != nil {
+			return nil, err
+		}
+		if err := BumpServiceRevision(c, tx); err != nil {
+			return nil, err
+		}
+		out.Service.Delete = append(out.Service.Delete, tmpServiceItem.ServiceName)
+	}
+
+	//删除配置包中不存在的租户
+	app := &APP{}
+	appList, _, err := app.PageList(c, tx, &dto.APPListInput{PageNum: 1, PageSize: 99999})
+	if err != nil {
+		return nil, err
+	}
+	for _, appItem := range appList {
+		if appIDs[appItem.APPID] {
+			continue
+		}
+		tmpAppItem := appItem
+		tmpAppItem.IsDelete = 1
+		if err := tmpAppItem.Save(c, tx); err != nil {
+			return nil, err
+		}
+		out.App.Delete = append(out.App.Delete, tmpAppItem.APPID)
+	}
+	return out, nil
+}
+
+const (
+	importUnchanged = iota
+	importCreate
+	importUpdate
+)
+
+// 导入单个服务 返回新增、更新或未变化
+func importService(c *gin.Context, tx *gorm.DB, serviceDetail *ServiceDetail, author string) (int, error) {
+	result := importUpdate
+	serviceInfo := &ServiceInfo{ServiceName: serviceDetail.Info.ServiceName}
+	if err := serviceInfo.Find(c, tx); err != nil {
+		return 0, err
+	}
+	current := &ServiceDetail{Info: &ServiceInfo{}, HTTPRule: &HttpRule{}, TCPRule: &TcpRule{}, GRPCRule: &GrpcRule{}, LoadBalance: &LoadBalance{}, AccessControl: &AccessControl{}}
+	if serviceInfo.ID == 0 {
+		result = importCreate
+		serviceInfo.LoadType = serviceDetail.Info.LoadType
+	} else {
+		if serviceInfo.LoadType != serviceDetail.Info.LoadType {
+			return 0, errors.New("服务类型与已有服务不一致")
+		}
+		var err error
+		if current, err = serviceInfo.ServiceDetail(c, tx); err != nil {
+			return 0, err
+		}
+		diffList, err := DiffServiceDetail(current, serviceDetail)
+		if err != nil {
+			return 0, err
+		}
+		if len(diffList) == 0 {
+			return importUnchanged, nil
+		}
+	}
+
+	//保存服务基本信息
+	serviceInfo.ServiceDesc = serviceDetail.Info.ServiceDesc
+	if err := serviceInfo.Save(c, tx); err != nil {
+		return 0, err
+	}
+
+	//按服务类型保存对应的规则信息 接入前缀、域名或端口已被其他服务占用时不允许导入
+	switch serviceInfo.LoadType {
+	case public.LoadTypeHTTP:
+		httpRule := &HttpRule{RuleType: serviceDetail.HTTPRule.RuleType, Rule: serviceDetail.HTTPRule.Rule}
+		if err := httpRule.Find(c, tx); err != nil {
+			return 0, err
+		}
+		if httpRule.ID != 0 && httpRule.ServiceID != serviceInfo.ID {
+			return 0, errors.New("服务接入前缀或域名已存在")
+		}
+		httpRule = serviceDetail.HTTPRule
+		httpRule.ID = current.HTTPRule.ID
+		httpRule.ServiceID = serviceInfo.ID
+		if err := httpRule.Save(c, tx); err != nil {
+			return 0, err
+		}
+	case public.LoadTypeTCP, public.LoadTypeGRPC:
+		port := serviceDetail.TCPRule.Port
+		if serviceInfo.LoadType == public.LoadTypeGRPC {
+			port = serviceDetail.GRPCRule.Port
+		}
+		tcpRule := &TcpRule{Port: port}
+		if err := tcpRule.Find(c, tx); err != nil {
+			return 0, err
+		}
+		grpcRule := &GrpcRule{Port: port}
+		if err := grpcRule.Find(c, tx); err != nil {
+			return 0, err
+		}
+		if (tcpRule.ID != 0 && tcpRule.ServiceID != serviceInfo.ID) || (grpcRule.ID != 0 && grpcRule.ServiceID != serviceInfo.ID) {
+			return 0, errors.New("服务端口被占用")
+		}
+		if serviceInfo.LoadType == public.LoadTypeTCP {
+			tcpRule = serviceDetail.TCPRule
+			tcpRule.ID = current.TCPRule.ID
+			tcpRule.ServiceID = serviceInfo.ID
+			if err := tcpRule.Save(c, tx); err != nil {
+				return 0, err
+			}
+		} else {
+			grpcRule = serviceDetail.GRPCRule
+			grpcRule.ID = current.GRPCRule.ID
+			grpcRule.ServiceID = serviceInfo.ID
+			if err := grpcRule.Save(c, tx); err != nil {
+				return 0, err
+			}
+		}
+	}
+
+	//保存服务的权限控制信息
+	accessControl := serviceDetail.AccessControl
+	accessControl.ID = current.AccessControl.ID
+	accessControl.ServiceID = serviceInfo.ID
+	if err := accessControl.Save(c, tx); err != nil {
+		return 0, err
+	}
+
+	//保存服务负载均衡信息
+	loadBalance := serviceDetail.LoadBalance
+	loadBalance.ID = current.LoadBalance.ID
+	loadBalance.ServiceID = serviceInfo.ID
+	if err := loadBalance.Save(c, tx); err != nil {
+		return 0, err
+	}
+
+	//保存服务配置版本快照
+	if _, err := SaveServiceVersion(c, tx, serviceInfo, author, "配置导入"); err != nil {
+		return 0, err
+	}
+	return result, nil
+}
+
+// 导入单个租户 返回新增、更新或未变化 已删除的同名租户会被恢复
+func importApp(c *gin.Context, tx *gorm.DB, app *APP) (int, error) {
+	//如果未输入密钥 则以app_id以md5算法生成密钥
+	if app.Secret == "" {
+		app.Secret = public.MD5(app.APPID)
+	}
+
+	result := importUpdate
+	current := &APP{APPID: app.APPID}
+	if err := current.Find(c, tx); err != nil {
+		return 0, err
+	}
+	if current.ID == 0 || current.IsDelete == 1 {
+		result = importCreate
+	} else if current.Name == app.Name && current.Secret == app.Secret && current.WhiteIPS == app.WhiteIPS &&
+		current.Qpd == app.Qpd && current.Qps == app.Qps {
+		return importUnchanged, nil
+	}
+
+	current.Name = app.Name
+	current.Secret = app.Secret
+	current.WhiteIPS = app.WhiteIPS
+	current.Qpd = app.Qpd
+	current.Qps = app.Qps
+	current.IsDelete = 0
+	if err := current.Save(c, tx); err != nil {
+		return 0, err
+	}
+	return result, nil
+}
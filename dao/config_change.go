@@ -0,0 +1,107 @@
+package dao
+
+import (
+	"encoding/json"
+	"github.com/e421083458/golang_common/lib"
+	"github.com/garyburd/redigo/redis"
+	"github.com/starMoonZhao/go_gateway/circuit_rate"
+	"github.com/starMoonZhao/go_gateway/public"
+	"log"
+	"time"
+)
+
+// 配置变更事件：管理后台保存服务或租户后通过redis频道通知所有代理节点
+type ConfigChangeEvent struct {
+	Type string `json:"type" description:"变更类型 service=服务 app=租户 circuit=服务熔断状态"`
+	Name string `json:"name" description:"服务名称或租户app_id"`
+	Data string `json:"data,omitempty" description:"变更内容 circuit类型为熔断器状态"`
+}
+
+// 发布配置变更事件
+func PublishConfigChange(changeType, name string) error {
+	return PublishConfigChangeData(changeType, name, "")
+}
+
+// 发布带变更内容的配置变更事件
+func PublishConfigChangeData(changeType, name, data string) error {
+	conn, err := lib.RedisConnFactory("default")
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	event := &ConfigChangeEvent{Type: changeType, Name: name, Data: data}
+	_, err = conn.Do("PUBLISH", public.RedisConfigChangeChannel, public.Obj2Json(event))
+	return err
+}
+
+// 订阅配置变更事件 收到事件后重新加载对应的服务或租户
+// 连接断开后自动重连 重连成功后全量加载一次以补齐断开期间错过的事件
+func SubscribeConfigChange() {
+	go func() {
+		subscribed := false
+		for {
+			err := subscribeConfigChange(func() {
+				if subscribed {
+					if err := ServiceManegerHandler.ReLoad(); err != nil {
+						log.Printf(" [ERROR] ServiceManger ReLoad err:%v\n", err)
+					}
+					if err := AppManegerHandler.ReLoad(); err != nil {
+						log.Printf(" [ERROR] AppManger ReLoad err:%v\n", err)
+					}
+				}
+				subscribed = true
+			})
+			log.Printf(" [ERROR] SubscribeConfigChange err:%v\n", err)
+			time.Sleep(time.Second)
+		}
+	}()
+}
+
+// 建立订阅连接并持续处理事件 直到连接出错
+func subscribeConfigChange(onSubscribe func()) error {
+	conn, err := lib.RedisConnFactory("default")
+	if err != nil {
+		return err
+	}
+	psc := redis.PubSubConn{Conn: conn}
+	defer psc.Close()
+	if err := psc.Subscribe(public.RedisConfigChangeChannel); err != nil {
+		return err
+	}
+	for {
+		//订阅连接需要一直阻塞等待消息 不使用连接默认的读超时
+		switch v := psc.ReceiveWithTimeout(0).(type) {
+		case redis.Subscription:
+			log.Printf(" [INFO] SubscribeConfigChange %s:%s\n", v.Kind, v.Channel)
+			onSubscribe()
+		case redis.Message:
+			applyConfigChange(v.Data)
+		case error:
+			return v
+		}
+	}
+}
+
+// 应用配置变更事件
+func applyConfigChange(data []byte) {
+	event := &ConfigChangeEvent{}
+	if err := json.Unmarshal(data, event); err != nil {
+		log.Printf(" [ERROR] ConfigChangeEvent unmarshal err:%v\n", err)
+		return
+	}
+	var err error
+	switch event.Type {
+	case public.ConfigChangeTypeService:
+		err = ServiceManegerHandler.ReLoadService(event.Name)
+	case public.ConfigChangeTypeApp:
+		err = AppManegerHandler.ReLoadApp(event.Name)
+	case public.ConfigChangeTypeCircuit:
+		circuit_rate.CircuitBreakerHandler.ForceState(event.Name, event.Data)
+	default:
+		log.Printf(" [WARN] ConfigChangeEvent unknown type:%s\n", event.Type)
+		return
+	}
+	if err != nil {
+		log.Printf(" [ERROR] ConfigChangeEvent %s:%s apply err:%v\n", event.Type, event.Name, err)
+	}
+}
@@ -0,0 +1,119 @@
+package dao
+
+import (
+	"github.com/e421083458/golang_common/lib"
+	"github.com/gin-gonic/gin"
+	"github.com/starMoonZhao/go_gateway/dto"
+	"net/http/httptest"
+)
+
+// 代理服务加载服务及租户配置的来源
+type ConfigSource interface {
+	ServiceVersion() (string, error)            //服务配置版本 版本变化时重新加载服务
+	LoadServiceList() ([]*ServiceDetail, error) //加载全部服务 按匹配顺序排列
+	AppVersion() (string, error)                //租户配置版本 版本变化时重新加载租户
+	LoadAppList() ([]*APP, error)               //加载全部租户
+}
+
+var ConfigSourceHandler ConfigSource
+
+func init() {
+	//默认从mysql加载配置
+	ConfigSourceHandler = &MysqlConfigSource{}
+}
+
+// 根据代理配置proxy.config_source初始化配置来源 type=mysql(默认)/file
+func InitConfigSource() error {
+	switch lib.GetStringConf("proxy.config_source.type") {
+	case "", ConfigSourceTypeMysql:
+		ConfigSourceHandler = &MysqlConfigSource{}
+	case ConfigSourceTypeFile:
+		fileConfigSource, err := NewFileConfigSource(lib.GetStringConf("proxy.config_source.path"))
+		if err != nil {
+			return err
+		}
+		ConfigSourceHandler = fileConfigSource
+	default:
+		return ErrUnknownConfigSource
+	}
+	return nil
+}
+
+// 是否从mysql加载配置 只有mysql配置来源支持管理后台的配置变更通知
+func IsMysqlConfigSource() bool {
+	_, ok := ConfigSourceHandler.(*MysqlConfigSource)
+	return ok
+}
+
+// mysql配置来源：从gateway_service_*及gateway_app表中加载
+type MysqlConfigSource struct {
+}
+
+func (m *MysqlConfigSource) ServiceVersion() (string, error) {
+	c, _ := gin.CreateTestContext(httptest.NewRecorder())
+	tx, err := lib.GetGormPool("default")
+	if err != nil {
+		return "", err
+	}
+	serviceInfo := &ServiceInfo{}
+	return serviceInfo.Version(c, tx)
+}
+
+func (m *MysqlConfigSource) LoadServiceList() ([]*ServiceDetail, error) {
+	//查询所有的服务信息
+	c, _ := gin.CreateTestContext(httptest.NewRecorder())
+	tx, err := lib.GetGormPool("default")
+	if err != nil {
+		return nil, err
+	}
+	//分页条件
+	params := &dto.ServiceListInput{PageNum: 1, PageSize: 99999}
+	serviceInfo := &ServiceInfo{}
+	list, _, err := serviceInfo.PageList(c, tx, params)
+	if err != nil {
+		return nil, err
+	}
+	serviceList := []*ServiceDetail{}
+	for _, serviceItem := range list {
+		tmpServiceItem := serviceItem
+		//查询该服务对应的详情
+		serviceDetail, err := tmpServiceItem.ServiceDetail(c, tx)
+		if err != nil {
+			return nil, err
+		}
+		serviceList = append(serviceList, serviceDetail)
+	}
+	return serviceList, nil
+}
+
+func (m *MysqlConfigSource) AppVersion() (string, error) {
+	c, _ := gin.CreateTestContext(httptest.NewRecorder())
+	tx, err := lib.GetGormPool("default")
+	if err != nil {
+		return "", err
+	}
+	app := &APP{}
+	return app.Version(c, tx)
+}
+
+func (m *MysqlConfigSource) LoadAppList() ([]*APP, error) {
+	//查询所有的租户信息
+	c, _ := gin.CreateTestContext(httptest.NewRecorder())
+	tx, err := lib.GetGormPool("default")
+	if err != nil {
+		return nil, err
+	}
+	//分页条件
+	params := &dto.APPListInput{PageNum: 1, PageSize: 99999}
+	app := &APP{}
+	list, _, err := app.PageList(c, tx, params)
+	if err != nil {
+		return nil, err
+	}
+	appList := []*APP{}
+	for _, appItem := range list {
+		tmpAppItem := appItem
+		appList = append(appList, &tmpAppItem)
+	}
+	return appList, nil
+}
@@ -0,0 +1,184 @@
+package dao
+
+import (
+	"crypto/md5"
+	"fmt"
+	"github.com/mitchellh/mapstructure"
+	"github.com/pkg/errors"
+	"github.com/spf13/viper"
+	"github.com/starMoonZhao/go_gateway/public"
+	"io/ioutil"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+const (
+	ConfigSourceTypeMysql = "mysql"
+	ConfigSourceTypeFile  = "file"
+)
+
+var ErrUnknownConfigSource = errors.New("unknown config source type")
+
+// 配置文件支持的格式
+var fileConfigExts = []string{".toml", ".yaml", ".yml", ".json"}
+
+// 单个配置文件的内容：一个文件中可以声明多个服务及租户
+// 字段与服务详情接口输出的json字段保持一致
+type fileConfig struct {
+	Service []*ServiceDetail `json:"service"`
+	App     []*APP           `json:"app"`
+}
+
+// 文件配置来源：从目录下的toml/yaml/json文件中加载服务及租户
+// 目录下的文件按文件名排序后依次加载 服务的匹配顺序与声明顺序一致
+// 以目录下所有配置文件内容的摘要作为版本 文件发生变化时重新加载
+type FileConfigSource struct {
+	path string
+}
+
+func NewFileConfigSource(path string) (*FileConfigSource, error) {
+	if path == "" {
+		return nil, errors.New("config source path is empty")
+	}
+	return &FileConfigSource{path: path}, nil
+}
+
+// 获取目录下的配置文件列表
+func (f *FileConfigSource) fileList() ([]string, error) {
+	infos, err := ioutil.ReadDir(f.path)
+	if err != nil {
+		return nil, err
+	}
+	fileList := []string{}
+	for _, info := range infos {
+		if info.IsDir() {
+			continue
+		}
+		for _, ext := range fileConfigExts {
+			if strings.ToLower(filepath.Ext(info.Name())) == ext {
+				fileList = append(fileList, filepath.Join(f.path, info.Name()))
+				break
+			}
+		}
+	}
+	sort.Strings(fileList)
+	return fileList, nil
+}
+
+// 以所有配置文件的文件名及内容计算摘要作为版本
+func (f *FileConfigSource) version() (string, error) {
+	fileList, err := f.fileList()
+	if err != nil {
+		return "", err
+	}
+	hash := md5.New()
+	for _, file := range fileList {
+		bts, err := ioutil.ReadFile(file)
+		if err != nil {
+			return "", err
+		}
+		hash.Write([]byte(file))
+		hash.Write(bts)
+	}
+	return fmt.Sprintf("%x", hash.Sum(nil)), nil
+}
+
+// 解析目录下的全部配置文件
+func (f *FileConfigSource) load() ([]*fileConfig, error) {
+	fileList, err := f.fileList()
+	if err != nil {
+		return nil, err
+	}
+	confList := []*fileConfig{}
+	for _, file := range fileList {
+		v := viper.New()
+		v.SetConfigFile(file)
+		if err := v.ReadInConfig(); err != nil {
+			return nil, errors.Wrapf(err, "read config file %s", file)
+		}
+		conf := &fileConfig{}
+		if err := v.Unmarshal(conf, func(decoderConfig *mapstructure.DecoderConfig) {
+			decoderConfig.TagName = "json"
+		}); err != nil {
+			return nil, errors.Wrapf(err, "parse config file %s", file)
+		}
+		confList = append(confList, conf)
+	}
+	return confList, nil
+}
+
+func (f *FileConfigSource) ServiceVersion() (string, error) {
+	return f.version()
+}
+
+func (f *FileConfigSource) LoadServiceList() ([]*ServiceDetail, error) {
+	confList, err := f.load()
+	if err != nil {
+		return nil, err
+	}
+	serviceList := []*ServiceDetail{}
+	serviceNames := map[string]bool{}
+	for _, conf := range confList {
+		for _, serviceDetail := range conf.Service {
+			//补齐未声明的规则 与从mysql加载的服务详情保持一致
+			if serviceDetail.Info == nil {
+				serviceDetail.Info = &ServiceInfo{}
+			}
+			if serviceDetail.HTTPRule == nil {
+				serviceDetail.HTTPRule = &HttpRule{}
+			}
+			if serviceDetail.TCPRule == nil {
+				serviceDetail.TCPRule = &TcpRule{}
+			}
+			if serviceDetail.GRPCRule == nil {
+				serviceDetail.GRPCRule = &GrpcRule{}
+			}
+			if serviceDetail.LoadBalance == nil {
+				serviceDetail.LoadBalance = &LoadBalance{}
+			}
+			if serviceDetail.AccessControl == nil {
+				serviceDetail.AccessControl = &AccessControl{}
+			}
+			serviceName := serviceDetail.Info.ServiceName
+			if serviceName == "" {
+				return nil, errors.New("service_name is empty")
+			}
+			if serviceNames[serviceName] {
+				return nil, errors.Errorf("service_name %s is duplicated", serviceName)
+			}
+			serviceNames[serviceName] = true
+			if !public.ValidIPWeightList(serviceDetail.LoadBalance.IpList, serviceDetail.LoadBalance.WeightList) {
+				return nil, errors.Errorf("service %s ip_list does not match weight_list", serviceName)
+			}
+			serviceList = append(serviceList, serviceDetail)
+		}
+	}
+	return serviceList, nil
+}
+
+func (f *FileConfigSource) AppVersion() (string, error) {
+	return f.version()
+}
+
+func (f *FileConfigSource) LoadAppList() ([]*APP, error) {
+	confList, err := f.load()
+	if err != nil {
+		return nil, err
+	}
+	appList := []*APP{}
+	appIDs := map[string]bool{}
+	for _, conf := range confList {
+		for _, app := range conf.App {
+			if app.APPID == "" {
+				return nil, errors.New("app_id is empty")
+			}
+			if appIDs[app.APPID] {
+				return nil, errors.Errorf("app_id %s is duplicated", app.APPID)
+			}
+			appIDs[app.APPID] = true
+			appList = append(appList, app)
+		}
+	}
+	return appList, nil
+}
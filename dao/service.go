@@ -4,12 +4,14 @@ import (
 	"errors"
 	"github.com/e421083458/golang_common/lib"
 	"github.com/gin-gonic/gin"
-	"github.com/starMoonZhao/go_gateway/dto"
+	"github.com/starMoonZhao/go_gateway/circuit_rate"
 	"github.com/starMoonZhao/go_gateway/public"
 	"gorm.io/gorm"
+	"log"
 	"net/http/httptest"
-	"strings"
 	"sync"
+	"sync/atomic"
+	"time"
 )
 
 // 初始化函数
@@ -76,12 +78,21 @@ func (serviceInfo *ServiceInfo) ServiceDetail(c *gin.Context, tx *gorm.DB) (*Ser
 
 var ServiceManegerHandler *ServiceManger
 
+// 服务变更观察者：服务信息重新加载后 调用Update方法同步服务变化
+type ServiceObserver interface {
+	Update()
+}
+
 type ServiceManger struct {
 	ServiceMap   map[string]*ServiceDetail
 	ServiceSlice []*ServiceDetail
 	Locker       sync.RWMutex
 	init         sync.Once
 	err          error
+	version      string                     //当前已加载的服务信息版本
+	reloadLocker sync.Mutex                 //保证同一时刻只有一个加载任务
+	observers    []ServiceObserver          //观察者列表
+	httpRouter   atomic.Pointer[httpRouter] //http服务路由器 服务变化时重建后整体替换
 }
 
 func NewServiceManager() *ServiceManger {
@@ -96,38 +107,203 @@ func NewServiceManager() *ServiceManger {
 // 系统初始化时加载服务信息
 func (serviceManger *ServiceManger) LoadOnce() error {
 	serviceManger.init.Do(func() {
-		//查询所有的服务信息
-		c, _ := gin.CreateTestContext(httptest.NewRecorder())
-		tx, err := lib.GetGormPool("default")
+		serviceManger.err = serviceManger.ReLoad()
+	})
+	return serviceManger.err
+}
+
+// 重新加载服务信息：从配置来源查询出全部服务后构建新的ServiceMap、ServiceSlice 再整体替换
+// 正在处理中的请求持有的是旧的ServiceDetail对象 不受替换影响
+func (serviceManger *ServiceManger) ReLoad() error {
+	serviceManger.reloadLocker.Lock()
+	defer serviceManger.reloadLocker.Unlock()
+
+	//先记录版本 加载期间发生的变更会在下次版本检查时再次加载
+	version, err := ConfigSourceHandler.ServiceVersion()
+	if err != nil {
+		return err
+	}
+	//从配置来源查询所有的服务信息
+	list, err := ConfigSourceHandler.LoadServiceList()
+	if err != nil {
+		return err
+	}
+
+	//将查询出的所有服务填充到新的ServiceMap、ServiceSlice中
+	serviceMap := map[string]*ServiceDetail{}
+	serviceSlice := []*ServiceDetail{}
+	for _, serviceDetail := range list {
+		serviceDetail.prepare()
+		serviceMap[serviceDetail.Info.ServiceName] = serviceDetail
+		serviceSlice = append(serviceSlice, serviceDetail)
+	}
+
+	//整体替换
+	serviceManger.swap(serviceMap, serviceSlice, version)
+	log.Printf(" [INFO] ServiceManger ReLoad version:%s services:%d\n", version, len(serviceSlice))
+
+	//恢复手动熔断的服务
+	if err := circuit_rate.CircuitBreakerHandler.SyncForcedState(); err != nil {
+		log.Printf(" [ERROR] CircuitBreaker SyncForcedState err:%v\n", err)
+	}
+	return nil
+}
+
+// 重新加载单个服务：服务已删除时将其移除 否则新增或替换该服务
+func (serviceManger *ServiceManger) ReLoadService(serviceName string) error {
+	serviceManger.reloadLocker.Lock()
+	defer serviceManger.reloadLocker.Unlock()
+
+	c, _ := gin.CreateTestContext(httptest.NewRecorder())
+	tx, err := lib.GetGormPool("default")
+	if err != nil {
+		return err
+	}
+	//查询服务基本信息及详情
+	serviceInfo := &ServiceInfo{ServiceName: serviceName}
+	if err := serviceInfo.Find(c, tx); err != nil && err != gorm.ErrRecordNotFound {
+		return err
+	}
+	var serviceDetail *ServiceDetail
+	if serviceInfo.ID != 0 {
+		serviceDetail, err = serviceInfo.ServiceDetail(c, tx)
 		if err != nil {
-			serviceManger.err = err
-			return
+			return err
 		}
-		//分页条件
-		params := &dto.ServiceListInput{PageNum: 1, PageSize: 99999}
-		serviceInfo := &ServiceInfo{}
-		list, _, err := serviceInfo.PageList(c, tx, params)
-		if err != nil {
-			serviceManger.err = err
-			return
+		serviceDetail.prepare()
+	}
+
+	//复制当前的ServiceMap、ServiceSlice后替换该服务 保持按id倒序排列
+	serviceManger.Locker.RLock()
+	oldServiceSlice := serviceManger.ServiceSlice
+	version := serviceManger.version
+	serviceManger.Locker.RUnlock()
+	serviceMap := map[string]*ServiceDetail{}
+	serviceSlice := []*ServiceDetail{}
+	for _, serviceItem := range oldServiceSlice {
+		if serviceItem.Info.ServiceName == serviceName {
+			continue
+		}
+		if serviceDetail != nil && serviceItem.Info.ID < serviceDetail.Info.ID && serviceMap[serviceName] == nil {
+			serviceMap[serviceName] = serviceDetail
+			serviceSlice = append(serviceSlice, serviceDetail)
 		}
+		serviceMap[serviceItem.Info.ServiceName] = serviceItem
+		serviceSlice = append(serviceSlice, serviceItem)
+	}
+	if serviceDetail != nil && serviceMap[serviceName] == nil {
+		serviceMap[serviceName] = serviceDetail
+		serviceSlice = append(serviceSlice, serviceDetail)
+	}
+
+	serviceManger.swap(serviceMap, serviceSlice, version)
+	log.Printf(" [INFO] ServiceManger ReLoadService service:%s exist:%v\n", serviceName, serviceDetail != nil)
+	return nil
+}
+
+// 预先解析服务配置中需要在请求时使用的规则 在服务配置快照发布前调用
+func (serviceDetail *ServiceDetail) prepare() {
+	if serviceDetail.HTTPRule != nil {
+		serviceDetail.HTTPRule.parseCanaryRules()
+	}
+}
+
+// 替换ServiceMap、ServiceSlice 清理发生变化服务的负载均衡器及连接池 并通知观察者
+func (serviceManger *ServiceManger) swap(serviceMap map[string]*ServiceDetail, serviceSlice []*ServiceDetail, version string) {
+	//先在锁外构建http服务路由器
+	router := newHTTPRouter(serviceSlice)
 
-		serviceManger.Locker.Lock()
-		defer serviceManger.Locker.Unlock()
-		//将查询出的所有服务填充到ServiceManger中的ServiceMap、ServiceSlice
-		for _, serviceItem := range list {
-			tmpServiceItem := serviceItem
-			//查询该服务对应的详情
-			serviceDetail, err := tmpServiceItem.ServiceDetail(c, tx)
+	serviceManger.Locker.Lock()
+	oldServiceMap := serviceManger.ServiceMap
+	serviceManger.ServiceMap = serviceMap
+	serviceManger.ServiceSlice = serviceSlice
+	serviceManger.version = version
+	serviceManger.httpRouter.Store(router)
+	serviceManger.Locker.Unlock()
+
+	//已删除的服务 清理其负载均衡器及连接池
+	//配置发生变化的服务 按新配置重建负载均衡器并沿用节点的运行时状态 清理连接池 下次请求时按新配置重建
+	for serviceName, oldServiceDetail := range oldServiceMap {
+		newServiceDetail, ok := serviceMap[serviceName]
+		if ok && public.Obj2Json(newServiceDetail) == public.Obj2Json(oldServiceDetail) {
+			continue
+		}
+		if ok {
+			LoadBalancerHandler.Update(newServiceDetail)
+		} else {
+			LoadBalancerHandler.Remove(serviceName)
+		}
+		TransportorHandler.Remove(serviceName)
+	}
+
+	//通知观察者同步服务变化
+	serviceManger.notify()
+}
+
+// 向服务管理器中注册观察者对象
+func (serviceManger *ServiceManger) Attach(o ServiceObserver) {
+	serviceManger.Locker.Lock()
+	defer serviceManger.Locker.Unlock()
+	serviceManger.observers = append(serviceManger.observers, o)
+}
+
+// 通知所有观察者服务发生变化
+func (serviceManger *ServiceManger) notify() {
+	serviceManger.Locker.RLock()
+	observers := serviceManger.observers
+	serviceManger.Locker.RUnlock()
+	for _, observer := range observers {
+		observer.Update()
+	}
+}
+
+// 定时检查服务信息版本 版本发生变化时重新加载
+func (serviceManger *ServiceManger) WatchReLoad(interval time.Duration) {
+	if interval <= 0 {
+		return
+	}
+	go func() {
+		for {
+			time.Sleep(interval)
+			//手动熔断不属于服务配置 每次检查时同步 补齐未收到的配置变更通知
+			if err := circuit_rate.CircuitBreakerHandler.SyncForcedState(); err != nil {
+				log.Printf(" [ERROR] CircuitBreaker SyncForcedState err:%v\n", err)
+			}
+			version, err := ConfigSourceHandler.ServiceVersion()
 			if err != nil {
-				serviceManger.err = err
-				return
+				log.Printf(" [ERROR] ServiceManger WatchReLoad err:%v\n", err)
+				continue
+			}
+			if version == serviceManger.GetVersion() {
+				continue
+			}
+			if err := serviceManger.ReLoad(); err != nil {
+				log.Printf(" [ERROR] ServiceManger ReLoad err:%v\n", err)
 			}
-			serviceManger.ServiceMap[tmpServiceItem.ServiceName] = serviceDetail
-			serviceManger.ServiceSlice = append(serviceManger.ServiceSlice, serviceDetail)
 		}
-	})
-	return serviceManger.err
+	}()
+}
+
+// 按服务名称获取当前已加载的服务
+func (serviceManger *ServiceManger) GetService(serviceName string) (*ServiceDetail, bool) {
+	serviceManger.Locker.RLock()
+	defer serviceManger.Locker.RUnlock()
+	serviceDetail, ok := serviceManger.ServiceMap[serviceName]
+	return serviceDetail, ok
+}
+
+// 获取当前已加载的服务信息版本
+func (serviceManger *ServiceManger) GetVersion() string {
+	serviceManger.Locker.RLock()
+	defer serviceManger.Locker.RUnlock()
+	return serviceManger.version
+}
+
+// 获取当前的服务列表快照
+func (serviceManger *ServiceManger) GetServiceList() []*ServiceDetail {
+	serviceManger.Locker.RLock()
+	defer serviceManger.Locker.RUnlock()
+	return serviceManger.ServiceSlice
 }
 
 // 进行http访问的校验
@@ -135,37 +311,29 @@ func (serviceManger *ServiceManger) HTTPAccessMode(c *gin.Context) (*ServiceDeta
 	//HTTP的匹配规则
 	//1、前缀匹配 /abc ==> ServiceSlice.rule
 	//2、域名匹配 www.test.com ==> ServiceSlice.rule
+	//3、域名+前缀匹配 api.test.com/v2 ==> ServiceSlice.rule
+	//4、泛域名匹配 *.test.com ==> ServiceSlice.rule
+	//5、正则匹配 ^/user/[0-9]+$ ==> ServiceSlice.rule
+	//规则越精确越优先 规则精确程度相同时按priority 规则可限制请求方法
 
 	//host:c.Request.host path:c.Request.URL.Path
-	host := c.Request.Host
-	host = host[0:strings.Index(host, ":")]
+	host := requestHost(c.Request.Host)
 	path := c.Request.URL.Path
 
-	//循环遍历ServiceSlice是否有符合规则的HTTP服务 如无则抛出异常
-	for _, serviceItem := range serviceManger.ServiceSlice {
-		//校验服务类型
-		if public.LoadTypeHTTP != serviceItem.Info.LoadType {
-			continue
-		}
-		//查看该http服务的规则类型
-		if public.HTTPRuleTypePrefixURL == serviceItem.HTTPRule.RuleType {
-			//前缀匹配
-			if strings.HasPrefix(path, serviceItem.HTTPRule.Rule) {
-				return serviceItem, nil
-			}
-		} else if public.HTTPRuleTypeDomain == serviceItem.HTTPRule.RuleType {
-			//域名匹配
-			if host == serviceItem.HTTPRule.Rule {
-				return serviceItem, nil
-			}
-		}
+	//通过http服务路由器查找 如无则抛出异常
+	router := serviceManger.httpRouter.Load()
+	if router == nil {
+		return nil, errors.New("not matched service.")
+	}
+	if serviceDetail := router.match(host, path, c.Request.Method); serviceDetail != nil {
+		return serviceDetail, nil
 	}
 	return nil, errors.New("not matched service.")
 }
 
 func (s *ServiceManger) GetTCPServiceList() []*ServiceDetail {
 	serviceList := []*ServiceDetail{}
-	for _, serviceItem := range s.ServiceSlice {
+	for _, serviceItem := range s.GetServiceList() {
 		tempServiceItem := serviceItem
 		if tempServiceItem.Info.LoadType == public.LoadTypeTCP {
 			serviceList = append(serviceList, tempServiceItem)
@@ -176,7 +344,7 @@ func (s *ServiceManger) GetTCPServiceList() []*ServiceDetail {
 
 func (s *ServiceManger) GetGRPCServiceList() []*ServiceDetail {
 	serviceList := []*ServiceDetail{}
-	for _, serviceItem := range s.ServiceSlice {
+	for _, serviceItem := range s.GetServiceList() {
 		tempServiceItem := serviceItem
 		if tempServiceItem.Info.LoadType == public.LoadTypeGRPC {
 			serviceList = append(serviceList, tempServiceItem)
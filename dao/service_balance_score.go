@@ -0,0 +1,103 @@
+package dao
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/e421083458/golang_common/lib"
+	"github.com/garyburd/redigo/redis"
+	"github.com/starMoonZhao/go_gateway/public"
+	"github.com/starMoonZhao/go_gateway/reverse_proxy/load_balance"
+	"log"
+	"sort"
+	"time"
+)
+
+const (
+	balanceScoreInterval = 5 * time.Second //节点评分写入redis的间隔
+	balanceScoreExpire   = 60              //节点评分过期时间, 单位s 代理节点停止后评分自动失效
+)
+
+// 负载均衡器节点评分快照 写入redis供管理后台展示
+type BalanceScore struct {
+	load_balance.P2CNodeStat
+	UpdateAt int64 `json:"update_at"`
+}
+
+// 节点评分在redis中的key 每个服务一个hash 字段为节点地址
+func BalanceScoreKey(serviceName string) string {
+	return fmt.Sprintf("%s_%s", public.RedisBalanceScoreKey, serviceName)
+}
+
+// 定时将按耗时选择节点的负载均衡器的节点评分写入redis 负载均衡器移除后退出
+func (lbItem *LoadBalancerItem) saveBalanceScoreLoop() {
+	ticker := time.NewTicker(balanceScoreInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-lbItem.closeChan:
+			return
+		case <-ticker.C:
+			if err := lbItem.saveBalanceScore(); err != nil {
+				log.Printf(" [ERROR] save balance score %s err:%v\n", lbItem.ServiceName, err)
+			}
+		}
+	}
+}
+
+func (lbItem *LoadBalancerItem) saveBalanceScore() error {
+	//配置了上游分组时 流量由各分组的负载均衡器承接
+	lbList := []load_balance.LoadBalance{lbItem.LoadBalance}
+	if len(lbItem.Groups) > 0 {
+		lbList = []load_balance.LoadBalance{}
+		for _, groupItem := range lbItem.Groups {
+			lbList = append(lbList, groupItem.LoadBalance)
+		}
+	}
+	now := time.Now().Unix()
+	key := BalanceScoreKey(lbItem.ServiceName)
+	conn, err := lib.RedisConnFactory("default")
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	conn.Send("MULTI")
+	conn.Send("DEL", key)
+	for _, lb := range lbList {
+		p2cLB, ok := lb.(*load_balance.P2CBalance)
+		if !ok {
+			continue
+		}
+		for _, stat := range p2cLB.Stats() {
+			data, _ := json.Marshal(&BalanceScore{P2CNodeStat: stat, UpdateAt: now})
+			conn.Send("HSET", key, stat.Addr, data)
+		}
+	}
+	conn.Send("EXPIRE", key, balanceScoreExpire)
+	_, err = conn.Do("EXEC")
+	return err
+}
+
+// 读取服务各节点的评分
+func GetBalanceScores(serviceName string) ([]*BalanceScore, error) {
+	conn, err := lib.RedisConnFactory("default")
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+	values, err := redis.StringMap(conn.Do("HGETALL", BalanceScoreKey(serviceName)))
+	if err != nil {
+		return nil, err
+	}
+	scoreList := []*BalanceScore{}
+	for _, value := range values {
+		score := &BalanceScore{}
+		if err := json.Unmarshal([]byte(value), score); err != nil {
+			continue
+		}
+		scoreList = append(scoreList, score)
+	}
+	sort.Slice(scoreList, func(i, j int) bool {
+		return scoreList[i].Addr < scoreList[j].Addr
+	})
+	return scoreList, nil
+}
@@ -0,0 +1,27 @@
+package dao
+
+import (
+	"github.com/starMoonZhao/go_gateway/circuit_rate"
+	"github.com/starMoonZhao/go_gateway/reverse_proxy"
+)
+
+// 服务级熔断器 服务未开启熔断时返回nil
+func ServiceCircuitBreaker(service *ServiceDetail) *circuit_rate.CircuitBreaker {
+	conf := service.LoadBalance.CircuitBreakerConf()
+	if !conf.Enabled() {
+		return nil
+	}
+	return circuit_rate.CircuitBreakerHandler.GetCircuitBreaker(service.Info.ServiceName, "", conf)
+}
+
+// 上游节点熔断器 服务未开启熔断时返回nil
+func NodeCircuitBreaker(service *ServiceDetail) reverse_proxy.NodeCircuitBreaker {
+	conf := service.LoadBalance.CircuitBreakerConf()
+	if !conf.Enabled() {
+		return nil
+	}
+	serviceName := service.Info.ServiceName
+	return func(node string) *circuit_rate.CircuitBreaker {
+		return circuit_rate.CircuitBreakerHandler.GetCircuitBreaker(serviceName, node, conf)
+	}
+}
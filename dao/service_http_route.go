@@ -0,0 +1,240 @@
+package dao
+
+import (
+	"fmt"
+	"github.com/gin-gonic/gin"
+	"github.com/starMoonZhao/go_gateway/dto"
+	"github.com/starMoonZhao/go_gateway/public"
+	"gorm.io/gorm"
+	"log"
+	"net"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// http服务路由项：服务及预先解析好的匹配条件
+type httpRoute struct {
+	service *ServiceDetail
+	regexp  *regexp.Regexp //url正则规则编译结果
+	methods []string       //允许的请求方法 为空表示不限制
+}
+
+// 请求是否命中该路由
+func (route *httpRoute) match(host, path, method string) bool {
+	if len(route.methods) > 0 && !public.InStringSlice(route.methods, method) {
+		return false
+	}
+	rule := route.service.HTTPRule
+	switch rule.RuleType {
+	case public.HTTPRuleTypePrefixURL:
+		return strings.HasPrefix(path, rule.Rule)
+	case public.HTTPRuleTypeDomain:
+		return host == rule.Rule
+	case public.HTTPRuleTypeHostPrefix:
+		return host == rule.Host() && strings.HasPrefix(path, rule.PathPrefix())
+	case public.HTTPRuleTypeWildcardDomain:
+		//*.test.com 匹配a.test.com、a.b.test.com 不匹配test.com
+		return strings.HasSuffix(host, rule.Rule[1:])
+	case public.HTTPRuleTypeRegexURL:
+		return route.regexp != nil && route.regexp.MatchString(path)
+	}
+	return false
+}
+
+// 构建http服务路由表：从服务列表中筛选出http服务 按匹配优先级排序
+// 匹配优先级：规则类型越精确越优先(域名+url前缀>域名>泛域名>url正则>url前缀) 同类型规则越长越优先
+// 限制请求方法的规则优先 其次priority越大越优先 最后按服务名排序保证结果稳定
+func newHTTPRouteList(serviceSlice []*ServiceDetail) []*httpRoute {
+	routeList := []*httpRoute{}
+	for _, serviceItem := range serviceSlice {
+		if serviceItem.Info.LoadType != public.LoadTypeHTTP {
+			continue
+		}
+		//忽略格式不正确的规则 如配置文件来源中手工填写的规则
+		if !public.ValidHTTPRule(serviceItem.HTTPRule.RuleType, serviceItem.HTTPRule.Rule) {
+			log.Printf(" [ERROR] service %s http rule %s is invalid\n", serviceItem.Info.ServiceName, serviceItem.HTTPRule.Rule)
+			continue
+		}
+		route := &httpRoute{service: serviceItem, methods: serviceItem.HTTPRule.MethodList()}
+		if serviceItem.HTTPRule.RuleType == public.HTTPRuleTypeRegexURL {
+			regex, err := regexp.Compile(serviceItem.HTTPRule.Rule)
+			if err != nil {
+				log.Printf(" [ERROR] service %s http rule regexp compile err:%v\n", serviceItem.Info.ServiceName, err)
+				continue
+			}
+			route.regexp = regex
+		}
+		routeList = append(routeList, route)
+	}
+	sort.SliceStable(routeList, func(i, j int) bool {
+		return httpRouteLess(routeList[i].service.HTTPRule, routeList[j].service.HTTPRule, routeList[i].service.Info.ServiceName, routeList[j].service.Info.ServiceName)
+	})
+	return routeList
+}
+
+// 比较两条http规则的匹配优先级 返回a是否优先于b
+func httpRouteLess(a, b *HttpRule, aName, bName string) bool {
+	aRank, aLength := httpRuleSpecificity(a)
+	bRank, bLength := httpRuleSpecificity(b)
+	if aRank != bRank {
+		return aRank > bRank
+	}
+	if aLength != bLength {
+		return aLength > bLength
+	}
+	if (a.Methods == "") != (b.Methods == "") {
+		return a.Methods != ""
+	}
+	if a.Priority != b.Priority {
+		return a.Priority > b.Priority
+	}
+	return aName < bName
+}
+
+// http规则的精确程度：规则类型等级及规则长度 等级越高、长度越长越优先 正则规则之间不比较长度
+func httpRuleSpecificity(rule *HttpRule) (int, int) {
+	switch rule.RuleType {
+	case public.HTTPRuleTypeHostPrefix:
+		return 4, len(rule.Rule)
+	case public.HTTPRuleTypeDomain:
+		return 3, len(rule.Rule)
+	case public.HTTPRuleTypeWildcardDomain:
+		return 2, len(rule.Rule)
+	case public.HTTPRuleTypeRegexURL:
+		return 1, 0
+	default:
+		return 0, len(rule.Rule)
+	}
+}
+
+// 两条http规则是否可能同时命中同一个请求
+// 规则重叠时由匹配优先级决定路由结果 带域名的规则与不带域名的规则之间总是带域名的优先 不视为重叠
+// url正则规则无法判断重叠 只有正则完全相同时视为重叠
+func httpRuleOverlap(a, b *HttpRule) bool {
+	//请求方法限制不相交
+	aMethods, bMethods := a.MethodList(), b.MethodList()
+	if len(aMethods) > 0 && len(bMethods) > 0 {
+		intersect := false
+		for _, method := range aMethods {
+			if public.InStringSlice(bMethods, method) {
+				intersect = true
+				break
+			}
+		}
+		if !intersect {
+			return false
+		}
+	}
+
+	aHost, bHost := a.Host(), b.Host()
+	if (aHost == "") != (bHost == "") {
+		return false
+	}
+	if aHost == "" {
+		//url前缀与url正则规则
+		if a.RuleType != b.RuleType {
+			return false
+		}
+		if a.RuleType == public.HTTPRuleTypeRegexURL {
+			return a.Rule == b.Rule
+		}
+		return strings.HasPrefix(a.Rule, b.Rule) || strings.HasPrefix(b.Rule, a.Rule)
+	}
+
+	//带域名的规则 先判断域名是否重叠
+	if !httpHostOverlap(a, aHost, b, bHost) {
+		return false
+	}
+	aPath, bPath := a.PathPrefix(), b.PathPrefix()
+	if aPath == "" || bPath == "" {
+		return true
+	}
+	return strings.HasPrefix(aPath, bPath) || strings.HasPrefix(bPath, aPath)
+}
+
+// 两条规则的域名部分是否可能命中同一个host
+func httpHostOverlap(a *HttpRule, aHost string, b *HttpRule, bHost string) bool {
+	aWildcard := a.RuleType == public.HTTPRuleTypeWildcardDomain
+	bWildcard := b.RuleType == public.HTTPRuleTypeWildcardDomain
+	switch {
+	case aWildcard && bWildcard:
+		return strings.HasSuffix(aHost[1:], bHost[1:]) || strings.HasSuffix(bHost[1:], aHost[1:])
+	case aWildcard:
+		return strings.HasSuffix(bHost, aHost[1:])
+	case bWildcard:
+		return strings.HasSuffix(aHost, bHost[1:])
+	}
+	return aHost == bHost
+}
+
+// 去除请求host中的端口
+func requestHost(host string) string {
+	if h, _, err := net.SplitHostPort(host); err == nil {
+		return h
+	}
+	return host
+}
+
+// 查询与http规则存在路由冲突的其他http服务：规则重叠时 说明请求实际会路由到哪个服务
+func HTTPRouteConflicts(c *gin.Context, tx *gorm.DB, serviceInfo *ServiceInfo, httpRule *HttpRule) ([]dto.HTTPRouteConflictItem, error) {
+	ruleList := []struct {
+		HttpRule
+		ServiceName string
+	}{}
+	if err := tx.WithContext(c).Table(httpRule.TableName()+" as r").
+		Joins("join "+serviceInfo.TableName()+" as s on s.id = r.service_id").
+		Where("s.is_delete = 0 and s.load_type = ? and s.id <> ?", public.LoadTypeHTTP, serviceInfo.ID).
+		Select("r.*, s.service_name").Scan(&ruleList).Error; err != nil {
+		return nil, err
+	}
+
+	conflictList := []dto.HTTPRouteConflictItem{}
+	for _, ruleItem := range ruleList {
+		otherRule := ruleItem.HttpRule
+		if !httpRuleOverlap(httpRule, &otherRule) {
+			continue
+		}
+		var message string
+		aRank, aLength := httpRuleSpecificity(httpRule)
+		bRank, bLength := httpRuleSpecificity(&otherRule)
+		switch {
+		case aRank != bRank || aLength != bLength || (httpRule.Methods == "") != (otherRule.Methods == ""):
+			//规则精确程度不同 更精确的规则优先
+			if httpRouteLess(httpRule, &otherRule, serviceInfo.ServiceName, ruleItem.ServiceName) {
+				message = fmt.Sprintf("规则%s更精确 同时命中两条规则的请求将路由至本服务", httpRule.Rule)
+			} else {
+				message = fmt.Sprintf("规则%s更精确 同时命中两条规则的请求将路由至服务%s", otherRule.Rule, ruleItem.ServiceName)
+			}
+		case httpRule.Priority != otherRule.Priority:
+			//规则精确程度相同 按优先级
+			if httpRule.Priority > otherRule.Priority {
+				message = "规则精确程度相同 本服务优先级更高 请求将路由至本服务"
+			} else {
+				message = fmt.Sprintf("规则精确程度相同 服务%s优先级更高 请求将路由至服务%s", ruleItem.ServiceName, ruleItem.ServiceName)
+			}
+		default:
+			message = "规则精确程度及优先级均相同 将按服务名排序路由 请调整优先级"
+		}
+		conflictList = append(conflictList, dto.HTTPRouteConflictItem{
+			ServiceName: ruleItem.ServiceName,
+			RuleType:    otherRule.RuleType,
+			Rule:        otherRule.Rule,
+			Priority:    otherRule.Priority,
+			Message:     message,
+		})
+	}
+	return conflictList, nil
+}
+
+// 查询服务当前http规则的路由冲突 非http服务返回空列表
+func (serviceInfo *ServiceInfo) HTTPRouteConflicts(c *gin.Context, tx *gorm.DB) ([]dto.HTTPRouteConflictItem, error) {
+	if serviceInfo.LoadType != public.LoadTypeHTTP {
+		return []dto.HTTPRouteConflictItem{}, nil
+	}
+	httpRule := &HttpRule{ServiceID: serviceInfo.ID}
+	if err := httpRule.Find(c, tx); err != nil {
+		return nil, err
+	}
+	return HTTPRouteConflicts(c, tx, serviceInfo, httpRule)
+}
@@ -0,0 +1,174 @@
+package dao
+
+import (
+	"github.com/starMoonZhao/go_gateway/public"
+	"strings"
+)
+
+// http服务路由器：按规则类型建立索引 避免每个请求遍历全部服务
+// 域名及域名+url前缀规则按host建立map 泛域名规则按域名后缀建立map url前缀规则建立path基数树
+// 各索引中的路由项均按匹配优先级排序 查找结果与按优先级顺序逐条匹配一致
+type httpRouter struct {
+	hosts     map[string]*httpHostRoutes //域名 ==> 该域名下的路由
+	wildcards map[string][]*httpRoute    //泛域名后缀 如.test.com ==> 路由
+	regexps   []*httpRoute               //url正则规则 只能逐条匹配
+	paths     *radixNode                 //url前缀规则
+}
+
+// 同一域名下的路由
+type httpHostRoutes struct {
+	paths  *radixNode   //域名+url前缀规则 以url前缀建立基数树
+	routes []*httpRoute //域名规则
+}
+
+// 构建http服务路由器 服务列表变化时整体重建
+func newHTTPRouter(serviceSlice []*ServiceDetail) *httpRouter {
+	router := &httpRouter{
+		hosts:     map[string]*httpHostRoutes{},
+		wildcards: map[string][]*httpRoute{},
+		regexps:   []*httpRoute{},
+		paths:     &radixNode{},
+	}
+	//按匹配优先级顺序插入 各索引中的路由项自然有序
+	for _, route := range newHTTPRouteList(serviceSlice) {
+		rule := route.service.HTTPRule
+		switch rule.RuleType {
+		case public.HTTPRuleTypePrefixURL:
+			router.paths.insert(rule.Rule, route)
+		case public.HTTPRuleTypeDomain:
+			router.host(rule.Host()).routes = append(router.host(rule.Host()).routes, route)
+		case public.HTTPRuleTypeHostPrefix:
+			router.host(rule.Host()).paths.insert(rule.PathPrefix(), route)
+		case public.HTTPRuleTypeWildcardDomain:
+			router.wildcards[rule.Rule[1:]] = append(router.wildcards[rule.Rule[1:]], route)
+		case public.HTTPRuleTypeRegexURL:
+			router.regexps = append(router.regexps, route)
+		}
+	}
+	return router
+}
+
+func (router *httpRouter) host(host string) *httpHostRoutes {
+	hostRoutes, ok := router.hosts[host]
+	if !ok {
+		hostRoutes = &httpHostRoutes{paths: &radixNode{}}
+		router.hosts[host] = hostRoutes
+	}
+	return hostRoutes
+}
+
+// 查找请求命中的http服务 匹配顺序：域名+url前缀>域名>泛域名>url正则>url前缀
+func (router *httpRouter) match(host, path, method string) *ServiceDetail {
+	if hostRoutes, ok := router.hosts[host]; ok {
+		if route := matchRoutes(hostRoutes.paths.lookup(path), host, path, method); route != nil {
+			return route.service
+		}
+		if route := matchRoutes([][]*httpRoute{hostRoutes.routes}, host, path, method); route != nil {
+			return route.service
+		}
+	}
+
+	//泛域名由长到短依次匹配域名后缀 a.b.test.com ==> .b.test.com .test.com .com
+	for index := strings.IndexByte(host, '.'); index >= 0; {
+		if routes, ok := router.wildcards[host[index:]]; ok {
+			if route := matchRoutes([][]*httpRoute{routes}, host, path, method); route != nil {
+				return route.service
+			}
+		}
+		next := strings.IndexByte(host[index+1:], '.')
+		if next < 0 {
+			break
+		}
+		index += next + 1
+	}
+
+	if route := matchRoutes([][]*httpRoute{router.regexps}, host, path, method); route != nil {
+		return route.service
+	}
+	if route := matchRoutes(router.paths.lookup(path), host, path, method); route != nil {
+		return route.service
+	}
+	return nil
+}
+
+// 在候选路由中按顺序查找第一个命中的路由
+func matchRoutes(candidates [][]*httpRoute, host, path, method string) *httpRoute {
+	for _, routes := range candidates {
+		for _, route := range routes {
+			if route.match(host, path, method) {
+				return route
+			}
+		}
+	}
+	return nil
+}
+
+// 基数树节点：prefix为从父节点到该节点的边 routes为以该节点为完整前缀的路由
+type radixNode struct {
+	prefix   string
+	children []*radixNode
+	routes   []*httpRoute
+}
+
+// 插入前缀及路由
+func (node *radixNode) insert(key string, route *httpRoute) {
+	for {
+		if key == "" {
+			node.routes = append(node.routes, route)
+			return
+		}
+		index := node.childIndex(key[0])
+		if index < 0 {
+			node.children = append(node.children, &radixNode{prefix: key, routes: []*httpRoute{route}})
+			return
+		}
+		child := node.children[index]
+		//计算公共前缀 公共前缀短于子节点的边时拆分子节点
+		common := 0
+		for common < len(key) && common < len(child.prefix) && key[common] == child.prefix[common] {
+			common++
+		}
+		if common < len(child.prefix) {
+			split := &radixNode{prefix: child.prefix[:common], children: []*radixNode{child}}
+			child.prefix = child.prefix[common:]
+			node.children[index] = split
+			child = split
+		}
+		node = child
+		key = key[common:]
+	}
+}
+
+// 查找path经过的所有带路由的节点 按前缀由长到短返回
+func (node *radixNode) lookup(path string) [][]*httpRoute {
+	matched := [][]*httpRoute{}
+	for {
+		if len(node.routes) > 0 {
+			matched = append(matched, node.routes)
+		}
+		if path == "" {
+			break
+		}
+		index := node.childIndex(path[0])
+		if index < 0 || !strings.HasPrefix(path, node.children[index].prefix) {
+			break
+		}
+		child := node.children[index]
+		path = path[len(child.prefix):]
+		node = child
+	}
+	for i, j := 0, len(matched)-1; i < j; i, j = i+1, j-1 {
+		matched[i], matched[j] = matched[j], matched[i]
+	}
+	return matched
+}
+
+// 查找边的首字节为b的子节点 不存在时返回-1
+func (node *radixNode) childIndex(b byte) int {
+	for i, child := range node.children {
+		if child.prefix[0] == b {
+			return i
+		}
+	}
+	return -1
+}
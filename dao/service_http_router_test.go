@@ -0,0 +1,138 @@
+package dao
+
+import (
+	"fmt"
+	"github.com/starMoonZhao/go_gateway/public"
+	"testing"
+)
+
+func newTestHTTPService(name string, ruleType int, rule string, priority int, methods string) *ServiceDetail {
+	return &ServiceDetail{
+		Info: &ServiceInfo{ServiceName: name, LoadType: public.LoadTypeHTTP},
+		HTTPRule: &HttpRule{
+			RuleType: ruleType,
+			Rule:     rule,
+			Priority: priority,
+			Methods:  methods,
+		},
+	}
+}
+
+// 按匹配优先级逐条匹配 即路由器需保持一致的结果
+func linearHTTPMatch(routeList []*httpRoute, host, path, method string) *ServiceDetail {
+	for _, route := range routeList {
+		if route.match(host, path, method) {
+			return route.service
+		}
+	}
+	return nil
+}
+
+func TestHTTPRouterMatch(t *testing.T) {
+	serviceSlice := []*ServiceDetail{
+		newTestHTTPService("prefix_api", public.HTTPRuleTypePrefixURL, "/api", 0, ""),
+		newTestHTTPService("prefix_api_v2", public.HTTPRuleTypePrefixURL, "/api/v2", 0, ""),
+		newTestHTTPService("prefix_user_low", public.HTTPRuleTypePrefixURL, "/user", 1, ""),
+		newTestHTTPService("prefix_user_high", public.HTTPRuleTypePrefixURL, "/user", 5, ""),
+		newTestHTTPService("prefix_order_a", public.HTTPRuleTypePrefixURL, "/order", 0, ""),
+		newTestHTTPService("prefix_order_b", public.HTTPRuleTypePrefixURL, "/order", 0, ""),
+		newTestHTTPService("prefix_item_any", public.HTTPRuleTypePrefixURL, "/item", 9, ""),
+		newTestHTTPService("prefix_item_post", public.HTTPRuleTypePrefixURL, "/item", 0, "POST"),
+		newTestHTTPService("domain", public.HTTPRuleTypeDomain, "www.test.com", 0, ""),
+		newTestHTTPService("host_prefix", public.HTTPRuleTypeHostPrefix, "api.test.com/v2", 0, ""),
+		newTestHTTPService("host_prefix_long", public.HTTPRuleTypeHostPrefix, "api.test.com/v2/admin", 0, ""),
+		newTestHTTPService("wildcard", public.HTTPRuleTypeWildcardDomain, "*.test.com", 0, ""),
+		newTestHTTPService("wildcard_long", public.HTTPRuleTypeWildcardDomain, "*.b.test.com", 0, ""),
+		newTestHTTPService("regexp", public.HTTPRuleTypeRegexURL, "^/user/[0-9]+$", 0, ""),
+	}
+	router := newHTTPRouter(serviceSlice)
+	routeList := newHTTPRouteList(serviceSlice)
+
+	testCases := []struct {
+		name    string
+		host    string
+		path    string
+		method  string
+		service string
+	}{
+		//带域名的规则优先于url前缀规则
+		{"domain beats prefix", "www.test.com", "/api/v2/user", "GET", "domain"},
+		{"host prefix beats domain rules", "api.test.com", "/v2/user", "GET", "host_prefix"},
+		{"wildcard beats prefix", "a.test.com", "/api", "GET", "wildcard"},
+		{"host prefix miss falls back to wildcard", "api.test.com", "/v3", "GET", "wildcard"},
+		{"unknown host uses prefix", "other.com", "/api", "GET", "prefix_api"},
+		//同类型规则最长前缀优先
+		{"longest prefix wins", "other.com", "/api/v2/user", "GET", "prefix_api_v2"},
+		{"shorter prefix when longer misses", "other.com", "/api/v3", "GET", "prefix_api"},
+		{"longest host prefix wins", "api.test.com", "/v2/admin/user", "GET", "host_prefix_long"},
+		{"longest wildcard wins", "a.b.test.com", "/", "GET", "wildcard_long"},
+		//精确程度相同时按优先级 再按服务名
+		{"regexp beats prefix", "other.com", "/user/12", "GET", "regexp"},
+		{"priority tie-break", "other.com", "/user/abc", "GET", "prefix_user_high"},
+		{"service name tie-break", "other.com", "/order/1", "GET", "prefix_order_a"},
+		//限制请求方法的规则优先 请求方法不符时不命中
+		{"method filter match", "other.com", "/item/1", "POST", "prefix_item_post"},
+		{"method filter miss", "other.com", "/item/1", "GET", "prefix_item_any"},
+		{"no match", "other.com", "/none", "GET", ""},
+	}
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			serviceName := ""
+			if serviceDetail := router.match(testCase.host, testCase.path, testCase.method); serviceDetail != nil {
+				serviceName = serviceDetail.Info.ServiceName
+			}
+			if serviceName != testCase.service {
+				t.Fatalf("match %s%s %s = %q, want %q", testCase.host, testCase.path, testCase.method, serviceName, testCase.service)
+			}
+			linearName := ""
+			if serviceDetail := linearHTTPMatch(routeList, testCase.host, testCase.path, testCase.method); serviceDetail != nil {
+				linearName = serviceDetail.Info.ServiceName
+			}
+			if linearName != serviceName {
+				t.Fatalf("router matched %q, linear scan matched %q", serviceName, linearName)
+			}
+		})
+	}
+}
+
+// 构建count个服务：每组包含url前缀、域名、域名+url前缀及泛域名规则
+func newBenchmarkHTTPServices(count int) []*ServiceDetail {
+	serviceSlice := []*ServiceDetail{}
+	for i := 0; len(serviceSlice) < count; i++ {
+		serviceSlice = append(serviceSlice,
+			newTestHTTPService(fmt.Sprintf("prefix_%d", i), public.HTTPRuleTypePrefixURL, fmt.Sprintf("/svc%d/api", i), 0, ""),
+			newTestHTTPService(fmt.Sprintf("domain_%d", i), public.HTTPRuleTypeDomain, fmt.Sprintf("www%d.test.com", i), 0, ""),
+			newTestHTTPService(fmt.Sprintf("host_prefix_%d", i), public.HTTPRuleTypeHostPrefix, fmt.Sprintf("api%d.test.com/v2", i), 0, ""),
+			newTestHTTPService(fmt.Sprintf("wildcard_%d", i), public.HTTPRuleTypeWildcardDomain, fmt.Sprintf("*.tenant%d.com", i), 0, ""),
+		)
+	}
+	return serviceSlice
+}
+
+// 对比按优先级逐条匹配与路由器查找的耗时 请求命中排在末尾的url前缀规则
+func BenchmarkHTTPAccessMode(b *testing.B) {
+	for _, count := range []int{100, 500, 1000} {
+		serviceSlice := newBenchmarkHTTPServices(count)
+		host := "other.com"
+		path := fmt.Sprintf("/svc%d/api/user", count/4-1)
+		want := fmt.Sprintf("prefix_%d", count/4-1)
+
+		routeList := newHTTPRouteList(serviceSlice)
+		b.Run(fmt.Sprintf("linear/%d", count), func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				if serviceDetail := linearHTTPMatch(routeList, host, path, "GET"); serviceDetail == nil || serviceDetail.Info.ServiceName != want {
+					b.Fatal("linear scan matched wrong service")
+				}
+			}
+		})
+
+		router := newHTTPRouter(serviceSlice)
+		b.Run(fmt.Sprintf("router/%d", count), func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				if serviceDetail := router.match(host, path, "GET"); serviceDetail == nil || serviceDetail.Info.ServiceName != want {
+					b.Fatal("router matched wrong service")
+				}
+			}
+		})
+	}
+}
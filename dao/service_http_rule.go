@@ -2,19 +2,32 @@ package dao
 
 import (
 	"github.com/gin-gonic/gin"
+	"github.com/pkg/errors"
+	"github.com/starMoonZhao/go_gateway/public"
 	"gorm.io/gorm"
+	"log"
+	"strings"
 )
 
 type HttpRule struct {
-	ID             int64  `json:"id" gorm:"primary_key"`
-	ServiceID      int64  `json:"service_id" gorm:"column:service_id" description:"服务id"`
-	RuleType       int    `json:"rule_type" gorm:"column:rule_type" description:"匹配类型 domain=域名, url_prefix=url前缀"`
-	Rule           string `json:"rule" gorm:"column:rule" description:"type=domain表示域名，type=url_prefix时表示url前缀"`
-	NeedHttps      int    `json:"need_https" gorm:"column:need_https" description:"type=支持https 1=支持"`
-	NeedWebsocket  int    `json:"need_websocket" gorm:"column:need_websocket" description:"启用websocket 1=启用"`
-	NeedStripUri   int    `json:"need_strip_uri" gorm:"column:need_strip_uri" description:"启用strip_uri 1=启用"`
-	UrlRewrite     string `json:"url_rewrite" gorm:"column:url_rewrite" description:"url重写功能，每行一个	"`
-	HeaderTransfer string `json:"header_transfer" gorm:"column:header_transfer" description:"header转换支持增加(add)、删除(del)、修改(edit) 格式: add headname headvalue	"`
+	ID              int64  `json:"id" gorm:"primary_key"`
+	ServiceID       int64  `json:"service_id" gorm:"column:service_id" description:"服务id"`
+	RuleType        int    `json:"rule_type" gorm:"column:rule_type" description:"匹配类型 0=url前缀 1=域名 2=域名+url前缀 3=泛域名 4=url正则"`
+	Rule            string `json:"rule" gorm:"column:rule" description:"type=domain表示域名，type=url_prefix时表示url前缀"`
+	NeedHttps       int    `json:"need_https" gorm:"column:need_https" description:"type=支持https 1=支持"`
+	NeedWebsocket   int    `json:"need_websocket" gorm:"column:need_websocket" description:"启用websocket 1=启用"`
+	NeedStripUri    int    `json:"need_strip_uri" gorm:"column:need_strip_uri" description:"启用strip_uri 1=启用"`
+	UrlRewrite      string `json:"url_rewrite" gorm:"column:url_rewrite" description:"url重写功能，每行一个	"`
+	HeaderTransfer  string `json:"header_transfer" gorm:"column:header_transfer" description:"header转换支持增加(add)、删除(del)、修改(edit) 格式: add headname headvalue	"`
+	Priority        int    `json:"priority" gorm:"column:priority" description:"路由优先级 规则精确程度相同时数值越大越优先"`
+	Methods         string `json:"methods" gorm:"column:methods" description:"允许的请求方法 多个以逗号分隔 为空表示不限制"`
+	CanaryRule      string `json:"canary_rule" gorm:"column:canary_rule" description:"灰度路由规则 格式: header X-Canary eq 1 目标服务 多条以逗号分隔"`
+	MirrorService   string `json:"mirror_service" gorm:"column:mirror_service" description:"流量镜像的影子服务名称 为空表示不镜像"`
+	MirrorPercent   int    `json:"mirror_percent" gorm:"column:mirror_percent" description:"流量镜像比例 0-100"`
+	MirrorTimeout   int    `json:"mirror_timeout" gorm:"column:mirror_timeout" description:"影子请求超时时间, 单位ms 为0时使用默认值"`
+	MirrorBodyLimit int    `json:"mirror_body_limit" gorm:"column:mirror_body_limit" description:"镜像请求体上限, 单位byte 超过时不镜像 为0时使用默认值"`
+
+	canaryRules []*public.CanaryRule //服务加载时预先解析的灰度路由规则 随服务配置快照一起替换
 }
 
 func (httpRule *HttpRule) TableName() string {
@@ -28,3 +41,99 @@ func (httpRule *HttpRule) Find(c *gin.Context, tx *gorm.DB) error {
 func (httpRule *HttpRule) Save(c *gin.Context, tx *gorm.DB) error {
 	return tx.WithContext(c).Save(httpRule).Error
 }
+
+// 规则中的域名部分 url前缀及url正则规则不限制域名
+func (httpRule *HttpRule) Host() string {
+	switch httpRule.RuleType {
+	case public.HTTPRuleTypeDomain, public.HTTPRuleTypeWildcardDomain:
+		return httpRule.Rule
+	case public.HTTPRuleTypeHostPrefix:
+		return strings.SplitN(httpRule.Rule, "/", 2)[0]
+	}
+	return ""
+}
+
+// 规则中的url前缀部分 可用于strip_uri
+func (httpRule *HttpRule) PathPrefix() string {
+	switch httpRule.RuleType {
+	case public.HTTPRuleTypePrefixURL:
+		return httpRule.Rule
+	case public.HTTPRuleTypeHostPrefix:
+		if index := strings.Index(httpRule.Rule, "/"); index >= 0 {
+			return httpRule.Rule[index:]
+		}
+		return "/"
+	}
+	return ""
+}
+
+// 允许的请求方法列表 为空表示不限制
+func (httpRule *HttpRule) MethodList() []string {
+	if httpRule.Methods == "" {
+		return nil
+	}
+	return strings.Split(httpRule.Methods, ",")
+}
+
+// 解析灰度路由规则并保存在规则中 需在服务配置快照发布前调用 规则格式不正确时忽略全部规则
+func (httpRule *HttpRule) parseCanaryRules() {
+	rules, err := public.ParseCanaryRules(httpRule.CanaryRule)
+	if err != nil {
+		log.Printf(" [ERROR] http rule %s canary rule parse err:%v\n", httpRule.Rule, err)
+		rules = []*public.CanaryRule{}
+	}
+	httpRule.canaryRules = rules
+}
+
+// 解析后的灰度路由规则 未预先解析时(如控制台查询出的规则)即时解析
+func (httpRule *HttpRule) CanaryRules() []*public.CanaryRule {
+	if httpRule.canaryRules != nil {
+		return httpRule.canaryRules
+	}
+	rules, err := public.ParseCanaryRules(httpRule.CanaryRule)
+	if err != nil {
+		return nil
+	}
+	return rules
+}
+
+// 校验灰度路由规则的目标：目标服务需存在、为http服务且不为服务自身 目标分组需在服务的分组流量比例中声明(比例可为0)
+func ValidCanaryTarget(c *gin.Context, tx *gorm.DB, serviceName, canaryRule, groupWeight string) error {
+	rules, err := public.ParseCanaryRules(canaryRule)
+	if err != nil {
+		return err
+	}
+	groupWeights, err := public.ParseUpstreamGroupWeight(groupWeight)
+	if err != nil {
+		return err
+	}
+	for _, rule := range rules {
+		if rule.Group != "" {
+			found := false
+			for _, item := range groupWeights {
+				if item.Name == rule.Group {
+					found = true
+					break
+				}
+			}
+			if !found {
+				return errors.Errorf("灰度路由的目标分组%s未配置流量比例", rule.Group)
+			}
+			continue
+		}
+		if rule.Target == serviceName {
+			return errors.New("灰度路由的目标服务不能为服务自身")
+		}
+		targetInfo := &ServiceInfo{}
+		if err := tx.WithContext(c).Where("service_name = ? and is_delete = 0", rule.Target).Find(targetInfo).Error; err != nil {
+			return err
+		}
+		if targetInfo.ID == 0 {
+			return errors.Errorf("灰度路由的目标服务%s不存在", rule.Target)
+		}
+		if targetInfo.LoadType != public.LoadTypeHTTP {
+			return errors.Errorf("灰度路由的目标服务%s不是http服务", rule.Target)
+		}
+	}
+	return nil
+}
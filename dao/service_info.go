@@ -1,6 +1,8 @@
 package dao
 
 import (
+	"database/sql"
+	"fmt"
 	"github.com/gin-gonic/gin"
 	"github.com/starMoonZhao/go_gateway/dto"
 	"gorm.io/gorm"
@@ -79,3 +81,20 @@ func (serviceInfo *ServiceInfo) GroupByLoadType(c *gin.Context, tx *gorm.DB) ([]
 
 	return dashServiceStatItemOutputList, nil
 }
+
+// 服务信息版本查询：以服务配置修订号标识服务配置是否发生变化 修订号在每次保存服务时递增(包括只修改子表的保存)
+// 同时带上记录总数及最近更新时间 直接修改数据库的服务基本信息同样能被发现（删除为软删除 同样会刷新更新时间）
+func (serviceInfo *ServiceInfo) Version(c *gin.Context, tx *gorm.DB) (string, error) {
+	version := &struct {
+		Total    int64
+		UpdateAt sql.NullTime
+	}{}
+	if err := tx.WithContext(c).Table(serviceInfo.TableName()).Select("count(*) as total", "max(update_at) as update_at").Scan(version).Error; err != nil {
+		return "", err
+	}
+	revision, err := GetServiceRevision(c, tx)
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("%d_%d_%d", revision, version.Total, version.UpdateAt.Time.UnixNano()), nil
+}
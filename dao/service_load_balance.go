@@ -1,11 +1,16 @@
 package dao
 
 import (
+	"errors"
 	"fmt"
 	"github.com/gin-gonic/gin"
+	"github.com/starMoonZhao/go_gateway/circuit_rate"
 	"github.com/starMoonZhao/go_gateway/public"
+	"github.com/starMoonZhao/go_gateway/reverse_proxy"
 	"github.com/starMoonZhao/go_gateway/reverse_proxy/load_balance"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"log"
 	"net"
 	"net/http"
 	"strings"
@@ -16,10 +21,17 @@ import (
 type LoadBalance struct {
 	ID                     int64  `json:"id" gorm:"primary_key"`
 	ServiceID              int64  `json:"service_id" gorm:"column:service_id" description:"服务id	"`
-	CheckMethod            int    `json:"check_method" gorm:"column:check_method" description:"检查方法 tcpchk=检测端口是否握手成功	"`
-	CheckTimeout           int    `json:"check_timeout" gorm:"column:check_timeout" description:"check超时时间	"`
+	CheckMethod            int    `json:"check_method" gorm:"column:check_method" description:"检查方法 0=tcpchk检测端口是否握手成功 1=httpchk检测http响应 2=grpcchk调用grpc健康检查"`
+	CheckTimeout           int    `json:"check_timeout" gorm:"column:check_timeout" description:"check超时时间, 单位s"`
 	CheckInterval          int    `json:"check_interval" gorm:"column:check_interval" description:"检查间隔, 单位s		"`
-	RoundType              int    `json:"round_type" gorm:"column:round_type" description:"轮询方式 round/weight_round/random/ip_hash"`
+	CheckPath              string `json:"check_path" gorm:"column:check_path" description:"http检查路径"`
+	CheckHost              string `json:"check_host" gorm:"column:check_host" description:"http检查使用的Host请求头 为空时使用节点地址"`
+	CheckStatus            string `json:"check_status" gorm:"column:check_status" description:"http检查正常状态码范围 如200-399"`
+	CheckBody              string `json:"check_body" gorm:"column:check_body" description:"http检查响应体需包含的内容 为空表示不检查"`
+	CheckService           string `json:"check_service" gorm:"column:check_service" description:"grpc检查的服务名称 为空表示检查节点整体状态"`
+	CheckHealthy           int    `json:"check_healthy" gorm:"column:check_healthy" description:"不可用节点连续检查成功达到该次数时恢复"`
+	CheckUnhealthy         int    `json:"check_unhealthy" gorm:"column:check_unhealthy" description:"可用节点连续检查失败达到该次数时摘除"`
+	RoundType              int    `json:"round_type" gorm:"column:round_type" description:"轮询方式 0=random 1=round 2=weight_round 3=ip_hash 4=least_conn 5=p2c"`
 	IpList                 string `json:"ip_list" gorm:"column:ip_list" description:"ip列表"`
 	WeightList             string `json:"weight_list" gorm:"column:weight_list" description:"权重列表"`
 	ForbidList             string `json:"forbid_list" gorm:"column:forbid_list" description:"禁用ip列表"`
@@ -27,6 +39,28 @@ type LoadBalance struct {
 	UpstreamHeaderTimeout  int    `json:"upstream_header_timeout" gorm:"column:upstream_header_timeout" description:"下游获取header超时, 单位s	"`
 	UpstreamIdleTimeout    int    `json:"upstream_idle_timeout" gorm:"column:upstream_idle_timeout" description:"下游链接最大空闲时间, 单位s	"`
 	UpstreamMaxIdle        int    `json:"upstream_max_idle" gorm:"column:upstream_max_idle" description:"下游最大空闲链接数"`
+	GroupList              string `json:"group_list" gorm:"column:group_list" description:"节点分组列表 与ip列表一一对应"`
+	GroupWeight            string `json:"group_weight" gorm:"column:group_weight" description:"分组流量比例 如v1:90,v2:10 为空表示不分组"`
+	GroupSticky            int    `json:"group_sticky" gorm:"column:group_sticky" description:"分组选择是否按客户端保持 1=是"`
+	RetryCount             int    `json:"retry_count" gorm:"column:retry_count" description:"请求失败时换节点重试的最大次数 0表示不重试"`
+	RetryBackoff           int    `json:"retry_backoff" gorm:"column:retry_backoff" description:"首次重试前的等待时间, 单位ms 之后每次翻倍"`
+	RetryBudget            int    `json:"retry_budget" gorm:"column:retry_budget" description:"重试预算 重试请求数占请求数的百分比上限 0表示不限制"`
+	CircuitErrorPercent    int    `json:"circuit_error_percent" gorm:"column:circuit_error_percent" description:"熔断错误率阈值 百分比 0表示不按错误率熔断"`
+	CircuitFailures        int    `json:"circuit_failures" gorm:"column:circuit_failures" description:"熔断连续失败次数阈值 0表示不按连续失败熔断"`
+	CircuitMinRequests     int    `json:"circuit_min_requests" gorm:"column:circuit_min_requests" description:"统计窗口内按错误率熔断的最小请求数"`
+	CircuitWindow          int    `json:"circuit_window" gorm:"column:circuit_window" description:"错误率统计窗口, 单位s"`
+	CircuitOpenTimeout     int    `json:"circuit_open_timeout" gorm:"column:circuit_open_timeout" description:"熔断持续时间, 单位s 之后进入半开状态"`
+	CircuitHalfOpen        int    `json:"circuit_half_open" gorm:"column:circuit_half_open" description:"半开状态放行的探测请求数"`
+	CircuitFallback        string `json:"circuit_fallback" gorm:"column:circuit_fallback" description:"熔断时返回的降级响应内容 为空时直接返回错误"`
+	OutlierFailures        int    `json:"outlier_failures" gorm:"column:outlier_failures" description:"被动健康检查 节点连续失败达到该次数时摘除 0表示不开启"`
+	OutlierEjectTime       int    `json:"outlier_eject_time" gorm:"column:outlier_eject_time" description:"节点基础摘除时间, 单位s 重复摘除时翻倍"`
+	OutlierMaxPercent      int    `json:"outlier_max_percent" gorm:"column:outlier_max_percent" description:"最多摘除节点的百分比"`
+	HashKey                string `json:"hash_key" gorm:"column:hash_key" description:"一致性hash的key 如client_ip、header:X-User-Id、cookie:sid、jwt:sub、path:1 为空时http使用请求url tcp及grpc使用客户端ip"`
+	HashReplicas           int    `json:"hash_replicas" gorm:"column:hash_replicas" description:"一致性hash每个节点的虚拟节点数 0表示使用默认值10"`
+	HashLoadFactor         int    `json:"hash_load_factor" gorm:"column:hash_load_factor" description:"一致性hash有界负载 节点进行中的请求数上限为平均值的(100+该值)% 0表示不限制"`
+	StickyCookie           string `json:"sticky_cookie" gorm:"column:sticky_cookie" description:"会话保持cookie名称 仅http服务 为空表示不开启"`
+	StickyMaxAge           int    `json:"sticky_max_age" gorm:"column:sticky_max_age" description:"会话保持cookie有效期, 单位s 0表示浏览器关闭前有效"`
+	SlowStart              int    `json:"slow_start" gorm:"column:slow_start" description:"慢启动时间, 单位s 恢复或新加入的节点权重在该时间内逐步增长 0表示不开启"`
 }
 
 func (loadBalance *LoadBalance) TableName() string {
@@ -37,6 +71,11 @@ func (loadBalance *LoadBalance) Find(c *gin.Context, tx *gorm.DB) error {
 	return tx.WithContext(c).Where(loadBalance).Find(loadBalance).Error
 }
 
+// 在事务中查询并锁定负载均衡配置 事务提交前其他事务无法读取锁定或修改该记录 用于先校验再保存的并发修改
+func (loadBalance *LoadBalance) FindForUpdate(c *gin.Context, tx *gorm.DB) error {
+	return tx.WithContext(c).Clauses(clause.Locking{Strength: "UPDATE"}).Where(loadBalance).Find(loadBalance).Error
+}
+
 func (loadBalance *LoadBalance) Save(c *gin.Context, tx *gorm.DB) error {
 	return tx.WithContext(c).Save(loadBalance).Error
 }
@@ -49,12 +88,127 @@ func (loadBalance *LoadBalance) GetWeightListByModel() []string {
 	return strings.Split(loadBalance.WeightList, ",")
 }
 
+// 手动禁用的节点列表 为空时返回空列表
+func (loadBalance *LoadBalance) GetForbidListByModel() []string {
+	forbidList := []string{}
+	for _, item := range strings.Split(loadBalance.ForbidList, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			forbidList = append(forbidList, item)
+		}
+	}
+	return forbidList
+}
+
+// 节点是否被手动禁用
+func (loadBalance *LoadBalance) NodeForbidden(node string) bool {
+	return load_balance.NodeForbidden(loadBalance.GetForbidListByModel(), node)
+}
+
+// 设置节点是否禁用 返回禁用列表是否发生变化
+func (loadBalance *LoadBalance) SetNodeForbidden(node string, forbidden bool) bool {
+	forbidList := []string{}
+	exist := false
+	for _, item := range loadBalance.GetForbidListByModel() {
+		if item == node {
+			exist = true
+			if !forbidden {
+				continue
+			}
+		}
+		forbidList = append(forbidList, item)
+	}
+	if exist == forbidden {
+		return false
+	}
+	if forbidden {
+		forbidList = append(forbidList, node)
+	}
+	loadBalance.ForbidList = strings.Join(forbidList, ",")
+	return true
+}
+
+func (loadBalance *LoadBalance) GetGroupListByModel() []string {
+	return strings.Split(loadBalance.GroupList, ",")
+}
+
+// 服务的熔断器配置 服务及上游节点的熔断器使用相同的配置
+func (loadBalance *LoadBalance) CircuitBreakerConf() circuit_rate.CircuitBreakerConf {
+	return circuit_rate.CircuitBreakerConf{
+		ErrorPercent:        loadBalance.CircuitErrorPercent,
+		ConsecutiveFailures: loadBalance.CircuitFailures,
+		MinRequests:         loadBalance.CircuitMinRequests,
+		Window:              time.Duration(loadBalance.CircuitWindow) * time.Second,
+		OpenTimeout:         time.Duration(loadBalance.CircuitOpenTimeout) * time.Second,
+		HalfOpenRequests:    loadBalance.CircuitHalfOpen,
+	}
+}
+
+// 服务的主动健康检查配置 状态码范围格式不正确时使用默认范围
+func (loadBalance *LoadBalance) CheckConf() load_balance.CheckConf {
+	statusMin, statusMax, err := public.ParseStatusRange(loadBalance.CheckStatus)
+	if err != nil {
+		statusMin, statusMax, _ = public.ParseStatusRange("")
+	}
+	return load_balance.CheckConf{
+		Method:             loadBalance.CheckMethod,
+		Timeout:            time.Duration(loadBalance.CheckTimeout) * time.Second,
+		Interval:           time.Duration(loadBalance.CheckInterval) * time.Second,
+		HealthyThreshold:   loadBalance.CheckHealthy,
+		UnhealthyThreshold: loadBalance.CheckUnhealthy,
+		HTTPPath:           loadBalance.CheckPath,
+		HTTPHost:           loadBalance.CheckHost,
+		StatusMin:          statusMin,
+		StatusMax:          statusMax,
+		BodyMatch:          loadBalance.CheckBody,
+		GRPCService:        loadBalance.CheckService,
+	}
+}
+
+// 服务的被动健康检查配置
+func (loadBalance *LoadBalance) OutlierConf() load_balance.OutlierConf {
+	return load_balance.OutlierConf{
+		ConsecutiveFailures: loadBalance.OutlierFailures,
+		EjectTime:           time.Duration(loadBalance.OutlierEjectTime) * time.Second,
+		MaxEjectPercent:     loadBalance.OutlierMaxPercent,
+	}
+}
+
+// 服务一致性hash的key 未配置或格式不正确时返回nil
+func (loadBalance *LoadBalance) HashKeyConf() *public.HashKey {
+	hashKey, err := public.ParseHashKey(loadBalance.HashKey)
+	if err != nil {
+		return nil
+	}
+	return hashKey
+}
+
+// 服务负载均衡器的可选参数
+func (loadBalance *LoadBalance) BalanceOptions() load_balance.LoadBalanceOptions {
+	return load_balance.LoadBalanceOptions{
+		HashReplicas:   loadBalance.HashReplicas,
+		HashLoadFactor: float64(loadBalance.HashLoadFactor) / 100,
+	}
+}
+
 var LoadBalancerHandler *LoadBalancer
 
 // 存储slice中的服务负载均衡器对象serviceName->LoadBalance
 type LoadBalancerItem struct {
 	ServiceName string
 	LoadBalance load_balance.LoadBalance
+	Conf        load_balance.LoadBalanceConf
+	GroupWeight []public.UpstreamGroupWeight      //分组流量比例 为空表示不分组
+	GroupSticky bool                              //分组选择是否按客户端保持
+	Groups      map[string]*LoadBalancerGroupItem //分组名称->分组负载均衡器
+	RetryBudget *reverse_proxy.RetryBudget        //失败重试预算 为nil表示不限制
+	service     *ServiceDetail                    //构建负载均衡器使用的服务配置
+	closeChan   chan struct{}                     //负载均衡器移除时关闭
+}
+
+// 上游分组的负载均衡器 只包含分组内的节点
+type LoadBalancerGroupItem struct {
+	LoadBalance load_balance.LoadBalance
+	Conf        load_balance.LoadBalanceConf
 }
 
 // 存储所有服务的负载均衡器 一个服务对应使用一个负载均衡器serviceName->LoadBalance
@@ -79,14 +233,271 @@ func init() {
 
 // 根据serviceDetail获取服务对应的负载均衡器
 func (l *LoadBalancer) GetLoadBalance(service *ServiceDetail) (load_balance.LoadBalance, error) {
-	//step1:查询LoadBalanceSlice中是否已存在对应服务的负载均衡器
-	for _, lbItem := range l.LoadBalanceSlice {
-		if lbItem.ServiceName == service.Info.ServiceName {
-			return lbItem.LoadBalance, nil
+	lbItem, err := l.getLoadBalancerItem(service)
+	if err != nil {
+		return nil, err
+	}
+	return lbItem.LoadBalance, nil
+}
+
+// 按分组流量比例选择上游分组 返回分组名称及分组的负载均衡器
+// 服务未配置分组时返回空分组名称及服务整体的负载均衡器 clientKey用于按客户端保持分组选择
+// groupName不为空时(如灰度路由规则指定了目标分组)直接使用该分组
+func (l *LoadBalancer) GetGroupLoadBalance(service *ServiceDetail, clientKey, groupName string) (string, load_balance.LoadBalance, error) {
+	lbItem, err := l.getLoadBalancerItem(service)
+	if err != nil {
+		return "", nil, err
+	}
+	if len(lbItem.GroupWeight) == 0 {
+		return "", lbItem.LoadBalance, nil
+	}
+	if groupName == "" {
+		stickyKey := ""
+		if lbItem.GroupSticky {
+			stickyKey = clientKey
 		}
+		groupName = public.SelectUpstreamGroup(lbItem.GroupWeight, stickyKey)
 	}
+	groupItem, ok := lbItem.Groups[groupName]
+	if !ok {
+		return "", lbItem.LoadBalance, nil
+	}
+	return groupName, groupItem.LoadBalance, nil
+}
 
-	//step2:如无则新建
+// 获取服务的失败重试策略 服务未开启重试时返回nil onRetry在每次重试时回调
+func (l *LoadBalancer) GetRetryPolicy(service *ServiceDetail, onRetry func()) (*reverse_proxy.RetryPolicy, error) {
+	if service.LoadBalance.RetryCount <= 0 {
+		return nil, nil
+	}
+	lbItem, err := l.getLoadBalancerItem(service)
+	if err != nil {
+		return nil, err
+	}
+	return &reverse_proxy.RetryPolicy{
+		Count:   service.LoadBalance.RetryCount,
+		Backoff: time.Duration(service.LoadBalance.RetryBackoff) * time.Millisecond,
+		Budget:  lbItem.RetryBudget,
+		OnRetry: onRetry,
+	}, nil
+}
+
+// 获取服务上游节点的请求结果上报函数 用于被动健康检查 服务未开启时返回nil
+// 节点同时上报到服务整体及所在分组的负载均衡配置
+func (l *LoadBalancer) GetNodeReporter(service *ServiceDetail) (reverse_proxy.NodeReporter, error) {
+	if !service.LoadBalance.OutlierConf().Enabled() {
+		return nil, nil
+	}
+	lbItem, err := l.getLoadBalancerItem(service)
+	if err != nil {
+		return nil, err
+	}
+	confList := []load_balance.LoadBalanceConf{lbItem.Conf}
+	for _, groupItem := range lbItem.Groups {
+		confList = append(confList, groupItem.Conf)
+	}
+	return func(node string, success bool) {
+		for _, conf := range confList {
+			if configCheck, ok := conf.(*load_balance.LoadBalanceConfigCheck); ok {
+				configCheck.ReportResult(node, success)
+			}
+		}
+	}, nil
+}
+
+// 获取http服务基于cookie的会话保持配置 服务未开启时返回nil
+// 会话保持的节点从groupName对应分组的活跃节点中查找 未分组时为服务整体的活跃节点 节点被摘除时重新选择节点并下发cookie
+func (l *LoadBalancer) GetStickySession(service *ServiceDetail, groupName string) (*reverse_proxy.StickySession, error) {
+	if service.LoadBalance.StickyCookie == "" || service.Info.LoadType != public.LoadTypeHTTP {
+		return nil, nil
+	}
+	lbItem, err := l.getLoadBalancerItem(service)
+	if err != nil {
+		return nil, err
+	}
+	conf := lbItem.Conf
+	if groupItem, ok := lbItem.Groups[groupName]; ok {
+		conf = groupItem.Conf
+	}
+	configCheck, ok := conf.(*load_balance.LoadBalanceConfigCheck)
+	if !ok {
+		return nil, nil
+	}
+	return &reverse_proxy.StickySession{
+		Name:       service.LoadBalance.StickyCookie,
+		MaxAge:     service.LoadBalance.StickyMaxAge,
+		Salt:       service.Info.ServiceName,
+		ActiveList: configCheck.ActiveList,
+	}, nil
+}
+
+func (l *LoadBalancer) getLoadBalancerItem(service *ServiceDetail) (*LoadBalancerItem, error) {
+	//请求持有的服务配置已被替换时 使用当前已加载的服务配置 避免按过期配置重建并缓存负载均衡器
+	currentService, ok := ServiceManegerHandler.GetService(service.Info.ServiceName)
+	if !ok {
+		//服务已删除 按请求持有的配置生成临时的负载均衡器 不缓存也不探活
+		lbItem, err := newLoadBalancerItem(service, nil)
+		if err != nil {
+			return nil, err
+		}
+		lbItem.close()
+		return lbItem, nil
+	}
+	service = currentService
+
+	//step1:查询LoadBalanceMap中是否已存在按当前服务配置构建的负载均衡器
+	l.Locker.RLock()
+	lbItem, ok := l.LoadBalanceMap[service.Info.ServiceName]
+	current := ok && lbItem.service == service
+	l.Locker.RUnlock()
+	if current {
+		return lbItem, nil
+	}
+
+	//step2:如无则新建 替换按过期配置构建的负载均衡器时沿用其节点的运行时状态
+	var previous *LoadBalancerItem
+	if ok {
+		previous = lbItem
+	}
+	lbItem, err := newLoadBalancerItem(service, previous)
+	if err != nil {
+		return nil, err
+	}
+
+	//step3:存入LoadBalanceMap和LoadBalanceSlice
+	l.Locker.Lock()
+	defer l.Locker.Unlock()
+	if existItem, ok := l.LoadBalanceMap[service.Info.ServiceName]; ok {
+		//并发创建时以先存入的负载均衡器为准
+		if existItem.service == service {
+			lbItem.close()
+			return existItem, nil
+		}
+		//替换按过期配置构建的负载均衡器
+		existItem.close()
+		l.removeItem(service.Info.ServiceName)
+	}
+	l.LoadBalanceSlice = append(l.LoadBalanceSlice, lbItem)
+	l.LoadBalanceMap[service.Info.ServiceName] = lbItem
+	//定时上报节点健康状态
+	go lbItem.saveNodeStatusLoop()
+	//按耗时选择节点时 定时上报节点评分
+	if load_balance.LbType(service.LoadBalance.RoundType) == load_balance.LbP2C {
+		go lbItem.saveBalanceScoreLoop()
+	}
+	return lbItem, nil
+}
+
+// 按服务配置生成负载均衡器及其分组负载均衡器 previous为按过期配置构建的负载均衡器 不为nil时沿用其节点的运行时状态
+func newLoadBalancerItem(service *ServiceDetail, previous *LoadBalancerItem) (*LoadBalancerItem, error) {
+	//获取服务ip列表及权重列表
+	ipList := service.LoadBalance.GetIPListByModel()
+	weightList := service.LoadBalance.GetWeightListByModel()
+	loadBalance, loadBalanceConfigCheck, err := newServiceLoadBalance(service, ipList, weightList, nil)
+	if err != nil {
+		return nil, err
+	}
+	lbItem := &LoadBalancerItem{
+		ServiceName: service.Info.ServiceName,
+		LoadBalance: loadBalance,
+		Conf:        loadBalanceConfigCheck,
+		GroupSticky: service.LoadBalance.GroupSticky == 1,
+		Groups:      map[string]*LoadBalancerGroupItem{},
+		RetryBudget: reverse_proxy.NewRetryBudget(service.LoadBalance.RetryBudget),
+		service:     service,
+		closeChan:   make(chan struct{}),
+	}
+
+	//按节点分组分别生成分组负载均衡器 分组配置不正确时不分组
+	if err := lbItem.buildGroups(service, ipList, weightList); err != nil {
+		log.Printf(" [ERROR] service %s upstream group err:%v\n", service.Info.ServiceName, err)
+		lbItem.closeGroups()
+		lbItem.GroupWeight = nil
+		lbItem.Groups = map[string]*LoadBalancerGroupItem{}
+	}
+	lbItem.inherit(previous)
+	return lbItem, nil
+}
+
+// 沿用重建前负载均衡器中节点的运行时状态 分组优先沿用旧负载均衡器中的同名分组 无同名分组时沿用服务整体
+func (lbItem *LoadBalancerItem) inherit(previous *LoadBalancerItem) {
+	if previous == nil {
+		return
+	}
+	inheritConf(lbItem.Conf, previous.Conf)
+	for groupName, groupItem := range lbItem.Groups {
+		if previousGroupItem, ok := previous.Groups[groupName]; ok {
+			inheritConf(groupItem.Conf, previousGroupItem.Conf)
+		} else {
+			inheritConf(groupItem.Conf, previous.Conf)
+		}
+	}
+}
+
+// 沿用旧负载均衡配置中节点的慢启动状态、状态变化时间及探活结果
+func inheritConf(conf, previous load_balance.LoadBalanceConf) {
+	configCheck, ok := conf.(*load_balance.LoadBalanceConfigCheck)
+	if !ok {
+		return
+	}
+	previousConfigCheck, ok := previous.(*load_balance.LoadBalanceConfigCheck)
+	if !ok {
+		return
+	}
+	configCheck.InheritSlowStart(previousConfigCheck)
+	configCheck.InheritNodeStates(previousConfigCheck)
+}
+
+// 停止负载均衡器的服务探活及定时上报
+func (lbItem *LoadBalancerItem) close() {
+	lbItem.Conf.CloseWatch()
+	lbItem.closeGroups()
+	close(lbItem.closeChan)
+}
+
+// 生成服务分组的负载均衡器
+func (lbItem *LoadBalancerItem) buildGroups(service *ServiceDetail, ipList, weightList []string) error {
+	if !public.ValidUpstreamGroup(service.LoadBalance.IpList, service.LoadBalance.WeightList, service.LoadBalance.GroupList, service.LoadBalance.GroupWeight) {
+		return errors.New("upstream group config invalid")
+	}
+	groupWeight, _ := public.ParseUpstreamGroupWeight(service.LoadBalance.GroupWeight)
+	if len(groupWeight) == 0 {
+		return nil
+	}
+	//分组列表按位置对应ip列表及权重列表
+	groupList := service.LoadBalance.GetGroupListByModel()
+	if len(groupList) != len(ipList) || len(groupList) != len(weightList) {
+		return errors.New("upstream group list does not match ip list or weight list")
+	}
+	//分组与服务整体共享主动健康检查 节点只探活一次
+	parent, _ := lbItem.Conf.(*load_balance.LoadBalanceConfigCheck)
+	for _, item := range groupWeight {
+		groupIpList, groupWeightList := []string{}, []string{}
+		for index, groupName := range groupList {
+			if groupName == item.Name {
+				groupIpList = append(groupIpList, ipList[index])
+				groupWeightList = append(groupWeightList, weightList[index])
+			}
+		}
+		loadBalance, loadBalanceConfigCheck, err := newServiceLoadBalance(service, groupIpList, groupWeightList, parent)
+		if err != nil {
+			return err
+		}
+		lbItem.Groups[item.Name] = &LoadBalancerGroupItem{LoadBalance: loadBalance, Conf: loadBalanceConfigCheck}
+	}
+	lbItem.GroupWeight = groupWeight
+	return nil
+}
+
+// 停止所有分组负载均衡器的服务探活
+func (lbItem *LoadBalancerItem) closeGroups() {
+	for _, groupItem := range lbItem.Groups {
+		groupItem.Conf.CloseWatch()
+	}
+}
+
+// 根据节点列表生成负载均衡器
+func newServiceLoadBalance(service *ServiceDetail, ipList, weightList []string, parent *load_balance.LoadBalanceConfigCheck) (load_balance.LoadBalance, *load_balance.LoadBalanceConfigCheck, error) {
 	schema := "http://"
 	if service.HTTPRule.NeedHttps == 1 {
 		schema = "https://"
@@ -96,11 +507,10 @@ func (l *LoadBalancer) GetLoadBalance(service *ServiceDetail) (load_balance.Load
 		schema = ""
 	}
 
-	//获取服务ip列表及权重列表
-	ipList := service.LoadBalance.GetIPListByModel()
-	weightList := service.LoadBalance.GetWeightListByModel()
-
-	//将服务及权重进行映射并组装2
+	//将服务及权重进行映射并组装2 ip列表与权重列表需一一对应
+	if len(ipList) != len(weightList) {
+		return nil, nil, errors.New("ip list does not match weight list")
+	}
 	ipConf := map[string]string{}
 	for index, ip := range ipList {
 		ipConf[ip] = weightList[index]
@@ -108,23 +518,107 @@ func (l *LoadBalancer) GetLoadBalance(service *ServiceDetail) (load_balance.Load
 	//生成服务格式化字符串format
 	format := fmt.Sprintf("%s%s", schema, "%s")
 	//生成负载均衡配置LoadBalanceConf：使用手动发现模式
-	loadBalanceConfigCheck, err := load_balance.NewLoadBalanceConfigCheck(ipConf, format)
-	if err != nil {
-		return nil, err
+	//分组的负载均衡配置使用服务整体的主动健康检查结果
+	var loadBalanceConfigCheck *load_balance.LoadBalanceConfigCheck
+	if parent != nil {
+		loadBalanceConfigCheck = parent.NewGroupConfigCheck(ipConf)
+	} else {
+		var err error
+		loadBalanceConfigCheck, err = load_balance.NewLoadBalanceConfigCheck(ipConf, format, service.LoadBalance.CheckConf())
+		if err != nil {
+			return nil, nil, err
+		}
 	}
+	//开启被动健康检查 按真实请求结果摘除连续失败的节点
+	loadBalanceConfigCheck.SetOutlierConf(service.LoadBalance.OutlierConf())
+	//手动禁用的节点不再分配新请求
+	loadBalanceConfigCheck.SetForbidList(service.LoadBalance.GetForbidListByModel())
+	//开启慢启动 恢复或新加入的节点逐步增加流量
+	loadBalanceConfigCheck.SetSlowStart(time.Duration(service.LoadBalance.SlowStart) * time.Second)
 	//使用负载均衡配置生成负载均衡器
-	loadBalance := load_balance.LoadBalanceFactoryWithConf(load_balance.LbType(service.LoadBalance.RoundType), loadBalanceConfigCheck)
+	loadBalance := load_balance.LoadBalanceFactoryWithOptions(load_balance.LbType(service.LoadBalance.RoundType), loadBalanceConfigCheck, service.LoadBalance.BalanceOptions())
+	return loadBalance, loadBalanceConfigCheck, nil
+}
 
-	//step3:存入LoadBalanceMap和LoadBalanceSlice
-	lbItem := &LoadBalancerItem{
-		ServiceName: service.Info.ServiceName,
-		LoadBalance: loadBalance,
+// 服务配置变化时按新配置重建已存在的负载均衡器 并沿用旧负载均衡器中节点的运行时状态
+// 仅手动禁用列表变化时(如摘除、恢复节点)不重建 在原负载均衡器上更新禁用列表
+// 服务还未创建负载均衡器时不处理 下次获取时再创建
+func (l *LoadBalancer) Update(service *ServiceDetail) {
+	l.Locker.RLock()
+	lbItem, ok := l.LoadBalanceMap[service.Info.ServiceName]
+	var oldService *ServiceDetail
+	if ok {
+		oldService = lbItem.service
 	}
-	l.LoadBalanceSlice = append(l.LoadBalanceSlice, lbItem)
+	l.Locker.RUnlock()
+	if !ok {
+		return
+	}
+	if forbidListChangedOnly(oldService, service) {
+		lbItem.setForbidList(service.LoadBalance.GetForbidListByModel())
+		l.Locker.Lock()
+		if l.LoadBalanceMap[service.Info.ServiceName] == lbItem {
+			lbItem.service = service
+		}
+		l.Locker.Unlock()
+		return
+	}
+	if _, err := l.getLoadBalancerItem(service); err != nil {
+		log.Printf(" [ERROR] service %s rebuild load balance err:%v\n", service.Info.ServiceName, err)
+		l.Remove(service.Info.ServiceName)
+	}
+}
+
+// 两个服务配置是否仅手动禁用列表不同
+func forbidListChangedOnly(oldService, newService *ServiceDetail) bool {
+	if oldService == nil || newService == nil || oldService.LoadBalance == nil || newService.LoadBalance == nil {
+		return false
+	}
+	if oldService.LoadBalance.ForbidList == newService.LoadBalance.ForbidList {
+		return false
+	}
+	oldLoadBalance, newLoadBalance := *oldService.LoadBalance, *newService.LoadBalance
+	oldLoadBalance.ForbidList, newLoadBalance.ForbidList = "", ""
+	oldDetail, newDetail := *oldService, *newService
+	oldDetail.LoadBalance, newDetail.LoadBalance = &oldLoadBalance, &newLoadBalance
+	return public.Obj2Json(oldDetail) == public.Obj2Json(newDetail)
+}
+
+// 在服务整体及所有分组的负载均衡配置上更新禁用列表 节点的运行时状态保持不变
+func (lbItem *LoadBalancerItem) setForbidList(forbidList []string) {
+	if conf, ok := lbItem.Conf.(*load_balance.LoadBalanceConfigCheck); ok {
+		conf.SetForbidList(forbidList)
+	}
+	for _, groupItem := range lbItem.Groups {
+		if conf, ok := groupItem.Conf.(*load_balance.LoadBalanceConfigCheck); ok {
+			conf.SetForbidList(forbidList)
+		}
+	}
+}
+
+// 移除服务对应的负载均衡器 并停止其服务探活
+// 正在使用旧负载均衡器的请求不受影响 下次获取时按最新服务配置重建
+func (l *LoadBalancer) Remove(serviceName string) {
 	l.Locker.Lock()
 	defer l.Locker.Unlock()
-	l.LoadBalanceMap[service.Info.ServiceName] = lbItem
-	return loadBalance, nil
+	lbItem, ok := l.LoadBalanceMap[serviceName]
+	if !ok {
+		return
+	}
+	lbItem.close()
+	l.removeItem(serviceName)
+}
+
+// 从LoadBalanceMap和LoadBalanceSlice中删除服务的负载均衡器 调用方需持有Locker
+func (l *LoadBalancer) removeItem(serviceName string) {
+	delete(l.LoadBalanceMap, serviceName)
+	lbSlice := []*LoadBalancerItem{}
+	for _, item := range l.LoadBalanceSlice {
+		if item.ServiceName != serviceName {
+			lbSlice = append(lbSlice, item)
+		}
+	}
+	l.LoadBalanceSlice = lbSlice
 }
 
 var TransportorHandler *Transportor
@@ -133,6 +627,7 @@ var TransportorHandler *Transportor
 type TransportorItem struct {
 	ServiceName string
 	Trans       *http.Transport
+	service     *ServiceDetail //构建连接池使用的服务配置
 }
 
 // 存储所有服务的连接池 一个服务对应使用一个连接池serviceName->TransportorItem
@@ -157,15 +652,49 @@ func init() {
 
 // 根据serviceDetail获取服务对应的连接池
 func (t *Transportor) GetTrans(service *ServiceDetail) (*http.Transport, error) {
-	//step1:查询TransportorSlice中是否已存在对应服务的负载均衡器
-	for _, tsItem := range t.TransportorSlice {
-		if tsItem.ServiceName == service.Info.ServiceName {
-			return tsItem.Trans, nil
-		}
+	//请求持有的服务配置已被替换时 使用当前已加载的服务配置 服务已删除时生成不缓存的连接池
+	currentService, ok := ServiceManegerHandler.GetService(service.Info.ServiceName)
+	if !ok {
+		return newServiceTransport(service), nil
+	}
+	service = currentService
+
+	//step1:查询TransportorMap中是否已存在按当前服务配置构建的连接池
+	t.Locker.RLock()
+	tsItem, ok := t.TransportorMap[service.Info.ServiceName]
+	t.Locker.RUnlock()
+	if ok && tsItem.service == service {
+		return tsItem.Trans, nil
 	}
 
 	//step2:如无则新建
-	transport := &http.Transport{
+	transport := newServiceTransport(service)
+
+	//step3:存入TransportorMap和TransportorSlice
+	tsItem = &TransportorItem{
+		ServiceName: service.Info.ServiceName,
+		Trans:       transport,
+		service:     service,
+	}
+	t.Locker.Lock()
+	defer t.Locker.Unlock()
+	if existItem, ok := t.TransportorMap[service.Info.ServiceName]; ok {
+		//并发创建时以先存入的连接池为准
+		if existItem.service == service {
+			return existItem.Trans, nil
+		}
+		//替换按过期配置构建的连接池
+		existItem.Trans.CloseIdleConnections()
+		t.removeItem(service.Info.ServiceName)
+	}
+	t.TransportorSlice = append(t.TransportorSlice, tsItem)
+	t.TransportorMap[service.Info.ServiceName] = tsItem
+	return transport, nil
+}
+
+// 按服务配置生成连接池
+func newServiceTransport(service *ServiceDetail) *http.Transport {
+	return &http.Transport{
 		Proxy: http.ProxyFromEnvironment,
 		DialContext: (&net.Dialer{
 			Timeout:   time.Duration(service.LoadBalance.UpstreamConnectTimeout) * time.Second, //连接超时
@@ -177,15 +706,29 @@ func (t *Transportor) GetTrans(service *ServiceDetail) (*http.Transport, error)
 		TLSHandshakeTimeout:   10 * time.Second,                                                       //tls握手超时时间
 		ResponseHeaderTimeout: time.Duration(service.LoadBalance.UpstreamHeaderTimeout) * time.Second, //100-continue超时时间
 	}
+}
 
-	//step3:存入TransportorMap和TransportorSlice
-	tsItem := &TransportorItem{
-		ServiceName: service.Info.ServiceName,
-		Trans:       transport,
-	}
-	t.TransportorSlice = append(t.TransportorSlice, tsItem)
+// 移除服务对应的连接池 并关闭其中的空闲连接
+// 正在使用中的连接不受影响 下次获取时按最新服务配置重建
+func (t *Transportor) Remove(serviceName string) {
 	t.Locker.Lock()
 	defer t.Locker.Unlock()
-	t.TransportorMap[service.Info.ServiceName] = tsItem
-	return transport, nil
+	tsItem, ok := t.TransportorMap[serviceName]
+	if !ok {
+		return
+	}
+	tsItem.Trans.CloseIdleConnections()
+	t.removeItem(serviceName)
+}
+
+// 从TransportorMap和TransportorSlice中删除服务的连接池 调用方需持有Locker
+func (t *Transportor) removeItem(serviceName string) {
+	delete(t.TransportorMap, serviceName)
+	tsSlice := []*TransportorItem{}
+	for _, item := range t.TransportorSlice {
+		if item.ServiceName != serviceName {
+			tsSlice = append(tsSlice, item)
+		}
+	}
+	t.TransportorSlice = tsSlice
 }
@@ -0,0 +1,144 @@
+package dao
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/e421083458/golang_common/lib"
+	"github.com/garyburd/redigo/redis"
+	"github.com/starMoonZhao/go_gateway/public"
+	"github.com/starMoonZhao/go_gateway/reverse_proxy/load_balance"
+	"log"
+	"os"
+	"sort"
+	"time"
+)
+
+const (
+	nodeStatusInterval = 5 * time.Second //节点状态写入redis的间隔
+	nodeStatusExpire   = 60              //节点状态过期时间, 单位s 代理节点停止后状态自动失效
+)
+
+// 上游节点状态快照 写入redis供管理后台展示
+type NodeStatus struct {
+	load_balance.NodeStatus
+	Proxy    string `json:"proxy"` //上报该状态的代理节点
+	UpdateAt int64  `json:"update_at"`
+}
+
+// 单个代理节点上报的服务各节点状态
+type proxyNodeStatus struct {
+	List     []load_balance.NodeStatus `json:"list"`
+	UpdateAt int64                     `json:"update_at"`
+}
+
+// 当前代理节点的标识 多个代理节点的状态分别写入 互不覆盖
+var proxyInstance = newProxyInstance()
+
+func newProxyInstance() string {
+	hostname, err := os.Hostname()
+	if err != nil {
+		hostname = "unknown"
+	}
+	return fmt.Sprintf("%s_%d", hostname, os.Getpid())
+}
+
+// 节点状态在redis中的key 每个服务一个hash 字段为代理节点标识 值为该代理节点上报的所有节点状态
+func NodeStatusKey(serviceName string) string {
+	return fmt.Sprintf("%s_%s", public.RedisNodeStatusKey, serviceName)
+}
+
+// 定时将服务各节点的健康状态写入redis 负载均衡器移除后退出
+func (lbItem *LoadBalancerItem) saveNodeStatusLoop() {
+	ticker := time.NewTicker(nodeStatusInterval)
+	defer ticker.Stop()
+	for {
+		if err := lbItem.saveNodeStatus(); err != nil {
+			log.Printf(" [ERROR] save node status %s err:%v\n", lbItem.ServiceName, err)
+		}
+		select {
+		case <-lbItem.closeChan:
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (lbItem *LoadBalancerItem) saveNodeStatus() error {
+	//服务整体的负载均衡配置包含所有节点 分组节点的摘除情况同样上报到服务整体
+	configCheck, ok := lbItem.Conf.(*load_balance.LoadBalanceConfigCheck)
+	if !ok {
+		return nil
+	}
+	data, _ := json.Marshal(&proxyNodeStatus{List: configCheck.NodeStatus(), UpdateAt: time.Now().Unix()})
+	key := NodeStatusKey(lbItem.ServiceName)
+	conn, err := lib.RedisConnFactory("default")
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	//只覆盖当前代理节点的字段 其他代理节点上报的状态保持不变
+	conn.Send("MULTI")
+	conn.Send("HSET", key, proxyInstance, data)
+	conn.Send("EXPIRE", key, nodeStatusExpire)
+	_, err = conn.Do("EXEC")
+	return err
+}
+
+// 节点状态的严重程度 多个代理节点上报的状态不一致时展示最严重的状态
+var nodeStateLevel = map[string]int{
+	load_balance.NodeStateDrained:   4,
+	load_balance.NodeStateUnhealthy: 3,
+	load_balance.NodeStateEjected:   2,
+	load_balance.NodeStateHealthy:   1,
+}
+
+// 读取服务各节点的健康状态 合并所有代理节点的上报
+// 同一节点取最严重的状态 状态相同时取最近一次主动探活的结果 超过过期时间未上报的代理节点忽略并清理
+func GetNodeStatus(serviceName string) ([]*NodeStatus, error) {
+	conn, err := lib.RedisConnFactory("default")
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+	key := NodeStatusKey(serviceName)
+	values, err := redis.StringMap(conn.Do("HGETALL", key))
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now().Unix()
+	statusMap := map[string]*NodeStatus{}
+	for proxy, value := range values {
+		proxyStatus := &proxyNodeStatus{}
+		if err := json.Unmarshal([]byte(value), proxyStatus); err != nil || now-proxyStatus.UpdateAt > nodeStatusExpire {
+			//代理节点已停止或格式不正确
+			conn.Do("HDEL", key, proxy)
+			continue
+		}
+		for _, item := range proxyStatus.List {
+			status := &NodeStatus{NodeStatus: item, Proxy: proxy, UpdateAt: proxyStatus.UpdateAt}
+			if old, ok := statusMap[item.Addr]; ok && !nodeStatusWorse(status, old) {
+				continue
+			}
+			statusMap[item.Addr] = status
+		}
+	}
+	statusList := []*NodeStatus{}
+	for _, status := range statusMap {
+		statusList = append(statusList, status)
+	}
+	sort.Slice(statusList, func(i, j int) bool {
+		return statusList[i].Addr < statusList[j].Addr
+	})
+	return statusList, nil
+}
+
+// 节点状态status是否应替换old展示
+func nodeStatusWorse(status, old *NodeStatus) bool {
+	if nodeStateLevel[status.State] != nodeStateLevel[old.State] {
+		return nodeStateLevel[status.State] > nodeStateLevel[old.State]
+	}
+	if status.LastProbeTime != old.LastProbeTime {
+		return status.LastProbeTime > old.LastProbeTime
+	}
+	return status.Proxy < old.Proxy
+}
@@ -0,0 +1,40 @@
+package dao
+
+import (
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// 服务配置修订号：全局单行计数器 任何服务配置的保存都在同一事务内递增
+// 服务配置可能只修改规则、负载均衡等子表 且更新时间只精确到秒 代理节点按修订号判断服务配置是否变化
+// 建表语句同时收录在migrations/upgrade.sql中
+//
+//	CREATE TABLE `gateway_service_revision` (
+//	  `id` bigint(20) NOT NULL COMMENT '主键 固定为1',
+//	  `revision` bigint(20) NOT NULL DEFAULT '0' COMMENT '服务配置修订号 每次保存递增',
+//	  PRIMARY KEY (`id`)
+//	) ENGINE=InnoDB DEFAULT CHARSET=utf8 COMMENT='网关服务配置修订号';
+type ServiceRevision struct {
+	ID       int64 `json:"id" gorm:"primary_key" description:"主键 固定为1"`
+	Revision int64 `json:"revision" gorm:"column:revision" description:"服务配置修订号 每次保存递增"`
+}
+
+func (serviceRevision *ServiceRevision) TableName() string {
+	return "gateway_service_revision"
+}
+
+// 递增服务配置修订号 在保存服务的事务内调用 行锁保证并发保存时修订号严格递增
+func BumpServiceRevision(c *gin.Context, tx *gorm.DB) error {
+	return tx.WithContext(c).Exec("INSERT INTO " + (&ServiceRevision{}).TableName() +
+		" (id, revision) VALUES (1, 1) ON DUPLICATE KEY UPDATE revision = revision + 1").Error
+}
+
+// 查询当前的服务配置修订号 还未保存过服务时为0
+func GetServiceRevision(c *gin.Context, tx *gorm.DB) (int64, error) {
+	serviceRevision := &ServiceRevision{}
+	if err := tx.WithContext(c).Table(serviceRevision.TableName()).Where("id = 1").
+		Select("coalesce(max(revision), 0) as revision").Scan(serviceRevision).Error; err != nil {
+		return 0, err
+	}
+	return serviceRevision.Revision, nil
+}
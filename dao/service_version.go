@@ -0,0 +1,176 @@
+package dao
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/gin-gonic/gin"
+	"github.com/starMoonZhao/go_gateway/dto"
+	"gorm.io/gorm"
+	"sort"
+	"time"
+)
+
+// 服务配置版本快照：服务每次保存后记录一份完整的服务详情
+// 建表语句同时收录在migrations/upgrade.sql中
+//
+//	CREATE TABLE `gateway_service_version` (
+//	  `id` bigint(20) NOT NULL AUTO_INCREMENT COMMENT '自增主键',
+//	  `service_id` bigint(20) NOT NULL DEFAULT '0' COMMENT '服务id',
+//	  `version` int(11) NOT NULL DEFAULT '0' COMMENT '版本号 同一服务内递增',
+//	  `detail` mediumtext NOT NULL COMMENT '服务详情快照 json',
+//	  `author` varchar(255) NOT NULL DEFAULT '' COMMENT '操作人',
+//	  `remark` varchar(255) NOT NULL DEFAULT '' COMMENT '备注',
+//	  `create_at` datetime NOT NULL DEFAULT '1971-01-01 00:00:00' COMMENT '创建时间',
+//	  PRIMARY KEY (`id`),
+//	  UNIQUE KEY `idx_service_version` (`service_id`,`version`)
+//	) ENGINE=InnoDB DEFAULT CHARSET=utf8 COMMENT='网关服务配置版本';
+type ServiceVersion struct {
+	ID        int64     `json:"id" gorm:"primary_key" description:"自增主键"`
+	ServiceID int64     `json:"service_id" gorm:"column:service_id" description:"服务id"`
+	Version   int       `json:"version" gorm:"column:version" description:"版本号 同一服务内递增"`
+	Detail    string    `json:"detail" gorm:"column:detail" description:"服务详情快照 json"`
+	Author    string    `json:"author" gorm:"column:author" description:"操作人"`
+	Remark    string    `json:"remark" gorm:"column:remark" description:"备注"`
+	CreatedAt time.Time `json:"create_at" gorm:"column:create_at" description:"创建时间"`
+}
+
+func (serviceVersion *ServiceVersion) TableName() string {
+	return "gateway_service_version"
+}
+
+func (serviceVersion *ServiceVersion) Find(c *gin.Context, tx *gorm.DB) error {
+	return tx.WithContext(c).Where(serviceVersion).Find(serviceVersion).Error
+}
+
+func (serviceVersion *ServiceVersion) Save(c *gin.Context, tx *gorm.DB) error {
+	return tx.WithContext(c).Save(serviceVersion).Error
+}
+
+// 服务版本列表分页查询 按版本号倒序
+func (serviceVersion *ServiceVersion) PageList(c *gin.Context, tx *gorm.DB, param *dto.ServiceVersionListInput) ([]ServiceVersion, int64, error) {
+	//总条数
+	total := int64(0)
+	//结果集
+	list := []ServiceVersion{}
+
+	//分页查询偏移量
+	offset := int((param.PageNum - 1) * param.PageSize)
+
+	query := tx.WithContext(c).Table(serviceVersion.TableName()).Where("service_id = ?", param.ID)
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+	//列表中不返回快照内容
+	if err := query.Select("id", "service_id", "version", "author", "remark", "create_at").
+		Order("version desc").Offset(offset).Limit(int(param.PageSize)).Find(&list).Error; err != nil && err != gorm.ErrRecordNotFound {
+		return nil, 0, err
+	}
+	return list, total, nil
+}
+
+// 解析快照中的服务详情
+func (serviceVersion *ServiceVersion) ServiceDetail() (*ServiceDetail, error) {
+	serviceDetail := &ServiceDetail{}
+	if err := json.Unmarshal([]byte(serviceVersion.Detail), serviceDetail); err != nil {
+		return nil, err
+	}
+	if serviceDetail.Info == nil || serviceDetail.HTTPRule == nil || serviceDetail.TCPRule == nil ||
+		serviceDetail.GRPCRule == nil || serviceDetail.LoadBalance == nil || serviceDetail.AccessControl == nil {
+		return nil, fmt.Errorf("service version %d snapshot is incomplete", serviceVersion.Version)
+	}
+	return serviceDetail, nil
+}
+
+// 为服务保存一份新的版本快照：在保存服务的事务内调用 快照内容为事务内查询到的最新服务详情
+// 同时递增服务配置修订号 代理节点在下次版本检查时同步
+func SaveServiceVersion(c *gin.Context, tx *gorm.DB, serviceInfo *ServiceInfo, author, remark string) (*ServiceVersion, error) {
+	if err := BumpServiceRevision(c, tx); err != nil {
+		return nil, err
+	}
+	serviceDetail, err := serviceInfo.ServiceDetail(c, tx)
+	if err != nil {
+		return nil, err
+	}
+	detail, err := json.Marshal(serviceDetail)
+	if err != nil {
+		return nil, err
+	}
+
+	//同一服务内版本号递增
+	lastVersion := struct {
+		Version int64
+	}{}
+	if err := tx.WithContext(c).Table((&ServiceVersion{}).TableName()).Where("service_id = ?", serviceInfo.ID).
+		Select("coalesce(max(version), 0) as version").Scan(&lastVersion).Error; err != nil {
+		return nil, err
+	}
+
+	serviceVersion := &ServiceVersion{
+		ServiceID: serviceInfo.ID,
+		Version:   int(lastVersion.Version) + 1,
+		Detail:    string(detail),
+		Author:    author,
+		Remark:    remark,
+		CreatedAt: time.Now(),
+	}
+	if err := serviceVersion.Save(c, tx); err != nil {
+		return nil, err
+	}
+	return serviceVersion, nil
+}
+
+// 对比两个服务详情 输出发生变化的字段 字段名为json路径 如load_balance.ip_list
+func DiffServiceDetail(from, to *ServiceDetail) ([]dto.ServiceVersionDiffItem, error) {
+	fromFields, err := flattenServiceDetail(from)
+	if err != nil {
+		return nil, err
+	}
+	toFields, err := flattenServiceDetail(to)
+	if err != nil {
+		return nil, err
+	}
+
+	fields := []string{}
+	for field := range fromFields {
+		fields = append(fields, field)
+	}
+	for field := range toFields {
+		if _, ok := fromFields[field]; !ok {
+			fields = append(fields, field)
+		}
+	}
+	sort.Strings(fields)
+
+	diffList := []dto.ServiceVersionDiffItem{}
+	for _, field := range fields {
+		fromValue, toValue := fromFields[field], toFields[field]
+		if fromValue == toValue {
+			continue
+		}
+		diffList = append(diffList, dto.ServiceVersionDiffItem{Field: field, From: fromValue, To: toValue})
+	}
+	return diffList, nil
+}
+
+// 将服务详情展开为 json路径->值 的形式 忽略主键、服务id及时间等不属于配置内容的字段
+func flattenServiceDetail(serviceDetail *ServiceDetail) (map[string]string, error) {
+	bts, err := json.Marshal(serviceDetail)
+	if err != nil {
+		return nil, err
+	}
+	sections := map[string]map[string]interface{}{}
+	if err := json.Unmarshal(bts, &sections); err != nil {
+		return nil, err
+	}
+	ignoreFields := map[string]bool{"id": true, "service_id": true, "create_at": true, "update_at": true, "is_delete": true}
+	fields := map[string]string{}
+	for section, values := range sections {
+		for field, value := range values {
+			if ignoreFields[field] {
+				continue
+			}
+			fields[section+"."+field] = fmt.Sprint(value)
+		}
+	}
+	return fields, nil
+}
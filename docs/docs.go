@@ -465,6 +465,89 @@ const docTemplate = `{
                 }
             }
         },
+        "/config/config_export": {
+            "get": {
+                "description": "导出全部服务及租户为配置包",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "配置管理"
+                ],
+                "summary": "配置导出",
+                "operationId": "/config/config_export",
+                "responses": {
+                    "200": {
+                        "description": "success",
+                        "schema": {
+                            "allOf": [
+                                {
+                                    "$ref": "#/definitions/middleware.Response"
+                                },
+                                {
+                                    "type": "object",
+                                    "properties": {
+                                        "data": {
+                                            "$ref": "#/definitions/dao.ConfigBundle"
+                                        }
+                                    }
+                                }
+                            ]
+                        }
+                    }
+                }
+            }
+        },
+        "/config/config_import": {
+            "post": {
+                "description": "导入配置包 按service_name/app_id新增或更新 试运行时只输出变更不保存",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "配置管理"
+                ],
+                "summary": "配置导入",
+                "operationId": "/config/config_import",
+                "parameters": [
+                    {
+                        "description": "body",
+                        "name": "body",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/dto.ConfigImportInput"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "success",
+                        "schema": {
+                            "allOf": [
+                                {
+                                    "$ref": "#/definitions/middleware.Response"
+                                },
+                                {
+                                    "type": "object",
+                                    "properties": {
+                                        "data": {
+                                            "$ref": "#/definitions/dto.ConfigImportOutput"
+                                        }
+                                    }
+                                }
+                            ]
+                        }
+                    }
+                }
+            }
+        },
         "/dashboard/flow_stat": {
             "get": {
                 "description": "流量统计",
@@ -761,9 +844,9 @@ const docTemplate = `{
                 }
             }
         },
-        "/service/service_delete": {
-            "delete": {
-                "description": "服务信息删除",
+        "/service/service_balance_score": {
+            "get": {
+                "description": "服务负载均衡节点评分查询 仅p2c轮询方式有节点评分",
                 "consumes": [
                     "application/json"
                 ],
@@ -773,12 +856,12 @@ const docTemplate = `{
                 "tags": [
                     "服务管理"
                 ],
-                "summary": "服务信息删除",
-                "operationId": "/service/service_delete",
+                "summary": "服务负载均衡节点评分查询",
+                "operationId": "/service/service_balance_score",
                 "parameters": [
                     {
                         "type": "integer",
-                        "example": 56,
+                        "example": 63,
                         "description": "服务id",
                         "name": "id",
                         "in": "query",
@@ -797,7 +880,7 @@ const docTemplate = `{
                                     "type": "object",
                                     "properties": {
                                         "data": {
-                                            "type": "string"
+                                            "$ref": "#/definitions/dto.ServiceBalanceScoreOutput"
                                         }
                                     }
                                 }
@@ -807,9 +890,9 @@ const docTemplate = `{
                 }
             }
         },
-        "/service/service_detail": {
+        "/service/service_circuit": {
             "get": {
-                "description": "服务详情查询",
+                "description": "服务熔断配置及状态查询",
                 "consumes": [
                     "application/json"
                 ],
@@ -819,8 +902,8 @@ const docTemplate = `{
                 "tags": [
                     "服务管理"
                 ],
-                "summary": "服务详情查询",
-                "operationId": "/service/service_detail",
+                "summary": "服务熔断配置及状态查询",
+                "operationId": "/service/service_circuit",
                 "parameters": [
                     {
                         "type": "integer",
@@ -843,7 +926,7 @@ const docTemplate = `{
                                     "type": "object",
                                     "properties": {
                                         "data": {
-                                            "$ref": "#/definitions/dao.ServiceDetail"
+                                            "$ref": "#/definitions/dto.ServiceCircuitOutput"
                                         }
                                     }
                                 }
@@ -853,9 +936,9 @@ const docTemplate = `{
                 }
             }
         },
-        "/service/service_list": {
-            "get": {
-                "description": "服务信息列表查询",
+        "/service/service_circuit_state": {
+            "post": {
+                "description": "手动熔断或恢复服务及其所有上游节点 手动熔断不随熔断持续时间恢复 代理节点重启后保持 直到手动恢复",
                 "consumes": [
                     "application/json"
                 ],
@@ -865,26 +948,108 @@ const docTemplate = `{
                 "tags": [
                     "服务管理"
                 ],
-                "summary": "服务信息列表查询",
-                "operationId": "/service/service_list",
+                "summary": "服务熔断状态修改",
+                "operationId": "/service/service_circuit_state",
                 "parameters": [
                     {
-                        "type": "string",
-                        "description": "关键词",
-                        "name": "info",
-                        "in": "query"
-                    },
+                        "description": "body",
+                        "name": "body",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/dto.ServiceCircuitStateInput"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "success",
+                        "schema": {
+                            "allOf": [
+                                {
+                                    "$ref": "#/definitions/middleware.Response"
+                                },
+                                {
+                                    "type": "object",
+                                    "properties": {
+                                        "data": {
+                                            "type": "string"
+                                        }
+                                    }
+                                }
+                            ]
+                        }
+                    }
+                }
+            }
+        },
+        "/service/service_circuit_update": {
+            "post": {
+                "description": "服务熔断配置修改",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "服务管理"
+                ],
+                "summary": "服务熔断配置修改",
+                "operationId": "/service/service_circuit_update",
+                "parameters": [
                     {
-                        "type": "integer",
-                        "description": "页码",
-                        "name": "page_num",
-                        "in": "query",
-                        "required": true
-                    },
+                        "description": "body",
+                        "name": "body",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/dto.ServiceCircuitUpdateInput"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "success",
+                        "schema": {
+                            "allOf": [
+                                {
+                                    "$ref": "#/definitions/middleware.Response"
+                                },
+                                {
+                                    "type": "object",
+                                    "properties": {
+                                        "data": {
+                                            "type": "string"
+                                        }
+                                    }
+                                }
+                            ]
+                        }
+                    }
+                }
+            }
+        },
+        "/service/service_delete": {
+            "delete": {
+                "description": "服务信息删除",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "服务管理"
+                ],
+                "summary": "服务信息删除",
+                "operationId": "/service/service_delete",
+                "parameters": [
                     {
                         "type": "integer",
-                        "description": "条数",
-                        "name": "page_size",
+                        "example": 56,
+                        "description": "服务id",
+                        "name": "id",
                         "in": "query",
                         "required": true
                     }
@@ -901,7 +1066,7 @@ const docTemplate = `{
                                     "type": "object",
                                     "properties": {
                                         "data": {
-                                            "$ref": "#/definitions/dto.ServiceListOutput"
+                                            "type": "string"
                                         }
                                     }
                                 }
@@ -911,9 +1076,9 @@ const docTemplate = `{
                 }
             }
         },
-        "/service/service_stat": {
+        "/service/service_detail": {
             "get": {
-                "description": "服务统计信息查询",
+                "description": "服务详情查询",
                 "consumes": [
                     "application/json"
                 ],
@@ -923,8 +1088,8 @@ const docTemplate = `{
                 "tags": [
                     "服务管理"
                 ],
-                "summary": "服务统计信息查询",
-                "operationId": "/service/service_stat",
+                "summary": "服务详情查询",
+                "operationId": "/service/service_detail",
                 "parameters": [
                     {
                         "type": "integer",
@@ -947,7 +1112,7 @@ const docTemplate = `{
                                     "type": "object",
                                     "properties": {
                                         "data": {
-                                            "$ref": "#/definitions/dto.ServiceStatOutput"
+                                            "$ref": "#/definitions/dao.ServiceDetail"
                                         }
                                     }
                                 }
@@ -957,9 +1122,9 @@ const docTemplate = `{
                 }
             }
         },
-        "/service/service_update_grpc": {
-            "put": {
-                "description": "GRPC服务更新",
+        "/service/service_group_weight": {
+            "post": {
+                "description": "上游分组流量比例调整",
                 "consumes": [
                     "application/json"
                 ],
@@ -969,8 +1134,8 @@ const docTemplate = `{
                 "tags": [
                     "服务管理"
                 ],
-                "summary": "GRPC服务更新",
-                "operationId": "/service/service_update_grpc",
+                "summary": "上游分组流量比例调整",
+                "operationId": "/service/service_group_weight",
                 "parameters": [
                     {
                         "description": "body",
@@ -978,7 +1143,7 @@ const docTemplate = `{
                         "in": "body",
                         "required": true,
                         "schema": {
-                            "$ref": "#/definitions/dto.ServiceUpdateGRPCInput"
+                            "$ref": "#/definitions/dto.ServiceGroupWeightInput"
                         }
                     }
                 ],
@@ -1004,9 +1169,9 @@ const docTemplate = `{
                 }
             }
         },
-        "/service/service_update_http": {
-            "put": {
-                "description": "HTTP服务更新",
+        "/service/service_health": {
+            "get": {
+                "description": "服务上游节点健康状态查询 状态由代理节点定时上报",
                 "consumes": [
                     "application/json"
                 ],
@@ -1016,17 +1181,16 @@ const docTemplate = `{
                 "tags": [
                     "服务管理"
                 ],
-                "summary": "HTTP服务更新",
-                "operationId": "/service/service_update_http",
+                "summary": "服务上游节点健康状态查询",
+                "operationId": "/service/service_health",
                 "parameters": [
                     {
-                        "description": "body",
-                        "name": "body",
-                        "in": "body",
-                        "required": true,
-                        "schema": {
-                            "$ref": "#/definitions/dto.ServiceUpdateHTTPInput"
-                        }
+                        "type": "integer",
+                        "example": 63,
+                        "description": "服务id",
+                        "name": "id",
+                        "in": "query",
+                        "required": true
                     }
                 ],
                 "responses": {
@@ -1041,7 +1205,7 @@ const docTemplate = `{
                                     "type": "object",
                                     "properties": {
                                         "data": {
-                                            "type": "string"
+                                            "$ref": "#/definitions/dto.ServiceHealthOutput"
                                         }
                                     }
                                 }
@@ -1051,9 +1215,9 @@ const docTemplate = `{
                 }
             }
         },
-        "/service/service_update_tcp": {
-            "put": {
-                "description": "TCP服务更新",
+        "/service/service_health_check": {
+            "get": {
+                "description": "服务主动健康检查配置查询",
                 "consumes": [
                     "application/json"
                 ],
@@ -1063,17 +1227,16 @@ const docTemplate = `{
                 "tags": [
                     "服务管理"
                 ],
-                "summary": "TCP服务更新",
-                "operationId": "/service/service_update_tcp",
+                "summary": "服务主动健康检查配置查询",
+                "operationId": "/service/service_health_check",
                 "parameters": [
                     {
-                        "description": "body",
-                        "name": "body",
-                        "in": "body",
-                        "required": true,
-                        "schema": {
-                            "$ref": "#/definitions/dto.ServiceUpdateTCPInput"
-                        }
+                        "type": "integer",
+                        "example": 63,
+                        "description": "服务id",
+                        "name": "id",
+                        "in": "query",
+                        "required": true
                     }
                 ],
                 "responses": {
@@ -1088,7 +1251,7 @@ const docTemplate = `{
                                     "type": "object",
                                     "properties": {
                                         "data": {
-                                            "type": "string"
+                                            "$ref": "#/definitions/dto.ServiceHealthCheckOutput"
                                         }
                                     }
                                 }
@@ -1097,11 +1260,986 @@ const docTemplate = `{
                     }
                 }
             }
-        }
-    },
-    "definitions": {
-        "dao.APP": {
-            "type": "object",
+        },
+        "/service/service_health_check_update": {
+            "post": {
+                "description": "服务主动健康检查配置修改",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "服务管理"
+                ],
+                "summary": "服务主动健康检查配置修改",
+                "operationId": "/service/service_health_check_update",
+                "parameters": [
+                    {
+                        "description": "body",
+                        "name": "body",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/dto.ServiceHealthCheckUpdateInput"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "success",
+                        "schema": {
+                            "allOf": [
+                                {
+                                    "$ref": "#/definitions/middleware.Response"
+                                },
+                                {
+                                    "type": "object",
+                                    "properties": {
+                                        "data": {
+                                            "type": "string"
+                                        }
+                                    }
+                                }
+                            ]
+                        }
+                    }
+                }
+            }
+        },
+        "/service/service_list": {
+            "get": {
+                "description": "服务信息列表查询",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "服务管理"
+                ],
+                "summary": "服务信息列表查询",
+                "operationId": "/service/service_list",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "关键词",
+                        "name": "info",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "页码",
+                        "name": "page_num",
+                        "in": "query",
+                        "required": true
+                    },
+                    {
+                        "type": "integer",
+                        "description": "条数",
+                        "name": "page_size",
+                        "in": "query",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "success",
+                        "schema": {
+                            "allOf": [
+                                {
+                                    "$ref": "#/definitions/middleware.Response"
+                                },
+                                {
+                                    "type": "object",
+                                    "properties": {
+                                        "data": {
+                                            "$ref": "#/definitions/dto.ServiceListOutput"
+                                        }
+                                    }
+                                }
+                            ]
+                        }
+                    }
+                }
+            }
+        },
+        "/service/service_node_drain": {
+            "post": {
+                "description": "手动摘除上游节点 节点不再分配新请求 进行中的请求及tcp连接不受影响 节点记录在禁用ip列表中",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "服务管理"
+                ],
+                "summary": "服务节点摘除",
+                "operationId": "/service/service_node_drain",
+                "parameters": [
+                    {
+                        "description": "body",
+                        "name": "body",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/dto.ServiceNodeInput"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "success",
+                        "schema": {
+                            "allOf": [
+                                {
+                                    "$ref": "#/definitions/middleware.Response"
+                                },
+                                {
+                                    "type": "object",
+                                    "properties": {
+                                        "data": {
+                                            "type": "string"
+                                        }
+                                    }
+                                }
+                            ]
+                        }
+                    }
+                }
+            }
+        },
+        "/service/service_node_enable": {
+            "post": {
+                "description": "恢复手动摘除的上游节点 节点从禁用ip列表中移除",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "服务管理"
+                ],
+                "summary": "服务节点恢复",
+                "operationId": "/service/service_node_enable",
+                "parameters": [
+                    {
+                        "description": "body",
+                        "name": "body",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/dto.ServiceNodeInput"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "success",
+                        "schema": {
+                            "allOf": [
+                                {
+                                    "$ref": "#/definitions/middleware.Response"
+                                },
+                                {
+                                    "type": "object",
+                                    "properties": {
+                                        "data": {
+                                            "type": "string"
+                                        }
+                                    }
+                                }
+                            ]
+                        }
+                    }
+                }
+            }
+        },
+        "/service/service_route_conflict": {
+            "get": {
+                "description": "查询与该服务接入规则重叠的其他http服务 并说明请求实际会路由到哪个服务 可在保存、回滚或导入后调用",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "服务管理"
+                ],
+                "summary": "http服务路由冲突查询",
+                "operationId": "/service/service_route_conflict",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "example": 56,
+                        "description": "服务id",
+                        "name": "id",
+                        "in": "query",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "success",
+                        "schema": {
+                            "allOf": [
+                                {
+                                    "$ref": "#/definitions/middleware.Response"
+                                },
+                                {
+                                    "type": "object",
+                                    "properties": {
+                                        "data": {
+                                            "$ref": "#/definitions/dto.ServiceRouteConflictOutput"
+                                        }
+                                    }
+                                }
+                            ]
+                        }
+                    }
+                }
+            }
+        },
+        "/service/service_stat": {
+            "get": {
+                "description": "服务统计信息查询",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "服务管理"
+                ],
+                "summary": "服务统计信息查询",
+                "operationId": "/service/service_stat",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "example": 63,
+                        "description": "服务id",
+                        "name": "id",
+                        "in": "query",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "success",
+                        "schema": {
+                            "allOf": [
+                                {
+                                    "$ref": "#/definitions/middleware.Response"
+                                },
+                                {
+                                    "type": "object",
+                                    "properties": {
+                                        "data": {
+                                            "$ref": "#/definitions/dto.ServiceStatOutput"
+                                        }
+                                    }
+                                }
+                            ]
+                        }
+                    }
+                }
+            }
+        },
+        "/service/service_update_grpc": {
+            "put": {
+                "description": "GRPC服务更新",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "服务管理"
+                ],
+                "summary": "GRPC服务更新",
+                "operationId": "/service/service_update_grpc",
+                "parameters": [
+                    {
+                        "description": "body",
+                        "name": "body",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/dto.ServiceUpdateGRPCInput"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "success",
+                        "schema": {
+                            "allOf": [
+                                {
+                                    "$ref": "#/definitions/middleware.Response"
+                                },
+                                {
+                                    "type": "object",
+                                    "properties": {
+                                        "data": {
+                                            "type": "string"
+                                        }
+                                    }
+                                }
+                            ]
+                        }
+                    }
+                }
+            }
+        },
+        "/service/service_update_http": {
+            "put": {
+                "description": "HTTP服务更新",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "服务管理"
+                ],
+                "summary": "HTTP服务更新",
+                "operationId": "/service/service_update_http",
+                "parameters": [
+                    {
+                        "description": "body",
+                        "name": "body",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/dto.ServiceUpdateHTTPInput"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "success",
+                        "schema": {
+                            "allOf": [
+                                {
+                                    "$ref": "#/definitions/middleware.Response"
+                                },
+                                {
+                                    "type": "object",
+                                    "properties": {
+                                        "data": {
+                                            "type": "string"
+                                        }
+                                    }
+                                }
+                            ]
+                        }
+                    }
+                }
+            }
+        },
+        "/service/service_update_tcp": {
+            "put": {
+                "description": "TCP服务更新",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "服务管理"
+                ],
+                "summary": "TCP服务更新",
+                "operationId": "/service/service_update_tcp",
+                "parameters": [
+                    {
+                        "description": "body",
+                        "name": "body",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/dto.ServiceUpdateTCPInput"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "success",
+                        "schema": {
+                            "allOf": [
+                                {
+                                    "$ref": "#/definitions/middleware.Response"
+                                },
+                                {
+                                    "type": "object",
+                                    "properties": {
+                                        "data": {
+                                            "type": "string"
+                                        }
+                                    }
+                                }
+                            ]
+                        }
+                    }
+                }
+            }
+        },
+        "/service/service_version_diff": {
+            "get": {
+                "description": "服务配置版本对比",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "服务管理"
+                ],
+                "summary": "服务配置版本对比",
+                "operationId": "/service/service_version_diff",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "服务id",
+                        "name": "id",
+                        "in": "query",
+                        "required": true
+                    },
+                    {
+                        "type": "integer",
+                        "description": "对比的起始版本",
+                        "name": "from_version",
+                        "in": "query",
+                        "required": true
+                    },
+                    {
+                        "type": "integer",
+                        "description": "对比的目标版本",
+                        "name": "to_version",
+                        "in": "query",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "success",
+                        "schema": {
+                            "allOf": [
+                                {
+                                    "$ref": "#/definitions/middleware.Response"
+                                },
+                                {
+                                    "type": "object",
+                                    "properties": {
+                                        "data": {
+                                            "$ref": "#/definitions/dto.ServiceVersionDiffOutput"
+                                        }
+                                    }
+                                }
+                            ]
+                        }
+                    }
+                }
+            }
+        },
+        "/service/service_version_list": {
+            "get": {
+                "description": "服务配置版本列表查询",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "服务管理"
+                ],
+                "summary": "服务配置版本列表查询",
+                "operationId": "/service/service_version_list",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "服务id",
+                        "name": "id",
+                        "in": "query",
+                        "required": true
+                    },
+                    {
+                        "type": "integer",
+                        "description": "页码",
+                        "name": "page_num",
+                        "in": "query",
+                        "required": true
+                    },
+                    {
+                        "type": "integer",
+                        "description": "条数",
+                        "name": "page_size",
+                        "in": "query",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "success",
+                        "schema": {
+                            "allOf": [
+                                {
+                                    "$ref": "#/definitions/middleware.Response"
+                                },
+                                {
+                                    "type": "object",
+                                    "properties": {
+                                        "data": {
+                                            "$ref": "#/definitions/dto.ServiceVersionListOutput"
+                                        }
+                                    }
+                                }
+                            ]
+                        }
+                    }
+                }
+            }
+        },
+        "/service/service_version_rollback": {
+            "post": {
+                "description": "服务配置版本回滚 回滚后生成一个新的版本",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "服务管理"
+                ],
+                "summary": "服务配置版本回滚",
+                "operationId": "/service/service_version_rollback",
+                "parameters": [
+                    {
+                        "description": "body",
+                        "name": "body",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/dto.ServiceVersionRollbackInput"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "success",
+                        "schema": {
+                            "allOf": [
+                                {
+                                    "$ref": "#/definitions/middleware.Response"
+                                },
+                                {
+                                    "type": "object",
+                                    "properties": {
+                                        "data": {
+                                            "type": "integer"
+                                        }
+                                    }
+                                }
+                            ]
+                        }
+                    }
+                }
+            }
+        }
+    },
+    "definitions": {
+        "dao.APP": {
+            "type": "object",
+            "properties": {
+                "app_id": {
+                    "type": "string"
+                },
+                "create_at": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "integer"
+                },
+                "is_delete": {
+                    "type": "integer"
+                },
+                "name": {
+                    "type": "string"
+                },
+                "qpd": {
+                    "type": "integer"
+                },
+                "qps": {
+                    "type": "integer"
+                },
+                "secret": {
+                    "type": "string"
+                },
+                "update_at": {
+                    "type": "string"
+                },
+                "white_ips": {
+                    "type": "string"
+                }
+            }
+        },
+        "dao.AccessControl": {
+            "type": "object",
+            "properties": {
+                "black_list": {
+                    "type": "string"
+                },
+                "clientip_flow_limit": {
+                    "type": "integer"
+                },
+                "id": {
+                    "type": "integer"
+                },
+                "open_auth": {
+                    "type": "integer"
+                },
+                "service_flow_limit": {
+                    "type": "integer"
+                },
+                "service_id": {
+                    "type": "integer"
+                },
+                "white_host_name": {
+                    "type": "string"
+                },
+                "white_list": {
+                    "type": "string"
+                }
+            }
+        },
+        "dao.ConfigBundle": {
+            "type": "object",
+            "properties": {
+                "app": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/dao.APP"
+                    }
+                },
+                "export_at": {
+                    "type": "string"
+                },
+                "service": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/dao.ServiceDetail"
+                    }
+                },
+                "version": {
+                    "type": "integer"
+                }
+            }
+        },
+        "dao.GrpcRule": {
+            "type": "object",
+            "properties": {
+                "header_transfer": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "integer"
+                },
+                "port": {
+                    "type": "integer"
+                },
+                "service_id": {
+                    "type": "integer"
+                }
+            }
+        },
+        "dao.HttpRule": {
+            "type": "object",
+            "properties": {
+                "canary_rule": {
+                    "type": "string"
+                },
+                "header_transfer": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "integer"
+                },
+                "methods": {
+                    "type": "string"
+                },
+                "mirror_body_limit": {
+                    "type": "integer"
+                },
+                "mirror_percent": {
+                    "type": "integer"
+                },
+                "mirror_service": {
+                    "type": "string"
+                },
+                "mirror_timeout": {
+                    "type": "integer"
+                },
+                "need_https": {
+                    "type": "integer"
+                },
+                "need_strip_uri": {
+                    "type": "integer"
+                },
+                "need_websocket": {
+                    "type": "integer"
+                },
+                "priority": {
+                    "type": "integer"
+                },
+                "rule": {
+                    "type": "string"
+                },
+                "rule_type": {
+                    "type": "integer"
+                },
+                "service_id": {
+                    "type": "integer"
+                },
+                "url_rewrite": {
+                    "type": "string"
+                }
+            }
+        },
+        "dao.LoadBalance": {
+            "type": "object",
+            "properties": {
+                "check_body": {
+                    "type": "string"
+                },
+                "check_healthy": {
+                    "type": "integer"
+                },
+                "check_host": {
+                    "type": "string"
+                },
+                "check_interval": {
+                    "type": "integer"
+                },
+                "check_method": {
+                    "type": "integer"
+                },
+                "check_path": {
+                    "type": "string"
+                },
+                "check_service": {
+                    "type": "string"
+                },
+                "check_status": {
+                    "type": "string"
+                },
+                "check_timeout": {
+                    "type": "integer"
+                },
+                "check_unhealthy": {
+                    "type": "integer"
+                },
+                "circuit_error_percent": {
+                    "type": "integer"
+                },
+                "circuit_failures": {
+                    "type": "integer"
+                },
+                "circuit_fallback": {
+                    "type": "string"
+                },
+                "circuit_half_open": {
+                    "type": "integer"
+                },
+                "circuit_min_requests": {
+                    "type": "integer"
+                },
+                "circuit_open_timeout": {
+                    "type": "integer"
+                },
+                "circuit_window": {
+                    "type": "integer"
+                },
+                "forbid_list": {
+                    "type": "string"
+                },
+                "group_list": {
+                    "type": "string"
+                },
+                "group_sticky": {
+                    "type": "integer"
+                },
+                "group_weight": {
+                    "type": "string"
+                },
+                "hash_key": {
+                    "type": "string"
+                },
+                "hash_load_factor": {
+                    "type": "integer"
+                },
+                "hash_replicas": {
+                    "type": "integer"
+                },
+                "id": {
+                    "type": "integer"
+                },
+                "ip_list": {
+                    "type": "string"
+                },
+                "outlier_eject_time": {
+                    "type": "integer"
+                },
+                "outlier_failures": {
+                    "type": "integer"
+                },
+                "outlier_max_percent": {
+                    "type": "integer"
+                },
+                "retry_backoff": {
+                    "type": "integer"
+                },
+                "retry_budget": {
+                    "type": "integer"
+                },
+                "retry_count": {
+                    "type": "integer"
+                },
+                "round_type": {
+                    "type": "integer"
+                },
+                "service_id": {
+                    "type": "integer"
+                },
+                "slow_start": {
+                    "type": "integer"
+                },
+                "sticky_cookie": {
+                    "type": "string"
+                },
+                "sticky_max_age": {
+                    "type": "integer"
+                },
+                "upstream_connect_timeout": {
+                    "type": "integer"
+                },
+                "upstream_header_timeout": {
+                    "type": "integer"
+                },
+                "upstream_idle_timeout": {
+                    "type": "integer"
+                },
+                "upstream_max_idle": {
+                    "type": "integer"
+                },
+                "weight_list": {
+                    "type": "string"
+                }
+            }
+        },
+        "dao.ServiceDetail": {
+            "type": "object",
+            "properties": {
+                "access_control": {
+                    "$ref": "#/definitions/dao.AccessControl"
+                },
+                "grpc_rule": {
+                    "$ref": "#/definitions/dao.GrpcRule"
+                },
+                "http_rule": {
+                    "$ref": "#/definitions/dao.HttpRule"
+                },
+                "info": {
+                    "$ref": "#/definitions/dao.ServiceInfo"
+                },
+                "load_balance": {
+                    "$ref": "#/definitions/dao.LoadBalance"
+                },
+                "tcp_rule": {
+                    "$ref": "#/definitions/dao.TcpRule"
+                }
+            }
+        },
+        "dao.ServiceInfo": {
+            "type": "object",
+            "properties": {
+                "create_at": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "integer"
+                },
+                "is_delete": {
+                    "type": "integer"
+                },
+                "load_type": {
+                    "type": "integer"
+                },
+                "service_desc": {
+                    "type": "string"
+                },
+                "service_name": {
+                    "type": "string"
+                },
+                "update_at": {
+                    "type": "string"
+                }
+            }
+        },
+        "dao.TcpRule": {
+            "type": "object",
+            "properties": {
+                "id": {
+                    "type": "integer"
+                },
+                "port": {
+                    "type": "integer"
+                },
+                "service_id": {
+                    "type": "integer"
+                }
+            }
+        },
+        "dto.APPAddInput": {
+            "type": "object",
+            "required": [
+                "app_id",
+                "name"
+            ],
+            "properties": {
+                "app_id": {
+                    "type": "string"
+                },
+                "name": {
+                    "type": "string"
+                },
+                "qpd": {
+                    "type": "integer"
+                },
+                "qps": {
+                    "type": "integer"
+                },
+                "secret": {
+                    "type": "string"
+                },
+                "white_ips": {
+                    "type": "string"
+                }
+            }
+        },
+        "dto.APPListItemOutput": {
+            "type": "object",
             "properties": {
                 "app_id": {
                     "type": "string"
@@ -1124,6 +2262,12 @@ const docTemplate = `{
                 "qps": {
                     "type": "integer"
                 },
+                "real_qpd": {
+                    "type": "integer"
+                },
+                "real_qps": {
+                    "type": "integer"
+                },
                 "secret": {
                     "type": "string"
                 },
@@ -1135,438 +2279,662 @@ const docTemplate = `{
                 }
             }
         },
-        "dao.AccessControl": {
+        "dto.APPListOutput": {
             "type": "object",
             "properties": {
-                "black_list": {
-                    "type": "string"
+                "list": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/dto.APPListItemOutput"
+                    }
                 },
-                "clientip_flow_limit": {
+                "total": {
                     "type": "integer"
+                }
+            }
+        },
+        "dto.APPStatisticsOutput": {
+            "type": "object",
+            "required": [
+                "today",
+                "yesterday"
+            ],
+            "properties": {
+                "today": {
+                    "type": "array",
+                    "items": {
+                        "type": "integer"
+                    }
+                },
+                "yesterday": {
+                    "type": "array",
+                    "items": {
+                        "type": "integer"
+                    }
+                }
+            }
+        },
+        "dto.APPUpdateInput": {
+            "type": "object",
+            "required": [
+                "id",
+                "name",
+                "secret"
+            ],
+            "properties": {
+                "app_id": {
+                    "type": "string"
                 },
                 "id": {
                     "type": "integer"
                 },
-                "open_auth": {
+                "name": {
+                    "type": "string"
+                },
+                "qpd": {
                     "type": "integer"
                 },
-                "service_flow_limit": {
+                "qps": {
                     "type": "integer"
                 },
-                "service_id": {
+                "secret": {
+                    "type": "string"
+                },
+                "white_ips": {
+                    "type": "string"
+                }
+            }
+        },
+        "dto.AdminChangePwdInput": {
+            "type": "object",
+            "required": [
+                "password"
+            ],
+            "properties": {
+                "password": {
+                    "type": "string",
+                    "example": "123456"
+                }
+            }
+        },
+        "dto.AdminInfoOutput": {
+            "type": "object",
+            "properties": {
+                "avatar": {
+                    "type": "string"
+                },
+                "id": {
                     "type": "integer"
                 },
-                "white_host_name": {
+                "introduction": {
                     "type": "string"
                 },
-                "white_list": {
+                "login_time": {
+                    "type": "string"
+                },
+                "roles": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "username": {
+                    "type": "string"
+                }
+            }
+        },
+        "dto.AdminLoginInput": {
+            "type": "object",
+            "required": [
+                "password",
+                "username"
+            ],
+            "properties": {
+                "password": {
+                    "type": "string",
+                    "example": "123456"
+                },
+                "username": {
+                    "type": "string",
+                    "example": "admin"
+                }
+            }
+        },
+        "dto.AdminLoginOutput": {
+            "type": "object",
+            "properties": {
+                "token": {
+                    "type": "string",
+                    "example": "token"
+                }
+            }
+        },
+        "dto.ConfigImportInput": {
+            "type": "object",
+            "required": [
+                "bundle"
+            ],
+            "properties": {
+                "bundle": {
+                    "description": "配置包 即导出接口输出的内容",
+                    "type": "array",
+                    "items": {
+                        "type": "integer"
+                    }
+                },
+                "dry_run": {
+                    "description": "是否试运行 1=只输出变更不保存",
+                    "type": "integer",
+                    "maximum": 1,
+                    "minimum": 0,
+                    "example": 1
+                },
+                "prune": {
+                    "description": "是否删除配置包中不存在的服务及租户",
+                    "type": "integer",
+                    "maximum": 1,
+                    "minimum": 0,
+                    "example": 0
+                }
+            }
+        },
+        "dto.ConfigImportOutput": {
+            "type": "object",
+            "properties": {
+                "app": {
+                    "description": "租户变更 按app_id",
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/dto.ConfigImportResult"
+                        }
+                    ]
+                },
+                "dry_run": {
+                    "description": "是否试运行",
+                    "type": "boolean"
+                },
+                "route_conflict": {
+                    "description": "新增或更新的http服务的路由冲突 按service_name",
+                    "type": "object",
+                    "additionalProperties": {
+                        "type": "array",
+                        "items": {
+                            "$ref": "#/definitions/dto.HTTPRouteConflictItem"
+                        }
+                    }
+                },
+                "service": {
+                    "description": "服务变更 按service_name",
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/dto.ConfigImportResult"
+                        }
+                    ]
+                }
+            }
+        },
+        "dto.ConfigImportResult": {
+            "type": "object",
+            "properties": {
+                "create": {
+                    "description": "新增",
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "delete": {
+                    "description": "删除",
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "unchanged": {
+                    "description": "未变化",
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "update": {
+                    "description": "更新",
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                }
+            }
+        },
+        "dto.DashServiceStatItemOutput": {
+            "type": "object",
+            "properties": {
+                "load_type": {
+                    "type": "integer"
+                },
+                "name": {
                     "type": "string"
+                },
+                "value": {
+                    "type": "integer"
+                }
+            }
+        },
+        "dto.DashServiceStatOutput": {
+            "type": "object",
+            "properties": {
+                "data": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/dto.DashServiceStatItemOutput"
+                    }
+                },
+                "legend": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
                 }
             }
         },
-        "dao.GrpcRule": {
+        "dto.HTTPRouteConflictItem": {
             "type": "object",
             "properties": {
-                "header_transfer": {
+                "message": {
+                    "description": "冲突说明",
                     "type": "string"
                 },
-                "id": {
+                "priority": {
+                    "description": "存在冲突的服务路由优先级",
                     "type": "integer"
                 },
-                "port": {
-                    "type": "integer"
+                "rule": {
+                    "description": "存在冲突的服务接入路径",
+                    "type": "string"
                 },
-                "service_id": {
+                "rule_type": {
+                    "description": "存在冲突的服务接入类型",
                     "type": "integer"
+                },
+                "service_name": {
+                    "description": "存在冲突的服务名称",
+                    "type": "string"
                 }
             }
         },
-        "dao.HttpRule": {
+        "dto.PanelGroupDataOutput": {
             "type": "object",
             "properties": {
-                "header_transfer": {
-                    "type": "string"
-                },
-                "id": {
-                    "type": "integer"
-                },
-                "need_https": {
-                    "type": "integer"
-                },
-                "need_strip_uri": {
+                "appNum": {
                     "type": "integer"
                 },
-                "need_websocket": {
+                "currentQps": {
                     "type": "integer"
                 },
-                "rule": {
-                    "type": "string"
-                },
-                "rule_type": {
+                "serviceNum": {
                     "type": "integer"
                 },
-                "service_id": {
+                "todayRequestNum": {
                     "type": "integer"
-                },
-                "url_rewrite": {
-                    "type": "string"
                 }
             }
         },
-        "dao.LoadBalance": {
+        "dto.ServiceAddGRPCInput": {
             "type": "object",
+            "required": [
+                "ip_list",
+                "port",
+                "service_desc",
+                "service_name",
+                "weight_list"
+            ],
             "properties": {
-                "check_interval": {
-                    "type": "integer"
-                },
-                "check_method": {
-                    "type": "integer"
+                "black_list": {
+                    "type": "string"
                 },
-                "check_timeout": {
+                "client_ip_flow_limit": {
                     "type": "integer"
                 },
                 "forbid_list": {
                     "type": "string"
                 },
-                "id": {
-                    "type": "integer"
-                },
-                "ip_list": {
+                "hash_key": {
                     "type": "string"
                 },
-                "round_type": {
-                    "type": "integer"
-                },
-                "service_id": {
-                    "type": "integer"
+                "hash_load_factor": {
+                    "type": "integer",
+                    "minimum": 0
                 },
-                "upstream_connect_timeout": {
-                    "type": "integer"
+                "hash_replicas": {
+                    "type": "integer",
+                    "maximum": 1000,
+                    "minimum": 0
                 },
-                "upstream_header_timeout": {
-                    "type": "integer"
+                "header_transfer": {
+                    "type": "string"
                 },
-                "upstream_idle_timeout": {
-                    "type": "integer"
+                "ip_list": {
+                    "type": "string"
                 },
-                "upstream_max_idle": {
+                "open_auth": {
                     "type": "integer"
                 },
-                "weight_list": {
-                    "type": "string"
-                }
-            }
-        },
-        "dao.ServiceDetail": {
-            "type": "object",
-            "properties": {
-                "access_control": {
-                    "$ref": "#/definitions/dao.AccessControl"
+                "outlier_eject_time": {
+                    "type": "integer",
+                    "minimum": 0
                 },
-                "grpc_rule": {
-                    "$ref": "#/definitions/dao.GrpcRule"
+                "outlier_failures": {
+                    "type": "integer",
+                    "minimum": 0
                 },
-                "http_rule": {
-                    "$ref": "#/definitions/dao.HttpRule"
+                "outlier_max_percent": {
+                    "type": "integer",
+                    "maximum": 100,
+                    "minimum": 0
                 },
-                "info": {
-                    "$ref": "#/definitions/dao.ServiceInfo"
+                "port": {
+                    "type": "integer",
+                    "maximum": 8999,
+                    "minimum": 8001
                 },
-                "load_balance": {
-                    "$ref": "#/definitions/dao.LoadBalance"
+                "round_type": {
+                    "type": "integer",
+                    "maximum": 5,
+                    "minimum": 0
                 },
-                "tcp_rule": {
-                    "$ref": "#/definitions/dao.TcpRule"
-                }
-            }
-        },
-        "dao.ServiceInfo": {
-            "type": "object",
-            "properties": {
-                "create_at": {
+                "service_desc": {
                     "type": "string"
                 },
-                "id": {
+                "service_flow_limit": {
                     "type": "integer"
                 },
-                "is_delete": {
-                    "type": "integer"
+                "service_name": {
+                    "type": "string"
                 },
-                "load_type": {
-                    "type": "integer"
+                "slow_start": {
+                    "type": "integer",
+                    "minimum": 0
                 },
-                "service_desc": {
+                "weight_list": {
                     "type": "string"
                 },
-                "service_name": {
+                "white_host_name": {
                     "type": "string"
                 },
-                "update_at": {
+                "white_list": {
                     "type": "string"
                 }
             }
         },
-        "dao.TcpRule": {
-            "type": "object",
-            "properties": {
-                "id": {
-                    "type": "integer"
-                },
-                "port": {
-                    "type": "integer"
-                },
-                "service_id": {
-                    "type": "integer"
-                }
-            }
-        },
-        "dto.APPAddInput": {
+        "dto.ServiceAddHTTPInput": {
             "type": "object",
             "required": [
-                "app_id",
-                "name"
+                "ip_list",
+                "rule",
+                "service_desc",
+                "service_name",
+                "weight_list"
             ],
             "properties": {
-                "app_id": {
-                    "type": "string"
+                "black_list": {
+                    "description": "黑名单ip",
+                    "type": "string",
+                    "example": ""
                 },
-                "name": {
-                    "type": "string"
+                "canary_rule": {
+                    "description": "灰度路由规则 多条以逗号分隔 目标为服务名称或group:本服务的上游分组",
+                    "type": "string",
+                    "example": "header X-Canary eq 1 test_service_v2"
                 },
-                "qpd": {
-                    "type": "integer"
+                "client_ip_flow_limit": {
+                    "description": "\u0008客户端ip限流",
+                    "type": "integer",
+                    "minimum": 0,
+                    "example": 0
                 },
-                "qps": {
-                    "type": "integer"
+                "group_list": {
+                    "description": "节点分组列表 与ip列表一一对应",
+                    "type": "string",
+                    "example": ""
                 },
-                "secret": {
-                    "type": "string"
+                "group_sticky": {
+                    "description": "分组选择是否按客户端保持",
+                    "type": "integer",
+                    "maximum": 1,
+                    "minimum": 0,
+                    "example": 0
                 },
-                "white_ips": {
-                    "type": "string"
-                }
-            }
-        },
-        "dto.APPListItemOutput": {
-            "type": "object",
-            "properties": {
-                "app_id": {
-                    "type": "string"
+                "group_weight": {
+                    "description": "分组流量比例 如v1:90,v2:10 为空表示不分组",
+                    "type": "string",
+                    "example": ""
                 },
-                "create_at": {
-                    "type": "string"
+                "hash_key": {
+                    "description": "一致性hash的key 如client_ip、header:X-User-Id、cookie:sid、jwt:sub、path:1 为空时使用请求url",
+                    "type": "string",
+                    "example": "header:X-User-Id"
                 },
-                "id": {
-                    "type": "integer"
+                "hash_load_factor": {
+                    "description": "节点进行中的请求数上限为平均值的(100+该值)% 0表示不限制",
+                    "type": "integer",
+                    "minimum": 0,
+                    "example": 25
                 },
-                "is_delete": {
-                    "type": "integer"
+                "hash_replicas": {
+                    "description": "一致性hash每个节点的虚拟节点数 0表示使用默认值",
+                    "type": "integer",
+                    "maximum": 1000,
+                    "minimum": 0,
+                    "example": 10
                 },
-                "name": {
-                    "type": "string"
+                "header_transfer": {
+                    "description": "header转换",
+                    "type": "string",
+                    "example": ""
                 },
-                "qpd": {
-                    "type": "integer"
+                "ip_list": {
+                    "description": "ip列表",
+                    "type": "string",
+                    "example": ""
                 },
-                "qps": {
-                    "type": "integer"
+                "methods": {
+                    "description": "允许的请求方法 多个以逗号分隔 为空表示不限制",
+                    "type": "string",
+                    "example": "GET,POST"
                 },
-                "real_qpd": {
-                    "type": "integer"
+                "mirror_body_limit": {
+                    "description": "镜像请求体上限, 单位byte",
+                    "type": "integer",
+                    "minimum": 0,
+                    "example": 0
                 },
-                "real_qps": {
-                    "type": "integer"
+                "mirror_percent": {
+                    "description": "流量镜像比例 0-100",
+                    "type": "integer",
+                    "maximum": 100,
+                    "minimum": 0,
+                    "example": 0
                 },
-                "secret": {
-                    "type": "string"
+                "mirror_service": {
+                    "description": "流量镜像的影子服务名称 为空表示不镜像",
+                    "type": "string",
+                    "example": ""
+                },
+                "mirror_timeout": {
+                    "description": "影子请求超时时间, 单位ms",
+                    "type": "integer",
+                    "minimum": 0,
+                    "example": 0
+                },
+                "need_https": {
+                    "description": "支持https",
+                    "type": "integer",
+                    "maximum": 1,
+                    "minimum": 0,
+                    "example": 0
+                },
+                "need_strip_uri": {
+                    "description": "启用strip_uri",
+                    "type": "integer",
+                    "maximum": 1,
+                    "minimum": 0,
+                    "example": 0
+                },
+                "need_websocket": {
+                    "description": "是否支持websocket",
+                    "type": "integer",
+                    "maximum": 1,
+                    "minimum": 0,
+                    "example": 0
                 },
-                "update_at": {
-                    "type": "string"
+                "open_auth": {
+                    "description": "权限控制相关字段",
+                    "type": "integer",
+                    "maximum": 1,
+                    "minimum": 0,
+                    "example": 0
                 },
-                "white_ips": {
-                    "type": "string"
-                }
-            }
-        },
-        "dto.APPListOutput": {
-            "type": "object",
-            "properties": {
-                "list": {
-                    "type": "array",
-                    "items": {
-                        "$ref": "#/definitions/dto.APPListItemOutput"
-                    }
+                "outlier_eject_time": {
+                    "description": "节点基础摘除时间, 单位s 重复摘除时翻倍",
+                    "type": "integer",
+                    "minimum": 0,
+                    "example": 30
                 },
-                "total": {
-                    "type": "integer"
-                }
-            }
-        },
-        "dto.APPStatisticsOutput": {
-            "type": "object",
-            "required": [
-                "today",
-                "yesterday"
-            ],
-            "properties": {
-                "today": {
-                    "type": "array",
-                    "items": {
-                        "type": "integer"
-                    }
+                "outlier_failures": {
+                    "description": "被动健康检查 节点连续失败达到该次数时摘除 0表示不开启",
+                    "type": "integer",
+                    "minimum": 0,
+                    "example": 0
                 },
-                "yesterday": {
-                    "type": "array",
-                    "items": {
-                        "type": "integer"
-                    }
-                }
-            }
-        },
-        "dto.APPUpdateInput": {
-            "type": "object",
-            "required": [
-                "id",
-                "name",
-                "secret"
-            ],
-            "properties": {
-                "app_id": {
-                    "type": "string"
+                "outlier_max_percent": {
+                    "description": "最多摘除节点的百分比",
+                    "type": "integer",
+                    "maximum": 100,
+                    "minimum": 0,
+                    "example": 50
                 },
-                "id": {
-                    "type": "integer"
+                "priority": {
+                    "description": "路由优先级 规则精确程度相同时数值越大越优先",
+                    "type": "integer",
+                    "minimum": 0,
+                    "example": 0
                 },
-                "name": {
-                    "type": "string"
+                "retry_backoff": {
+                    "description": "首次重试前的等待时间, 单位ms 之后每次翻倍",
+                    "type": "integer",
+                    "minimum": 0,
+                    "example": 0
                 },
-                "qpd": {
-                    "type": "integer"
+                "retry_budget": {
+                    "description": "重试请求数占请求数的百分比上限 0表示不限制",
+                    "type": "integer",
+                    "maximum": 100,
+                    "minimum": 0,
+                    "example": 20
                 },
-                "qps": {
-                    "type": "integer"
+                "retry_count": {
+                    "description": "请求失败时换节点重试的最大次数 0表示不重试",
+                    "type": "integer",
+                    "maximum": 5,
+                    "minimum": 0,
+                    "example": 0
                 },
-                "secret": {
-                    "type": "string"
+                "round_type": {
+                    "description": "负载均衡相关字段",
+                    "type": "integer",
+                    "maximum": 5,
+                    "minimum": 0,
+                    "example": 0
                 },
-                "white_ips": {
-                    "type": "string"
-                }
-            }
-        },
-        "dto.AdminChangePwdInput": {
-            "type": "object",
-            "required": [
-                "password"
-            ],
-            "properties": {
-                "password": {
+                "rule": {
+                    "description": "域名或者前缀",
                     "type": "string",
-                    "example": "123456"
-                }
-            }
-        },
-        "dto.AdminInfoOutput": {
-            "type": "object",
-            "properties": {
-                "avatar": {
-                    "type": "string"
-                },
-                "id": {
-                    "type": "integer"
+                    "example": ""
                 },
-                "introduction": {
-                    "type": "string"
+                "rule_type": {
+                    "description": "接入类型",
+                    "type": "integer",
+                    "maximum": 5,
+                    "minimum": 0,
+                    "example": 0
                 },
-                "login_time": {
-                    "type": "string"
+                "service_desc": {
+                    "description": "服务描述",
+                    "type": "string",
+                    "maxLength": 255,
+                    "minLength": 1,
+                    "example": ""
                 },
-                "roles": {
-                    "type": "array",
-                    "items": {
-                        "type": "string"
-                    }
+                "service_flow_limit": {
+                    "description": "服务端限流",
+                    "type": "integer",
+                    "minimum": 0,
+                    "example": 0
                 },
-                "username": {
-                    "type": "string"
-                }
-            }
-        },
-        "dto.AdminLoginInput": {
-            "type": "object",
-            "required": [
-                "password",
-                "username"
-            ],
-            "properties": {
-                "password": {
+                "service_name": {
+                    "description": "服务基本信息字段",
                     "type": "string",
-                    "example": "123456"
+                    "example": ""
                 },
-                "username": {
-                    "type": "string",
-                    "example": "admin"
-                }
-            }
-        },
-        "dto.AdminLoginOutput": {
-            "type": "object",
-            "properties": {
-                "token": {
+                "slow_start": {
+                    "description": "恢复或新加入的节点权重在该时间内逐步增长 0表示不开启",
+                    "type": "integer",
+                    "minimum": 0,
+                    "example": 0
+                },
+                "sticky_cookie": {
+                    "description": "会话保持cookie名称 为空表示不开启",
                     "type": "string",
-                    "example": "token"
-                }
-            }
-        },
-        "dto.DashServiceStatItemOutput": {
-            "type": "object",
-            "properties": {
-                "load_type": {
-                    "type": "integer"
+                    "example": "GW_STICKY"
                 },
-                "name": {
-                    "type": "string"
+                "sticky_max_age": {
+                    "description": "会话保持cookie有效期, 单位s 0表示浏览器关闭前有效",
+                    "type": "integer",
+                    "minimum": 0,
+                    "example": 0
                 },
-                "value": {
-                    "type": "integer"
-                }
-            }
-        },
-        "dto.DashServiceStatOutput": {
-            "type": "object",
-            "properties": {
-                "data": {
-                    "type": "array",
-                    "items": {
-                        "$ref": "#/definitions/dto.DashServiceStatItemOutput"
-                    }
+                "upstream_connect_timeout": {
+                    "description": "建立连接超时, 单位s",
+                    "type": "integer",
+                    "minimum": 0,
+                    "example": 0
                 },
-                "legend": {
-                    "type": "array",
-                    "items": {
-                        "type": "string"
-                    }
-                }
-            }
-        },
-        "dto.PanelGroupDataOutput": {
-            "type": "object",
-            "properties": {
-                "appNum": {
-                    "type": "integer"
+                "upstream_header_timeout": {
+                    "description": "获取header超时, 单位s",
+                    "type": "integer",
+                    "minimum": 0,
+                    "example": 0
                 },
-                "currentQps": {
-                    "type": "integer"
+                "upstream_idle_timeout": {
+                    "description": "链接最大空闲时间, 单位s",
+                    "type": "integer",
+                    "minimum": 0,
+                    "example": 0
                 },
-                "serviceNum": {
-                    "type": "integer"
+                "upstream_max_idle": {
+                    "description": "最大空闲链接数",
+                    "type": "integer",
+                    "minimum": 0,
+                    "example": 0
                 },
-                "todayRequestNum": {
-                    "type": "integer"
+                "url_rewrite": {
+                    "description": "url重写功能",
+                    "type": "string",
+                    "example": ""
+                },
+                "weight_list": {
+                    "description": "\u0008权重列表",
+                    "type": "string",
+                    "example": ""
+                },
+                "white_list": {
+                    "description": "白名单ip",
+                    "type": "string",
+                    "example": ""
                 }
             }
         },
-        "dto.ServiceAddGRPCInput": {
+        "dto.ServiceAddTCPInput": {
             "type": "object",
             "required": [
                 "ip_list",
@@ -1585,8 +2953,14 @@ const docTemplate = `{
                 "forbid_list": {
                     "type": "string"
                 },
-                "header_transfer": {
-                    "type": "string"
+                "hash_load_factor": {
+                    "type": "integer",
+                    "minimum": 0
+                },
+                "hash_replicas": {
+                    "type": "integer",
+                    "maximum": 1000,
+                    "minimum": 0
                 },
                 "ip_list": {
                     "type": "string"
@@ -1594,13 +2968,28 @@ const docTemplate = `{
                 "open_auth": {
                     "type": "integer"
                 },
+                "outlier_eject_time": {
+                    "type": "integer",
+                    "minimum": 0
+                },
+                "outlier_failures": {
+                    "type": "integer",
+                    "minimum": 0
+                },
+                "outlier_max_percent": {
+                    "type": "integer",
+                    "maximum": 100,
+                    "minimum": 0
+                },
                 "port": {
                     "type": "integer",
                     "maximum": 8999,
                     "minimum": 8001
                 },
                 "round_type": {
-                    "type": "integer"
+                    "type": "integer",
+                    "maximum": 5,
+                    "minimum": 0
                 },
                 "service_desc": {
                     "type": "string"
@@ -1611,6 +3000,10 @@ const docTemplate = `{
                 "service_name": {
                     "type": "string"
                 },
+                "slow_start": {
+                    "type": "integer",
+                    "minimum": 0
+                },
                 "weight_list": {
                     "type": "string"
                 },
@@ -1622,193 +3015,407 @@ const docTemplate = `{
                 }
             }
         },
-        "dto.ServiceAddHTTPInput": {
+        "dto.ServiceBalanceScoreItem": {
+            "type": "object",
+            "properties": {
+                "addr": {
+                    "description": "节点地址",
+                    "type": "string"
+                },
+                "inflight": {
+                    "description": "进行中的请求数",
+                    "type": "integer"
+                },
+                "latency": {
+                    "description": "ewma耗时, 单位ms",
+                    "type": "number"
+                },
+                "score": {
+                    "description": "评分 越小越优先",
+                    "type": "number"
+                },
+                "update_at": {
+                    "description": "评分上报时间",
+                    "type": "integer"
+                }
+            }
+        },
+        "dto.ServiceBalanceScoreOutput": {
+            "type": "object",
+            "properties": {
+                "nodes": {
+                    "description": "节点评分",
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/dto.ServiceBalanceScoreItem"
+                    }
+                },
+                "round_type": {
+                    "description": "轮询方式 只有p2c策略上报节点评分",
+                    "type": "integer"
+                }
+            }
+        },
+        "dto.ServiceCircuitOutput": {
+            "type": "object",
+            "properties": {
+                "error_percent": {
+                    "description": "熔断错误率阈值 百分比",
+                    "type": "integer"
+                },
+                "failures": {
+                    "description": "熔断连续失败次数阈值",
+                    "type": "integer"
+                },
+                "fallback": {
+                    "description": "熔断时返回的降级响应内容",
+                    "type": "string"
+                },
+                "half_open": {
+                    "description": "半开状态放行的探测请求数",
+                    "type": "integer"
+                },
+                "min_requests": {
+                    "description": "按错误率熔断的最小请求数",
+                    "type": "integer"
+                },
+                "open_timeout": {
+                    "description": "熔断持续时间, 单位s",
+                    "type": "integer"
+                },
+                "state": {
+                    "description": "服务及上游节点熔断器状态",
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/dto.ServiceCircuitStatItem"
+                    }
+                },
+                "window": {
+                    "description": "错误率统计窗口, 单位s",
+                    "type": "integer"
+                }
+            }
+        },
+        "dto.ServiceCircuitStatItem": {
+            "type": "object",
+            "properties": {
+                "consecutive_failures": {
+                    "description": "状态切换时连续失败数",
+                    "type": "integer"
+                },
+                "failures": {
+                    "description": "状态切换时窗口内失败数",
+                    "type": "integer"
+                },
+                "node": {
+                    "description": "上游节点地址 为空表示服务级熔断器",
+                    "type": "string"
+                },
+                "requests": {
+                    "description": "状态切换时窗口内请求数",
+                    "type": "integer"
+                },
+                "state": {
+                    "description": "熔断器状态 closed/open/half_open",
+                    "type": "string"
+                },
+                "update_at": {
+                    "description": "状态切换时间",
+                    "type": "integer"
+                }
+            }
+        },
+        "dto.ServiceCircuitStateInput": {
             "type": "object",
             "required": [
-                "ip_list",
-                "rule",
-                "service_desc",
-                "service_name",
-                "weight_list"
+                "id",
+                "state"
             ],
             "properties": {
-                "black_list": {
-                    "description": "黑名单ip",
-                    "type": "string",
-                    "example": ""
+                "id": {
+                    "description": "服务id",
+                    "type": "integer",
+                    "example": 63
                 },
-                "client_ip_flow_limit": {
-                    "description": "\u0008客户端ip限流",
+                "state": {
+                    "description": "熔断器状态 closed=恢复 open=手动熔断",
+                    "type": "string",
+                    "enum": [
+                        "closed",
+                        "open"
+                    ],
+                    "example": "closed"
+                }
+            }
+        },
+        "dto.ServiceCircuitUpdateInput": {
+            "type": "object",
+            "required": [
+                "id"
+            ],
+            "properties": {
+                "error_percent": {
+                    "description": "熔断错误率阈值 百分比 0表示不按错误率熔断",
                     "type": "integer",
+                    "maximum": 100,
                     "minimum": 0,
-                    "example": 0
+                    "example": 50
                 },
-                "header_transfer": {
-                    "description": "header转换",
-                    "type": "string",
-                    "example": ""
+                "failures": {
+                    "description": "熔断连续失败次数阈值 0表示不按连续失败熔断",
+                    "type": "integer",
+                    "minimum": 0,
+                    "example": 5
                 },
-                "ip_list": {
-                    "description": "ip列表",
+                "fallback": {
+                    "description": "熔断时返回的降级响应内容 为空时直接返回错误",
                     "type": "string",
+                    "maxLength": 4096,
                     "example": ""
                 },
-                "need_https": {
-                    "description": "支持https",
+                "half_open": {
+                    "description": "半开状态放行的探测请求数",
                     "type": "integer",
-                    "maximum": 1,
                     "minimum": 0,
-                    "example": 0
+                    "example": 1
                 },
-                "need_strip_uri": {
-                    "description": "启用strip_uri",
+                "id": {
+                    "description": "服务id",
                     "type": "integer",
-                    "maximum": 1,
-                    "minimum": 0,
-                    "example": 0
+                    "example": 63
                 },
-                "need_websocket": {
-                    "description": "是否支持websocket",
+                "min_requests": {
+                    "description": "按错误率熔断的最小请求数",
                     "type": "integer",
-                    "maximum": 1,
                     "minimum": 0,
-                    "example": 0
+                    "example": 20
                 },
-                "open_auth": {
-                    "description": "权限控制相关字段",
+                "open_timeout": {
+                    "description": "熔断持续时间, 单位s",
                     "type": "integer",
-                    "maximum": 1,
                     "minimum": 0,
-                    "example": 0
+                    "example": 30
                 },
-                "round_type": {
-                    "description": "负载均衡相关字段",
+                "window": {
+                    "description": "错误率统计窗口, 单位s",
                     "type": "integer",
-                    "maximum": 3,
                     "minimum": 0,
-                    "example": 0
+                    "example": 10
+                }
+            }
+        },
+        "dto.ServiceGroupStatItem": {
+            "type": "object",
+            "properties": {
+                "name": {
+                    "description": "分组名称",
+                    "type": "string"
                 },
-                "rule": {
-                    "description": "域名或者前缀",
-                    "type": "string",
-                    "example": ""
+                "percent": {
+                    "description": "流量比例",
+                    "type": "integer"
                 },
-                "rule_type": {
-                    "description": "接入类型",
+                "qps": {
+                    "description": "qps",
+                    "type": "integer"
+                },
+                "today_count": {
+                    "description": "今日请求量",
+                    "type": "integer"
+                }
+            }
+        },
+        "dto.ServiceGroupWeightInput": {
+            "type": "object",
+            "required": [
+                "id"
+            ],
+            "properties": {
+                "group_sticky": {
+                    "description": "分组选择是否按客户端保持",
                     "type": "integer",
                     "maximum": 1,
                     "minimum": 0,
                     "example": 0
                 },
-                "service_desc": {
-                    "description": "服务描述",
+                "group_weight": {
+                    "description": "分组流量比例 为空表示不分组",
                     "type": "string",
-                    "maxLength": 255,
-                    "minLength": 1,
-                    "example": ""
+                    "example": "v1:90,v2:10"
                 },
-                "service_flow_limit": {
-                    "description": "服务端限流",
+                "id": {
+                    "description": "服务id",
                     "type": "integer",
-                    "minimum": 0,
-                    "example": 0
+                    "example": 63
+                }
+            }
+        },
+        "dto.ServiceHealthCheckOutput": {
+            "type": "object",
+            "properties": {
+                "body": {
+                    "description": "http检查响应体需包含的内容",
+                    "type": "string"
                 },
-                "service_name": {
-                    "description": "服务基本信息字段",
+                "healthy": {
+                    "description": "不可用节点连续检查成功达到该次数时恢复",
+                    "type": "integer"
+                },
+                "host": {
+                    "description": "http检查使用的Host请求头",
+                    "type": "string"
+                },
+                "interval": {
+                    "description": "检查间隔, 单位s",
+                    "type": "integer"
+                },
+                "method": {
+                    "description": "检查方法 0=tcp 1=http 2=grpc",
+                    "type": "integer"
+                },
+                "path": {
+                    "description": "http检查路径",
+                    "type": "string"
+                },
+                "service": {
+                    "description": "grpc检查的服务名称",
+                    "type": "string"
+                },
+                "status": {
+                    "description": "http检查正常状态码范围",
+                    "type": "string"
+                },
+                "timeout": {
+                    "description": "检查超时时间, 单位s",
+                    "type": "integer"
+                },
+                "unhealthy": {
+                    "description": "可用节点连续检查失败达到该次数时摘除",
+                    "type": "integer"
+                }
+            }
+        },
+        "dto.ServiceHealthCheckUpdateInput": {
+            "type": "object",
+            "required": [
+                "id"
+            ],
+            "properties": {
+                "body": {
+                    "description": "http检查响应体需包含的内容 为空表示不检查",
                     "type": "string",
+                    "maxLength": 255,
                     "example": ""
                 },
-                "upstream_connect_timeout": {
-                    "description": "建立连接超时, 单位s",
+                "healthy": {
+                    "description": "不可用节点连续检查成功达到该次数时恢复 0表示使用默认值",
                     "type": "integer",
                     "minimum": 0,
-                    "example": 0
+                    "example": 1
                 },
-                "upstream_header_timeout": {
-                    "description": "获取header超时, 单位s",
+                "host": {
+                    "description": "http检查使用的Host请求头 为空时使用节点地址",
+                    "type": "string",
+                    "maxLength": 255,
+                    "example": ""
+                },
+                "id": {
+                    "description": "服务id",
                     "type": "integer",
-                    "minimum": 0,
-                    "example": 0
+                    "example": 63
                 },
-                "upstream_idle_timeout": {
-                    "description": "链接最大空闲时间, 单位s",
+                "interval": {
+                    "description": "检查间隔, 单位s 0表示使用默认值",
                     "type": "integer",
+                    "maximum": 3600,
                     "minimum": 0,
-                    "example": 0
+                    "example": 5
                 },
-                "upstream_max_idle": {
-                    "description": "最大空闲链接数",
+                "method": {
+                    "description": "检查方法 0=tcp 1=http 2=grpc 仅grpc服务可使用grpc检查",
                     "type": "integer",
+                    "maximum": 2,
                     "minimum": 0,
-                    "example": 0
-                },
-                "url_rewrite": {
-                    "description": "url重写功能",
-                    "type": "string",
-                    "example": ""
+                    "example": 1
                 },
-                "weight_list": {
-                    "description": "\u0008权重列表",
+                "path": {
+                    "description": "http检查路径 为空时为/",
                     "type": "string",
-                    "example": ""
+                    "maxLength": 255,
+                    "example": "/health"
                 },
-                "white_list": {
-                    "description": "白名单ip",
+                "service": {
+                    "description": "grpc检查的服务名称 为空表示检查节点整体状态",
                     "type": "string",
-                    "example": ""
-                }
-            }
-        },
-        "dto.ServiceAddTCPInput": {
-            "type": "object",
-            "required": [
-                "ip_list",
-                "port",
-                "service_desc",
-                "service_name",
-                "weight_list"
-            ],
-            "properties": {
-                "black_list": {
-                    "type": "string"
-                },
-                "client_ip_flow_limit": {
-                    "type": "integer"
-                },
-                "forbid_list": {
-                    "type": "string"
+                    "maxLength": 255,
+                    "example": ""
                 },
-                "ip_list": {
-                    "type": "string"
+                "status": {
+                    "description": "http检查正常状态码范围 如200-399 为空时为200-399",
+                    "type": "string",
+                    "example": "200-399"
                 },
-                "open_auth": {
-                    "type": "integer"
+                "timeout": {
+                    "description": "检查超时时间, 单位s 0表示使用默认值",
+                    "type": "integer",
+                    "maximum": 60,
+                    "minimum": 0,
+                    "example": 2
                 },
-                "port": {
+                "unhealthy": {
+                    "description": "可用节点连续检查失败达到该次数时摘除 0表示使用默认值",
                     "type": "integer",
-                    "maximum": 8999,
-                    "minimum": 8001
+                    "minimum": 0,
+                    "example": 2
+                }
+            }
+        },
+        "dto.ServiceHealthItem": {
+            "type": "object",
+            "properties": {
+                "addr": {
+                    "description": "节点地址",
+                    "type": "string"
                 },
-                "round_type": {
+                "consecutive_failures": {
+                    "description": "主动探活连续失败次数",
                     "type": "integer"
                 },
-                "service_desc": {
+                "last_error": {
+                    "description": "最近一次主动探活的错误",
                     "type": "string"
                 },
-                "service_flow_limit": {
+                "last_probe_time": {
+                    "description": "最近一次主动探活时间",
                     "type": "integer"
                 },
-                "service_name": {
+                "proxy": {
+                    "description": "上报该状态的代理节点 多个代理节点状态不一致时展示最严重的状态",
                     "type": "string"
                 },
-                "weight_list": {
+                "state": {
+                    "description": "节点状态 healthy=可用 unhealthy=主动探活摘除 ejected=被动健康检查摘除 drained=手动摘除 unknown=未上报",
                     "type": "string"
                 },
-                "white_host_name": {
-                    "type": "string"
+                "state_change_time": {
+                    "description": "状态最近一次变化的时间",
+                    "type": "integer"
                 },
-                "white_list": {
-                    "type": "string"
+                "update_at": {
+                    "description": "状态上报时间",
+                    "type": "integer"
+                }
+            }
+        },
+        "dto.ServiceHealthOutput": {
+            "type": "object",
+            "properties": {
+                "nodes": {
+                    "description": "节点状态",
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/dto.ServiceHealthItem"
+                    }
                 }
             }
         },
@@ -1865,9 +3472,87 @@ const docTemplate = `{
                 }
             }
         },
+        "dto.ServiceMirrorStatItem": {
+            "type": "object",
+            "properties": {
+                "error": {
+                    "description": "影子请求失败或超时",
+                    "type": "integer"
+                },
+                "match": {
+                    "description": "影子与主服务状态码一致",
+                    "type": "integer"
+                },
+                "mismatch": {
+                    "description": "影子与主服务状态码不一致",
+                    "type": "integer"
+                },
+                "skip": {
+                    "description": "请求体超过上限未镜像",
+                    "type": "integer"
+                },
+                "total": {
+                    "description": "命中镜像比例的请求量",
+                    "type": "integer"
+                }
+            }
+        },
+        "dto.ServiceNodeInput": {
+            "type": "object",
+            "required": [
+                "id",
+                "node"
+            ],
+            "properties": {
+                "id": {
+                    "description": "服务id",
+                    "type": "integer",
+                    "example": 63
+                },
+                "node": {
+                    "description": "节点地址 需在服务的ip列表中",
+                    "type": "string",
+                    "example": "127.0.0.1:2003"
+                }
+            }
+        },
+        "dto.ServiceRouteConflictOutput": {
+            "type": "object",
+            "properties": {
+                "list": {
+                    "description": "路由冲突",
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/dto.HTTPRouteConflictItem"
+                    }
+                }
+            }
+        },
         "dto.ServiceStatOutput": {
             "type": "object",
             "properties": {
+                "group": {
+                    "description": "上游分组统计",
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/dto.ServiceGroupStatItem"
+                    }
+                },
+                "mirror": {
+                    "description": "流量镜像今日统计",
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/dto.ServiceMirrorStatItem"
+                        }
+                    ]
+                },
+                "retry": {
+                    "description": "今日每小时失败重试次数",
+                    "type": "array",
+                    "items": {
+                        "type": "integer"
+                    }
+                },
                 "today": {
                     "description": "今日信息统计",
                     "type": "array",
@@ -1904,6 +3589,18 @@ const docTemplate = `{
                 "forbid_list": {
                     "type": "string"
                 },
+                "hash_key": {
+                    "type": "string"
+                },
+                "hash_load_factor": {
+                    "type": "integer",
+                    "minimum": 0
+                },
+                "hash_replicas": {
+                    "type": "integer",
+                    "maximum": 1000,
+                    "minimum": 0
+                },
                 "header_transfer": {
                     "type": "string"
                 },
@@ -1916,13 +3613,28 @@ const docTemplate = `{
                 "open_auth": {
                     "type": "integer"
                 },
+                "outlier_eject_time": {
+                    "type": "integer",
+                    "minimum": 0
+                },
+                "outlier_failures": {
+                    "type": "integer",
+                    "minimum": 0
+                },
+                "outlier_max_percent": {
+                    "type": "integer",
+                    "maximum": 100,
+                    "minimum": 0
+                },
                 "port": {
                     "type": "integer",
                     "maximum": 8999,
                     "minimum": 8001
                 },
                 "round_type": {
-                    "type": "integer"
+                    "type": "integer",
+                    "maximum": 5,
+                    "minimum": 0
                 },
                 "service_desc": {
                     "type": "string"
@@ -1933,6 +3645,10 @@ const docTemplate = `{
                 "service_name": {
                     "type": "string"
                 },
+                "slow_start": {
+                    "type": "integer",
+                    "minimum": 0
+                },
                 "weight_list": {
                     "type": "string"
                 },
@@ -1960,12 +3676,52 @@ const docTemplate = `{
                     "type": "string",
                     "example": ""
                 },
+                "canary_rule": {
+                    "description": "灰度路由规则 多条以逗号分隔 目标为服务名称或group:本服务的上游分组",
+                    "type": "string",
+                    "example": "header X-Canary eq 1 test_service_v2"
+                },
                 "clientip_flow_limit": {
                     "description": "\u0008客户端ip限流",
                     "type": "integer",
                     "minimum": 0,
                     "example": 0
                 },
+                "group_list": {
+                    "description": "节点分组列表 与ip列表一一对应",
+                    "type": "string",
+                    "example": ""
+                },
+                "group_sticky": {
+                    "description": "分组选择是否按客户端保持",
+                    "type": "integer",
+                    "maximum": 1,
+                    "minimum": 0,
+                    "example": 0
+                },
+                "group_weight": {
+                    "description": "分组流量比例 如v1:90,v2:10 为空表示不分组",
+                    "type": "string",
+                    "example": ""
+                },
+                "hash_key": {
+                    "description": "一致性hash的key 如client_ip、header:X-User-Id、cookie:sid、jwt:sub、path:1 为空时使用请求url",
+                    "type": "string",
+                    "example": "header:X-User-Id"
+                },
+                "hash_load_factor": {
+                    "description": "节点进行中的请求数上限为平均值的(100+该值)% 0表示不限制",
+                    "type": "integer",
+                    "minimum": 0,
+                    "example": 25
+                },
+                "hash_replicas": {
+                    "description": "一致性hash每个节点的虚拟节点数 0表示使用默认值",
+                    "type": "integer",
+                    "maximum": 1000,
+                    "minimum": 0,
+                    "example": 10
+                },
                 "header_transfer": {
                     "description": "header转换",
                     "type": "string",
@@ -1981,6 +3737,35 @@ const docTemplate = `{
                     "type": "string",
                     "example": "192.168.55.12:88"
                 },
+                "methods": {
+                    "description": "允许的请求方法 多个以逗号分隔 为空表示不限制",
+                    "type": "string",
+                    "example": "GET,POST"
+                },
+                "mirror_body_limit": {
+                    "description": "镜像请求体上限, 单位byte",
+                    "type": "integer",
+                    "minimum": 0,
+                    "example": 0
+                },
+                "mirror_percent": {
+                    "description": "流量镜像比例 0-100",
+                    "type": "integer",
+                    "maximum": 100,
+                    "minimum": 0,
+                    "example": 0
+                },
+                "mirror_service": {
+                    "description": "流量镜像的影子服务名称 为空表示不镜像",
+                    "type": "string",
+                    "example": ""
+                },
+                "mirror_timeout": {
+                    "description": "影子请求超时时间, 单位ms",
+                    "type": "integer",
+                    "minimum": 0,
+                    "example": 0
+                },
                 "need_https": {
                     "description": "支持https",
                     "type": "integer",
@@ -2009,10 +3794,55 @@ const docTemplate = `{
                     "minimum": 0,
                     "example": 0
                 },
+                "outlier_eject_time": {
+                    "description": "节点基础摘除时间, 单位s 重复摘除时翻倍",
+                    "type": "integer",
+                    "minimum": 0,
+                    "example": 30
+                },
+                "outlier_failures": {
+                    "description": "被动健康检查 节点连续失败达到该次数时摘除 0表示不开启",
+                    "type": "integer",
+                    "minimum": 0,
+                    "example": 0
+                },
+                "outlier_max_percent": {
+                    "description": "最多摘除节点的百分比",
+                    "type": "integer",
+                    "maximum": 100,
+                    "minimum": 0,
+                    "example": 50
+                },
+                "priority": {
+                    "description": "路由优先级 规则精确程度相同时数值越大越优先",
+                    "type": "integer",
+                    "minimum": 0,
+                    "example": 0
+                },
+                "retry_backoff": {
+                    "description": "首次重试前的等待时间, 单位ms 之后每次翻倍",
+                    "type": "integer",
+                    "minimum": 0,
+                    "example": 0
+                },
+                "retry_budget": {
+                    "description": "重试请求数占请求数的百分比上限 0表示不限制",
+                    "type": "integer",
+                    "maximum": 100,
+                    "minimum": 0,
+                    "example": 20
+                },
+                "retry_count": {
+                    "description": "请求失败时换节点重试的最大次数 0表示不重试",
+                    "type": "integer",
+                    "maximum": 5,
+                    "minimum": 0,
+                    "example": 0
+                },
                 "round_type": {
                     "description": "负载均衡相关字段",
                     "type": "integer",
-                    "maximum": 3,
+                    "maximum": 5,
                     "minimum": 0,
                     "example": 0
                 },
@@ -2024,7 +3854,7 @@ const docTemplate = `{
                 "rule_type": {
                     "description": "接入类型",
                     "type": "integer",
-                    "maximum": 1,
+                    "maximum": 5,
                     "minimum": 0,
                     "example": 0
                 },
@@ -2046,6 +3876,23 @@ const docTemplate = `{
                     "type": "string",
                     "example": "addtest"
                 },
+                "slow_start": {
+                    "description": "恢复或新加入的节点权重在该时间内逐步增长 0表示不开启",
+                    "type": "integer",
+                    "minimum": 0,
+                    "example": 0
+                },
+                "sticky_cookie": {
+                    "description": "会话保持cookie名称 为空表示不开启",
+                    "type": "string",
+                    "example": "GW_STICKY"
+                },
+                "sticky_max_age": {
+                    "description": "会话保持cookie有效期, 单位s 0表示浏览器关闭前有效",
+                    "type": "integer",
+                    "minimum": 0,
+                    "example": 0
+                },
                 "upstream_connect_timeout": {
                     "description": "建立连接超时, 单位s",
                     "type": "integer",
@@ -2107,6 +3954,15 @@ const docTemplate = `{
                 "forbid_list": {
                     "type": "string"
                 },
+                "hash_load_factor": {
+                    "type": "integer",
+                    "minimum": 0
+                },
+                "hash_replicas": {
+                    "type": "integer",
+                    "maximum": 1000,
+                    "minimum": 0
+                },
                 "id": {
                     "type": "integer"
                 },
@@ -2116,13 +3972,28 @@ const docTemplate = `{
                 "open_auth": {
                     "type": "integer"
                 },
+                "outlier_eject_time": {
+                    "type": "integer",
+                    "minimum": 0
+                },
+                "outlier_failures": {
+                    "type": "integer",
+                    "minimum": 0
+                },
+                "outlier_max_percent": {
+                    "type": "integer",
+                    "maximum": 100,
+                    "minimum": 0
+                },
                 "port": {
                     "type": "integer",
                     "maximum": 8999,
                     "minimum": 8001
                 },
                 "round_type": {
-                    "type": "integer"
+                    "type": "integer",
+                    "maximum": 5,
+                    "minimum": 0
                 },
                 "service_desc": {
                     "type": "string"
@@ -2133,6 +4004,10 @@ const docTemplate = `{
                 "service_name": {
                     "type": "string"
                 },
+                "slow_start": {
+                    "type": "integer",
+                    "minimum": 0
+                },
                 "weight_list": {
                     "type": "string"
                 },
@@ -2144,6 +4019,103 @@ const docTemplate = `{
                 }
             }
         },
+        "dto.ServiceVersionDiffItem": {
+            "type": "object",
+            "properties": {
+                "field": {
+                    "description": "字段 如load_balance.ip_list",
+                    "type": "string"
+                },
+                "from": {
+                    "description": "起始版本的值",
+                    "type": "string"
+                },
+                "to": {
+                    "description": "目标版本的值",
+                    "type": "string"
+                }
+            }
+        },
+        "dto.ServiceVersionDiffOutput": {
+            "type": "object",
+            "properties": {
+                "from_version": {
+                    "description": "起始版本",
+                    "type": "integer"
+                },
+                "list": {
+                    "description": "发生变化的字段",
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/dto.ServiceVersionDiffItem"
+                    }
+                },
+                "to_version": {
+                    "description": "目标版本",
+                    "type": "integer"
+                }
+            }
+        },
+        "dto.ServiceVersionListItemOutput": {
+            "type": "object",
+            "properties": {
+                "author": {
+                    "description": "操作人",
+                    "type": "string"
+                },
+                "create_at": {
+                    "description": "创建时间",
+                    "type": "string"
+                },
+                "id": {
+                    "description": "id",
+                    "type": "integer"
+                },
+                "remark": {
+                    "description": "备注",
+                    "type": "string"
+                },
+                "version": {
+                    "description": "版本号",
+                    "type": "integer"
+                }
+            }
+        },
+        "dto.ServiceVersionListOutput": {
+            "type": "object",
+            "properties": {
+                "list": {
+                    "description": "列表",
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/dto.ServiceVersionListItemOutput"
+                    }
+                },
+                "total": {
+                    "description": "总数",
+                    "type": "integer"
+                }
+            }
+        },
+        "dto.ServiceVersionRollbackInput": {
+            "type": "object",
+            "required": [
+                "id",
+                "version"
+            ],
+            "properties": {
+                "id": {
+                    "description": "服务id",
+                    "type": "integer",
+                    "example": 56
+                },
+                "version": {
+                    "description": "回滚的目标版本",
+                    "type": "integer",
+                    "example": 1
+                }
+            }
+        },
         "dto.TokensInput": {
             "type": "object",
             "required": [
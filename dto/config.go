@@ -0,0 +1,32 @@
+package dto
+
+import (
+	"encoding/json"
+	"github.com/gin-gonic/gin"
+	"github.com/starMoonZhao/go_gateway/public"
+)
+
+type ConfigImportInput struct {
+	DryRun int             `json:"dry_run" form:"dry_run" comment:"是否试运行" example:"1" validate:"max=1,min=0"`         //是否试运行 1=只输出变更不保存
+	Prune  int             `json:"prune" form:"prune" comment:"是否删除配置包中不存在的服务及租户" example:"0" validate:"max=1,min=0"` //是否删除配置包中不存在的服务及租户
+	Bundle json.RawMessage `json:"bundle" form:"bundle" comment:"配置包" validate:"required"`                            //配置包 即导出接口输出的内容
+}
+
+func (param *ConfigImportInput) BindValidParam(c *gin.Context) error {
+	return public.DefaultGetValidParams(c, param)
+}
+
+type ConfigImportResult struct {
+	Create    []string `json:"create" form:"create"`       //新增
+	Update    []string `json:"update" form:"update"`       //更新
+	Delete    []string `json:"delete" form:"delete"`       //删除
+	Unchanged []string `json:"unchanged" form:"unchanged"` //未变化
+}
+
+type ConfigImportOutput struct {
+	DryRun  bool               `json:"dry_run" form:"dry_run"` //是否试运行
+	Service ConfigImportResult `json:"service" form:"service"` //服务变更 按service_name
+	App     ConfigImportResult `json:"app" form:"app"`         //租户变更 按app_id
+
+	RouteConflict map[string][]HTTPRouteConflictItem `json:"route_conflict" form:"route_conflict"` //新增或更新的http服务的路由冲突 按service_name
+}
@@ -3,6 +3,7 @@ package dto
 import (
 	"github.com/gin-gonic/gin"
 	"github.com/starMoonZhao/go_gateway/public"
+	"time"
 )
 
 type ServiceListInput struct {
@@ -41,15 +42,22 @@ func (param *ServiceDeleteInput) BindValidParam(c *gin.Context) error {
 
 type ServiceAddHTTPInput struct {
 	//服务基本信息字段
-	ServiceName    string `json:"service_name" form:"service_name" comment:"服务名" example:"" validate:"required,valid_service_name"`      //服务名
-	ServiceDesc    string `json:"service_desc" form:"service_desc" comment:"服务描述" example:"" validate:"required,max=255,min=1"`          //服务描述
-	RuleType       int    `json:"rule_type" form:"rule_type" comment:"接入类型" example:"0" validate:"max=1,min=0"`                          //接入类型
-	Rule           string `json:"rule" form:"rule" comment:"接入路径：域名或者前缀" example:"" validate:"required,valid_rule"`                      //域名或者前缀
-	NeedHttps      int    `json:"need_https" form:"need_https" comment:"支持https" example:"0" validate:"max=1,min=0"`                     //支持https
-	NeedStripUri   int    `json:"need_strip_uri" form:"need_strip_uri" comment:"启用strip_uri" example:"0" validate:"max=1,min=0"`         //启用strip_uri
-	NeedWebsocket  int    `json:"need_websocket" form:"need_websocket" comment:"是否支持websocket" example:"0" validate:"max=1,min=0"`       //是否支持websocket
-	UrlRewrite     string `json:"url_rewrite" form:"url_rewrite" comment:"url重写功能" example:"" validate:"valid_url_rewrite"`              //url重写功能
-	HeaderTransfer string `json:"header_transfer" form:"header_transfer" comment:"header转换" example:"" validate:"valid_header_transfer"` //header转换
+	ServiceName     string `json:"service_name" form:"service_name" comment:"服务名" example:"" validate:"required,valid_service_name"`                            //服务名
+	ServiceDesc     string `json:"service_desc" form:"service_desc" comment:"服务描述" example:"" validate:"required,max=255,min=1"`                                //服务描述
+	RuleType        int    `json:"rule_type" form:"rule_type" comment:"接入类型" example:"0" validate:"max=5,min=0"`                                                //接入类型
+	Rule            string `json:"rule" form:"rule" comment:"接入路径：域名或者前缀" example:"" validate:"required,valid_rule"`                                            //域名或者前缀
+	NeedHttps       int    `json:"need_https" form:"need_https" comment:"支持https" example:"0" validate:"max=1,min=0"`                                           //支持https
+	NeedStripUri    int    `json:"need_strip_uri" form:"need_strip_uri" comment:"启用strip_uri" example:"0" validate:"max=1,min=0"`                               //启用strip_uri
+	NeedWebsocket   int    `json:"need_websocket" form:"need_websocket" comment:"是否支持websocket" example:"0" validate:"max=1,min=0"`                             //是否支持websocket
+	UrlRewrite      string `json:"url_rewrite" form:"url_rewrite" comment:"url重写功能" example:"" validate:"valid_url_rewrite"`                                    //url重写功能
+	HeaderTransfer  string `json:"header_transfer" form:"header_transfer" comment:"header转换" example:"" validate:"valid_header_transfer"`                       //header转换
+	Priority        int    `json:"priority" form:"priority" comment:"路由优先级" example:"0" validate:"min=0"`                                                       //路由优先级 规则精确程度相同时数值越大越优先
+	Methods         string `json:"methods" form:"methods" comment:"允许的请求方法" example:"GET,POST" validate:"valid_http_methods"`                                   //允许的请求方法 多个以逗号分隔 为空表示不限制
+	CanaryRule      string `json:"canary_rule" form:"canary_rule" comment:"灰度路由规则" example:"header X-Canary eq 1 test_service_v2" validate:"valid_canary_rule"` //灰度路由规则 多条以逗号分隔 目标为服务名称或group:本服务的上游分组
+	MirrorService   string `json:"mirror_service" form:"mirror_service" comment:"流量镜像影子服务" example:"" validate:""`                                              //流量镜像的影子服务名称 为空表示不镜像
+	MirrorPercent   int    `json:"mirror_percent" form:"mirror_percent" comment:"流量镜像比例" example:"0" validate:"max=100,min=0"`                                  //流量镜像比例 0-100
+	MirrorTimeout   int    `json:"mirror_timeout" form:"mirror_timeout" comment:"影子请求超时时间, 单位ms" example:"0" validate:"min=0"`                                  //影子请求超时时间, 单位ms
+	MirrorBodyLimit int    `json:"mirror_body_limit" form:"mirror_body_limit" comment:"镜像请求体上限, 单位byte" example:"0" validate:"min=0"`                           //镜像请求体上限, 单位byte
 
 	//权限控制相关字段
 	OpenAuth          int    `json:"open_auth" form:"open_auth" comment:"是否开启权限" example:"0" validate:"max=1,min=0"`                   //关键词
@@ -59,13 +67,28 @@ type ServiceAddHTTPInput struct {
 	ServiceFlowLimit  int    `json:"service_flow_limit" form:"service_flow_limit" comment:"服务端限流" example:"0" validate:"min=0"`        //服务端限流
 
 	//负载均衡相关字段
-	RoundType              int    `json:"round_type" form:"round_type" comment:"轮询方式" example:"0" validate:"max=3,min=0"`                                //轮询方式
+	RoundType              int    `json:"round_type" form:"round_type" comment:"轮询方式" example:"0" validate:"max=5,min=0"`                                //轮询方式
 	IpList                 string `json:"ip_list" form:"ip_list" comment:"ip列表" example:"" validate:"required,valid_ipportlist"`                         //ip列表
 	WeightList             string `json:"weight_list" form:"weight_list" comment:"权重列表" example:"" validate:"required,valid_weightlist"`                //权重列表
 	UpstreamConnectTimeout int    `json:"upstream_connect_timeout" form:"upstream_connect_timeout" comment:"建立连接超时, 单位s" example:"0" validate:"min=0"`   //建立连接超时, 单位s
 	UpstreamHeaderTimeout  int    `json:"upstream_header_timeout" form:"upstream_header_timeout" comment:"获取header超时, 单位s" example:"0" validate:"min=0"` //获取header超时, 单位s
 	UpstreamIdleTimeout    int    `json:"upstream_idle_timeout" form:"upstream_idle_timeout" comment:"链接最大空闲时间, 单位s" example:"0" validate:"min=0"`       //链接最大空闲时间, 单位s
 	UpstreamMaxIdle        int    `json:"upstream_max_idle" form:"upstream_max_idle" comment:"最大空闲链接数" example:"0" validate:"min=0"`                     //最大空闲链接数
+	GroupList              string `json:"group_list" form:"group_list" comment:"节点分组列表" example:"" validate:""`                                          //节点分组列表 与ip列表一一对应
+	GroupWeight            string `json:"group_weight" form:"group_weight" comment:"分组流量比例" example:"" validate:"valid_group_weight"`                    //分组流量比例 如v1:90,v2:10 为空表示不分组
+	GroupSticky            int    `json:"group_sticky" form:"group_sticky" comment:"分组选择按客户端保持" example:"0" validate:"max=1,min=0"`                      //分组选择是否按客户端保持
+	RetryCount             int    `json:"retry_count" form:"retry_count" comment:"失败重试次数" example:"0" validate:"max=5,min=0"`                            //请求失败时换节点重试的最大次数 0表示不重试
+	RetryBackoff           int    `json:"retry_backoff" form:"retry_backoff" comment:"重试等待时间, 单位ms" example:"0" validate:"min=0"`                        //首次重试前的等待时间, 单位ms 之后每次翻倍
+	RetryBudget            int    `json:"retry_budget" form:"retry_budget" comment:"重试预算百分比" example:"20" validate:"max=100,min=0"`                      //重试请求数占请求数的百分比上限 0表示不限制
+	OutlierFailures        int    `json:"outlier_failures" form:"outlier_failures" comment:"节点摘除连续失败次数" example:"0" validate:"min=0"`                    //被动健康检查 节点连续失败达到该次数时摘除 0表示不开启
+	OutlierEjectTime       int    `json:"outlier_eject_time" form:"outlier_eject_time" comment:"节点摘除时间, 单位s" example:"30" validate:"min=0"`              //节点基础摘除时间, 单位s 重复摘除时翻倍
+	OutlierMaxPercent      int    `json:"outlier_max_percent" form:"outlier_max_percent" comment:"最多摘除节点百分比" example:"50" validate:"max=100,min=0"`      //最多摘除节点的百分比
+	HashKey                string `json:"hash_key" form:"hash_key" comment:"一致性hash的key" example:"header:X-User-Id" validate:"valid_hash_key"`           //一致性hash的key 如client_ip、header:X-User-Id、cookie:sid、jwt:sub、path:1 为空时使用请求url
+	HashReplicas           int    `json:"hash_replicas" form:"hash_replicas" comment:"一致性hash虚拟节点数" example:"10" validate:"max=1000,min=0"`              //一致性hash每个节点的虚拟节点数 0表示使用默认值
+	HashLoadFactor         int    `json:"hash_load_factor" form:"hash_load_factor" comment:"一致性hash有界负载系数" example:"25" validate:"min=0"`                //节点进行中的请求数上限为平均值的(100+该值)% 0表示不限制
+	SlowStart              int    `json:"slow_start" form:"slow_start" comment:"慢启动时间, 单位s" example:"0" validate:"min=0"`                                //恢复或新加入的节点权重在该时间内逐步增长 0表示不开启
+	StickyCookie           string `json:"sticky_cookie" form:"sticky_cookie" comment:"会话保持cookie名称" example:"GW_STICKY" validate:"valid_cookie_name"`    //会话保持cookie名称 为空表示不开启
+	StickyMaxAge           int    `json:"sticky_max_age" form:"sticky_max_age" comment:"会话保持cookie有效期, 单位s" example:"0" validate:"min=0"`                //会话保持cookie有效期, 单位s 0表示浏览器关闭前有效
 }
 
 func (param *ServiceAddHTTPInput) BindValidParam(c *gin.Context) error {
@@ -74,16 +97,23 @@ func (param *ServiceAddHTTPInput) BindValidParam(c *gin.Context) error {
 
 type ServiceUpdateHTTPInput struct {
 	//服务基本信息字段
-	ID             int64  `json:"id" form:"id" comment:"服务id" example:"63" validate:"required"`                                            //服务id
-	ServiceName    string `json:"service_name" form:"service_name" comment:"服务名" example:"addtest" validate:"required,valid_service_name"` //服务名
-	ServiceDesc    string `json:"service_desc" form:"service_desc" comment:"服务描述" example:"服务更新测试" validate:"required,max=255,min=1"`      //服务描述
-	RuleType       int    `json:"rule_type" form:"rule_type" comment:"接入类型" example:"0" validate:"max=1,min=0"`                            //接入类型
-	Rule           string `json:"rule" form:"rule" comment:"接入路径：域名或者前缀" example:"/abe" validate:"required,valid_rule"`                    //域名或者前缀
-	NeedHttps      int    `json:"need_https" form:"need_https" comment:"支持https" example:"0" validate:"max=1,min=0"`                       //支持https
-	NeedStripUri   int    `json:"need_strip_uri" form:"need_strip_uri" comment:"启用strip_uri" example:"0" validate:"max=1,min=0"`           //启用strip_uri
-	NeedWebsocket  int    `json:"need_websocket" form:"need_websocket" comment:"是否支持websocket" example:"0" validate:"max=1,min=0"`         //是否支持websocket
-	UrlRewrite     string `json:"url_rewrite" form:"url_rewrite" comment:"url重写功能" example:"" validate:"valid_url_rewrite"`                //url重写功能
-	HeaderTransfer string `json:"header_transfer" form:"header_transfer" comment:"header转换" example:"" validate:"valid_header_transfer"`   //header转换
+	ID              int64  `json:"id" form:"id" comment:"服务id" example:"63" validate:"required"`                                                                //服务id
+	ServiceName     string `json:"service_name" form:"service_name" comment:"服务名" example:"addtest" validate:"required,valid_service_name"`                     //服务名
+	ServiceDesc     string `json:"service_desc" form:"service_desc" comment:"服务描述" example:"服务更新测试" validate:"required,max=255,min=1"`                          //服务描述
+	RuleType        int    `json:"rule_type" form:"rule_type" comment:"接入类型" example:"0" validate:"max=5,min=0"`                                                //接入类型
+	Rule            string `json:"rule" form:"rule" comment:"接入路径：域名或者前缀" example:"/abe" validate:"required,valid_rule"`                                        //域名或者前缀
+	NeedHttps       int    `json:"need_https" form:"need_https" comment:"支持https" example:"0" validate:"max=1,min=0"`                                           //支持https
+	NeedStripUri    int    `json:"need_strip_uri" form:"need_strip_uri" comment:"启用strip_uri" example:"0" validate:"max=1,min=0"`                               //启用strip_uri
+	NeedWebsocket   int    `json:"need_websocket" form:"need_websocket" comment:"是否支持websocket" example:"0" validate:"max=1,min=0"`                             //是否支持websocket
+	UrlRewrite      string `json:"url_rewrite" form:"url_rewrite" comment:"url重写功能" example:"" validate:"valid_url_rewrite"`                                    //url重写功能
+	HeaderTransfer  string `json:"header_transfer" form:"header_transfer" comment:"header转换" example:"" validate:"valid_header_transfer"`                       //header转换
+	Priority        int    `json:"priority" form:"priority" comment:"路由优先级" example:"0" validate:"min=0"`                                                       //路由优先级 规则精确程度相同时数值越大越优先
+	Methods         string `json:"methods" form:"methods" comment:"允许的请求方法" example:"GET,POST" validate:"valid_http_methods"`                                   //允许的请求方法 多个以逗号分隔 为空表示不限制
+	CanaryRule      string `json:"canary_rule" form:"canary_rule" comment:"灰度路由规则" example:"header X-Canary eq 1 test_service_v2" validate:"valid_canary_rule"` //灰度路由规则 多条以逗号分隔 目标为服务名称或group:本服务的上游分组
+	MirrorService   string `json:"mirror_service" form:"mirror_service" comment:"流量镜像影子服务" example:"" validate:""`                                              //流量镜像的影子服务名称 为空表示不镜像
+	MirrorPercent   int    `json:"mirror_percent" form:"mirror_percent" comment:"流量镜像比例" example:"0" validate:"max=100,min=0"`                                  //流量镜像比例 0-100
+	MirrorTimeout   int    `json:"mirror_timeout" form:"mirror_timeout" comment:"影子请求超时时间, 单位ms" example:"0" validate:"min=0"`                                  //影子请求超时时间, 单位ms
+	MirrorBodyLimit int    `json:"mirror_body_limit" form:"mirror_body_limit" comment:"镜像请求体上限, 单位byte" example:"0" validate:"min=0"`                           //镜像请求体上限, 单位byte
 
 	//权限控制相关字段
 	OpenAuth          int    `json:"open_auth" form:"open_auth" comment:"是否开启权限" example:"0" validate:"max=1,min=0"`                 //关键词
@@ -93,13 +123,28 @@ type ServiceUpdateHTTPInput struct {
 	ServiceFlowLimit  int    `json:"service_flow_limit" form:"service_flow_limit" comment:"服务端限流" example:"0" validate:"min=0"`      //服务端限流
 
 	//负载均衡相关字段
-	RoundType              int    `json:"round_type" form:"round_type" comment:"轮询方式" example:"0" validate:"max=3,min=0"`                                //轮询方式
+	RoundType              int    `json:"round_type" form:"round_type" comment:"轮询方式" example:"0" validate:"max=5,min=0"`                                //轮询方式
 	IpList                 string `json:"ip_list" form:"ip_list" comment:"ip列表" example:"192.168.55.12:88" validate:"required,valid_ipportlist"`         //ip列表
 	WeightList             string `json:"weight_list" form:"weight_list" comment:"权重列表" example:"1" validate:"required,valid_weightlist"`               //权重列表
 	UpstreamConnectTimeout int    `json:"upstream_connect_timeout" form:"upstream_connect_timeout" comment:"建立连接超时, 单位s" example:"0" validate:"min=0"`   //建立连接超时, 单位s
 	UpstreamHeaderTimeout  int    `json:"upstream_header_timeout" form:"upstream_header_timeout" comment:"获取header超时, 单位s" example:"0" validate:"min=0"` //获取header超时, 单位s
 	UpstreamIdleTimeout    int    `json:"upstream_idle_timeout" form:"upstream_idle_timeout" comment:"链接最大空闲时间, 单位s" example:"0" validate:"min=0"`       //链接最大空闲时间, 单位s
 	UpstreamMaxIdle        int    `json:"upstream_max_idle" form:"upstream_max_idle" comment:"最大空闲链接数" example:"0" validate:"min=0"`                     //最大空闲链接数
+	GroupList              string `json:"group_list" form:"group_list" comment:"节点分组列表" example:"" validate:""`                                          //节点分组列表 与ip列表一一对应
+	GroupWeight            string `json:"group_weight" form:"group_weight" comment:"分组流量比例" example:"" validate:"valid_group_weight"`                    //分组流量比例 如v1:90,v2:10 为空表示不分组
+	GroupSticky            int    `json:"group_sticky" form:"group_sticky" comment:"分组选择按客户端保持" example:"0" validate:"max=1,min=0"`                      //分组选择是否按客户端保持
+	RetryCount             int    `json:"retry_count" form:"retry_count" comment:"失败重试次数" example:"0" validate:"max=5,min=0"`                            //请求失败时换节点重试的最大次数 0表示不重试
+	RetryBackoff           int    `json:"retry_backoff" form:"retry_backoff" comment:"重试等待时间, 单位ms" example:"0" validate:"min=0"`                        //首次重试前的等待时间, 单位ms 之后每次翻倍
+	RetryBudget            int    `json:"retry_budget" form:"retry_budget" comment:"重试预算百分比" example:"20" validate:"max=100,min=0"`                      //重试请求数占请求数的百分比上限 0表示不限制
+	OutlierFailures        int    `json:"outlier_failures" form:"outlier_failures" comment:"节点摘除连续失败次数" example:"0" validate:"min=0"`                    //被动健康检查 节点连续失败达到该次数时摘除 0表示不开启
+	OutlierEjectTime       int    `json:"outlier_eject_time" form:"outlier_eject_time" comment:"节点摘除时间, 单位s" example:"30" validate:"min=0"`              //节点基础摘除时间, 单位s 重复摘除时翻倍
+	OutlierMaxPercent      int    `json:"outlier_max_percent" form:"outlier_max_percent" comment:"最多摘除节点百分比" example:"50" validate:"max=100,min=0"`      //最多摘除节点的百分比
+	HashKey                string `json:"hash_key" form:"hash_key" comment:"一致性hash的key" example:"header:X-User-Id" validate:"valid_hash_key"`           //一致性hash的key 如client_ip、header:X-User-Id、cookie:sid、jwt:sub、path:1 为空时使用请求url
+	HashReplicas           int    `json:"hash_replicas" form:"hash_replicas" comment:"一致性hash虚拟节点数" example:"10" validate:"max=1000,min=0"`              //一致性hash每个节点的虚拟节点数 0表示使用默认值
+	HashLoadFactor         int    `json:"hash_load_factor" form:"hash_load_factor" comment:"一致性hash有界负载系数" example:"25" validate:"min=0"`                //节点进行中的请求数上限为平均值的(100+该值)% 0表示不限制
+	SlowStart              int    `json:"slow_start" form:"slow_start" comment:"慢启动时间, 单位s" example:"0" validate:"min=0"`                                //恢复或新加入的节点权重在该时间内逐步增长 0表示不开启
+	StickyCookie           string `json:"sticky_cookie" form:"sticky_cookie" comment:"会话保持cookie名称" example:"GW_STICKY" validate:"valid_cookie_name"`    //会话保持cookie名称 为空表示不开启
+	StickyMaxAge           int    `json:"sticky_max_age" form:"sticky_max_age" comment:"会话保持cookie有效期, 单位s" example:"0" validate:"min=0"`                //会话保持cookie有效期, 单位s 0表示浏览器关闭前有效
 }
 
 func (param *ServiceUpdateHTTPInput) BindValidParam(c *gin.Context) error {
@@ -123,8 +168,36 @@ func (param *ServiceStatInput) BindValidParam(c *gin.Context) error {
 }
 
 type ServiceStatOutput struct {
-	Today     []int64 `json:"today" form:"today" comment:"今日信息统计" validate:""`         //今日信息统计
-	Yesterday []int64 `json:"yesterday" form:"yesterday" comment:"昨日信息统计" validate:""` //昨日信息统计
+	Today     []int64                `json:"today" form:"today" comment:"今日信息统计" validate:""`         //今日信息统计
+	Yesterday []int64                `json:"yesterday" form:"yesterday" comment:"昨日信息统计" validate:""` //昨日信息统计
+	Group     []ServiceGroupStatItem `json:"group" form:"group" comment:"上游分组统计" validate:""`         //上游分组统计
+	Mirror    ServiceMirrorStatItem  `json:"mirror" form:"mirror" comment:"流量镜像统计" validate:""`       //流量镜像今日统计
+	Retry     []int64                `json:"retry" form:"retry" comment:"今日失败重试统计" validate:""`       //今日每小时失败重试次数
+}
+
+type ServiceMirrorStatItem struct {
+	Total    int64 `json:"total" form:"total"`       //命中镜像比例的请求量
+	Skip     int64 `json:"skip" form:"skip"`         //请求体超过上限未镜像
+	Error    int64 `json:"error" form:"error"`       //影子请求失败或超时
+	Match    int64 `json:"match" form:"match"`       //影子与主服务状态码一致
+	Mismatch int64 `json:"mismatch" form:"mismatch"` //影子与主服务状态码不一致
+}
+
+type ServiceGroupStatItem struct {
+	Name       string `json:"name" form:"name"`               //分组名称
+	Percent    int    `json:"percent" form:"percent"`         //流量比例
+	Qps        int64  `json:"qps" form:"qps"`                 //qps
+	TodayCount int64  `json:"today_count" form:"today_count"` //今日请求量
+}
+
+type ServiceGroupWeightInput struct {
+	ID          int64  `json:"id" form:"id" comment:"服务id" example:"63" validate:"required"`                                          //服务id
+	GroupWeight string `json:"group_weight" form:"group_weight" comment:"分组流量比例" example:"v1:90,v2:10" validate:"valid_group_weight"` //分组流量比例 为空表示不分组
+	GroupSticky int    `json:"group_sticky" form:"group_sticky" comment:"分组选择按客户端保持" example:"0" validate:"max=1,min=0"`              //分组选择是否按客户端保持
+}
+
+func (param *ServiceGroupWeightInput) BindValidParam(c *gin.Context) error {
+	return public.DefaultGetValidParams(c, param)
 }
 
 func (param *ServiceStatOutput) BindValidParam(c *gin.Context) error {
@@ -141,10 +214,16 @@ type ServiceAddTCPInput struct {
 	WhiteHostName     string `json:"white_host_name" form:"white_host_name" comment:"白名单主机，以逗号间隔" validate:"valid_iplist"`
 	ClientIPFlowLimit int    `json:"client_ip_flow_limit" form:"client_ip_flow_limit" comment:"客户端IP限流" validate:""`
 	ServiceFlowLimit  int    `json:"service_flow_limit" form:"service_flow_limit" comment:"服务端限流" validate:""`
-	RoundType         int    `json:"round_type" form:"round_type" comment:"轮询策略" validate:""`
+	RoundType         int    `json:"round_type" form:"round_type" comment:"轮询策略 0=random 1=round-robin 2=weight_round-robin 3=consistent_hash 4=least_conn 5=p2c" validate:"max=5,min=0"`
 	IpList            string `json:"ip_list" form:"ip_list" comment:"IP列表" validate:"required,valid_ipportlist"`
 	WeightList        string `json:"weight_list" form:"weight_list" comment:"权重列表" validate:"required,valid_weightlist"`
 	ForbidList        string `json:"forbid_list" form:"forbid_list" comment:"禁用IP列表" validate:"valid_iplist"`
+	OutlierFailures   int    `json:"outlier_failures" form:"outlier_failures" comment:"节点摘除连续失败次数，0表示不开启被动健康检查" validate:"min=0"`
+	OutlierEjectTime  int    `json:"outlier_eject_time" form:"outlier_eject_time" comment:"节点摘除时间，单位s，重复摘除时翻倍" validate:"min=0"`
+	OutlierMaxPercent int    `json:"outlier_max_percent" form:"outlier_max_percent" comment:"最多摘除节点百分比" validate:"max=100,min=0"`
+	HashReplicas      int    `json:"hash_replicas" form:"hash_replicas" comment:"一致性hash虚拟节点数，0表示使用默认值" validate:"max=1000,min=0"`
+	HashLoadFactor    int    `json:"hash_load_factor" form:"hash_load_factor" comment:"一致性hash有界负载系数，节点连接数上限为平均值的(100+该值)%，0表示不限制" validate:"min=0"`
+	SlowStart         int    `json:"slow_start" form:"slow_start" comment:"慢启动时间，单位s，恢复或新加入的节点权重在该时间内逐步增长，0表示不开启" validate:"min=0"`
 }
 
 func (params *ServiceAddTCPInput) BindValidParam(c *gin.Context) error {
@@ -162,10 +241,16 @@ type ServiceUpdateTCPInput struct {
 	WhiteHostName     string `json:"white_host_name" form:"white_host_name" comment:"白名单主机，以逗号间隔" validate:"valid_iplist"`
 	ClientIPFlowLimit int    `json:"client_ip_flow_limit" form:"client_ip_flow_limit" comment:"客户端IP限流" validate:""`
 	ServiceFlowLimit  int    `json:"service_flow_limit" form:"service_flow_limit" comment:"服务端限流" validate:""`
-	RoundType         int    `json:"round_type" form:"round_type" comment:"轮询策略" validate:""`
+	RoundType         int    `json:"round_type" form:"round_type" comment:"轮询策略 0=random 1=round-robin 2=weight_round-robin 3=consistent_hash 4=least_conn 5=p2c" validate:"max=5,min=0"`
 	IpList            string `json:"ip_list" form:"ip_list" comment:"IP列表" validate:"required,valid_ipportlist"`
 	WeightList        string `json:"weight_list" form:"weight_list" comment:"权重列表" validate:"required,valid_weightlist"`
 	ForbidList        string `json:"forbid_list" form:"forbid_list" comment:"禁用IP列表" validate:"valid_iplist"`
+	OutlierFailures   int    `json:"outlier_failures" form:"outlier_failures" comment:"节点摘除连续失败次数，0表示不开启被动健康检查" validate:"min=0"`
+	OutlierEjectTime  int    `json:"outlier_eject_time" form:"outlier_eject_time" comment:"节点摘除时间，单位s，重复摘除时翻倍" validate:"min=0"`
+	OutlierMaxPercent int    `json:"outlier_max_percent" form:"outlier_max_percent" comment:"最多摘除节点百分比" validate:"max=100,min=0"`
+	HashReplicas      int    `json:"hash_replicas" form:"hash_replicas" comment:"一致性hash虚拟节点数，0表示使用默认值" validate:"max=1000,min=0"`
+	HashLoadFactor    int    `json:"hash_load_factor" form:"hash_load_factor" comment:"一致性hash有界负载系数，节点连接数上限为平均值的(100+该值)%，0表示不限制" validate:"min=0"`
+	SlowStart         int    `json:"slow_start" form:"slow_start" comment:"慢启动时间，单位s，恢复或新加入的节点权重在该时间内逐步增长，0表示不开启" validate:"min=0"`
 }
 
 func (params *ServiceUpdateTCPInput) BindValidParam(c *gin.Context) error {
@@ -183,10 +268,17 @@ type ServiceAddGRPCInput struct {
 	WhiteHostName     string `json:"white_host_name" form:"white_host_name" comment:"白名单主机，以逗号间隔" validate:"valid_iplist"`
 	ClientIPFlowLimit int    `json:"client_ip_flow_limit" form:"client_ip_flow_limit" comment:"客户端IP限流" validate:""`
 	ServiceFlowLimit  int    `json:"service_flow_limit" form:"service_flow_limit" comment:"服务端限流" validate:""`
-	RoundType         int    `json:"round_type" form:"round_type" comment:"轮询策略" validate:""`
+	RoundType         int    `json:"round_type" form:"round_type" comment:"轮询策略 0=random 1=round-robin 2=weight_round-robin 3=consistent_hash 4=least_conn 5=p2c" validate:"max=5,min=0"`
 	IpList            string `json:"ip_list" form:"ip_list" comment:"IP列表" validate:"required,valid_ipportlist"`
 	WeightList        string `json:"weight_list" form:"weight_list" comment:"权重列表" validate:"required,valid_weightlist"`
 	ForbidList        string `json:"forbid_list" form:"forbid_list" comment:"禁用IP列表" validate:"valid_iplist"`
+	OutlierFailures   int    `json:"outlier_failures" form:"outlier_failures" comment:"节点摘除连续失败次数，0表示不开启被动健康检查" validate:"min=0"`
+	OutlierEjectTime  int    `json:"outlier_eject_time" form:"outlier_eject_time" comment:"节点摘除时间，单位s，重复摘除时翻倍" validate:"min=0"`
+	OutlierMaxPercent int    `json:"outlier_max_percent" form:"outlier_max_percent" comment:"最多摘除节点百分比" validate:"max=100,min=0"`
+	HashKey           string `json:"hash_key" form:"hash_key" comment:"一致性hash的key，支持client_ip、header:名称、jwt:名称，为空时使用客户端ip" validate:"valid_hash_key"`
+	HashReplicas      int    `json:"hash_replicas" form:"hash_replicas" comment:"一致性hash虚拟节点数，0表示使用默认值" validate:"max=1000,min=0"`
+	HashLoadFactor    int    `json:"hash_load_factor" form:"hash_load_factor" comment:"一致性hash有界负载系数，节点请求数上限为平均值的(100+该值)%，0表示不限制" validate:"min=0"`
+	SlowStart         int    `json:"slow_start" form:"slow_start" comment:"慢启动时间，单位s，恢复或新加入的节点权重在该时间内逐步增长，0表示不开启" validate:"min=0"`
 }
 
 func (params *ServiceAddGRPCInput) BindValidParam(c *gin.Context) error {
@@ -205,12 +297,237 @@ type ServiceUpdateGRPCInput struct {
 	WhiteHostName     string `json:"white_host_name" form:"white_host_name" comment:"白名单主机，以逗号间隔" validate:"valid_iplist"`
 	ClientIPFlowLimit int    `json:"client_ip_flow_limit" form:"client_ip_flow_limit" comment:"客户端IP限流" validate:""`
 	ServiceFlowLimit  int    `json:"service_flow_limit" form:"service_flow_limit" comment:"服务端限流" validate:""`
-	RoundType         int    `json:"round_type" form:"round_type" comment:"轮询策略" validate:""`
+	RoundType         int    `json:"round_type" form:"round_type" comment:"轮询策略 0=random 1=round-robin 2=weight_round-robin 3=consistent_hash 4=least_conn 5=p2c" validate:"max=5,min=0"`
 	IpList            string `json:"ip_list" form:"ip_list" comment:"IP列表" validate:"required,valid_ipportlist"`
 	WeightList        string `json:"weight_list" form:"weight_list" comment:"权重列表" validate:"required,valid_weightlist"`
 	ForbidList        string `json:"forbid_list" form:"forbid_list" comment:"禁用IP列表" validate:"valid_iplist"`
+	OutlierFailures   int    `json:"outlier_failures" form:"outlier_failures" comment:"节点摘除连续失败次数，0表示不开启被动健康检查" validate:"min=0"`
+	OutlierEjectTime  int    `json:"outlier_eject_time" form:"outlier_eject_time" comment:"节点摘除时间，单位s，重复摘除时翻倍" validate:"min=0"`
+	OutlierMaxPercent int    `json:"outlier_max_percent" form:"outlier_max_percent" comment:"最多摘除节点百分比" validate:"max=100,min=0"`
+	HashKey           string `json:"hash_key" form:"hash_key" comment:"一致性hash的key，支持client_ip、header:名称、jwt:名称，为空时使用客户端ip" validate:"valid_hash_key"`
+	HashReplicas      int    `json:"hash_replicas" form:"hash_replicas" comment:"一致性hash虚拟节点数，0表示使用默认值" validate:"max=1000,min=0"`
+	HashLoadFactor    int    `json:"hash_load_factor" form:"hash_load_factor" comment:"一致性hash有界负载系数，节点请求数上限为平均值的(100+该值)%，0表示不限制" validate:"min=0"`
+	SlowStart         int    `json:"slow_start" form:"slow_start" comment:"慢启动时间，单位s，恢复或新加入的节点权重在该时间内逐步增长，0表示不开启" validate:"min=0"`
 }
 
 func (params *ServiceUpdateGRPCInput) BindValidParam(c *gin.Context) error {
 	return public.DefaultGetValidParams(c, params)
 }
+
+type ServiceVersionListInput struct {
+	ID       int64 `json:"id" form:"id" comment:"服务id" example:"56" validate:"required"`             //服务id
+	PageNum  int64 `json:"page_num" form:"page_num" comment:"页码" example:"1" validate:"required"`    //页码
+	PageSize int64 `json:"page_size" form:"page_size" comment:"条数" example:"20" validate:"required"` //条数
+}
+
+func (param *ServiceVersionListInput) BindValidParam(c *gin.Context) error {
+	return public.DefaultGetValidParams(c, param)
+}
+
+type ServiceVersionListItemOutput struct {
+	Id        int64     `json:"id" form:"id"`               //id
+	Version   int       `json:"version" form:"version"`     //版本号
+	Author    string    `json:"author" form:"author"`       //操作人
+	Remark    string    `json:"remark" form:"remark"`       //备注
+	CreatedAt time.Time `json:"create_at" form:"create_at"` //创建时间
+}
+
+type ServiceVersionListOutput struct {
+	Total int64                          `json:"total" form:"total" comment:"总数"` //总数
+	List  []ServiceVersionListItemOutput `json:"list" form:"list" comment:"列表"`   //列表
+}
+
+type ServiceVersionDiffInput struct {
+	ID          int64 `json:"id" form:"id" comment:"服务id" example:"56" validate:"required"`                       //服务id
+	FromVersion int   `json:"from_version" form:"from_version" comment:"对比的起始版本" example:"1" validate:"required"` //对比的起始版本
+	ToVersion   int   `json:"to_version" form:"to_version" comment:"对比的目标版本" example:"2" validate:"required"`     //对比的目标版本
+}
+
+func (param *ServiceVersionDiffInput) BindValidParam(c *gin.Context) error {
+	return public.DefaultGetValidParams(c, param)
+}
+
+type ServiceVersionDiffItem struct {
+	Field string `json:"field" form:"field"` //字段 如load_balance.ip_list
+	From  string `json:"from" form:"from"`   //起始版本的值
+	To    string `json:"to" form:"to"`       //目标版本的值
+}
+
+type ServiceVersionDiffOutput struct {
+	FromVersion int                      `json:"from_version" form:"from_version"` //起始版本
+	ToVersion   int                      `json:"to_version" form:"to_version"`     //目标版本
+	List        []ServiceVersionDiffItem `json:"list" form:"list"`                 //发生变化的字段
+}
+
+type ServiceVersionRollbackInput struct {
+	ID      int64 `json:"id" form:"id" comment:"服务id" example:"56" validate:"required"`             //服务id
+	Version int   `json:"version" form:"version" comment:"回滚的目标版本" example:"1" validate:"required"` //回滚的目标版本
+}
+
+func (param *ServiceVersionRollbackInput) BindValidParam(c *gin.Context) error {
+	return public.DefaultGetValidParams(c, param)
+}
+
+type HTTPRouteConflictItem struct {
+	ServiceName string `json:"service_name" form:"service_name"` //存在冲突的服务名称
+	RuleType    int    `json:"rule_type" form:"rule_type"`       //存在冲突的服务接入类型
+	Rule        string `json:"rule" form:"rule"`                 //存在冲突的服务接入路径
+	Priority    int    `json:"priority" form:"priority"`         //存在冲突的服务路由优先级
+	Message     string `json:"message" form:"message"`           //冲突说明
+}
+
+type ServiceRouteConflictInput struct {
+	ID int64 `json:"id" form:"id" comment:"服务id" example:"56" validate:"required"` //服务id
+}
+
+func (param *ServiceRouteConflictInput) BindValidParam(c *gin.Context) error {
+	return public.DefaultGetValidParams(c, param)
+}
+
+type ServiceRouteConflictOutput struct {
+	List []HTTPRouteConflictItem `json:"list" form:"list"` //路由冲突
+}
+
+type ServiceCircuitInput struct {
+	ID int64 `json:"id" form:"id" comment:"服务id" example:"63" validate:"required"` //服务id
+}
+
+func (param *ServiceCircuitInput) BindValidParam(c *gin.Context) error {
+	return public.DefaultGetValidParams(c, param)
+}
+
+type ServiceCircuitStatItem struct {
+	Node                string `json:"node" form:"node"`                                 //上游节点地址 为空表示服务级熔断器
+	State               string `json:"state" form:"state"`                               //熔断器状态 closed/open/half_open
+	Requests            int64  `json:"requests" form:"requests"`                         //状态切换时窗口内请求数
+	Failures            int64  `json:"failures" form:"failures"`                         //状态切换时窗口内失败数
+	ConsecutiveFailures int64  `json:"consecutive_failures" form:"consecutive_failures"` //状态切换时连续失败数
+	UpdateAt            int64  `json:"update_at" form:"update_at"`                       //状态切换时间
+}
+
+type ServiceCircuitOutput struct {
+	ErrorPercent int                      `json:"error_percent" form:"error_percent"` //熔断错误率阈值 百分比
+	Failures     int                      `json:"failures" form:"failures"`           //熔断连续失败次数阈值
+	MinRequests  int                      `json:"min_requests" form:"min_requests"`   //按错误率熔断的最小请求数
+	Window       int                      `json:"window" form:"window"`               //错误率统计窗口, 单位s
+	OpenTimeout  int                      `json:"open_timeout" form:"open_timeout"`   //熔断持续时间, 单位s
+	HalfOpen     int                      `json:"half_open" form:"half_open"`         //半开状态放行的探测请求数
+	Fallback     string                   `json:"fallback" form:"fallback"`           //熔断时返回的降级响应内容
+	State        []ServiceCircuitStatItem `json:"state" form:"state"`                 //服务及上游节点熔断器状态
+}
+
+type ServiceCircuitUpdateInput struct {
+	ID           int64  `json:"id" form:"id" comment:"服务id" example:"63" validate:"required"`                               //服务id
+	ErrorPercent int    `json:"error_percent" form:"error_percent" comment:"熔断错误率阈值" example:"50" validate:"max=100,min=0"` //熔断错误率阈值 百分比 0表示不按错误率熔断
+	Failures     int    `json:"failures" form:"failures" comment:"熔断连续失败次数阈值" example:"5" validate:"min=0"`                 //熔断连续失败次数阈值 0表示不按连续失败熔断
+	MinRequests  int    `json:"min_requests" form:"min_requests" comment:"最小请求数" example:"20" validate:"min=0"`             //按错误率熔断的最小请求数
+	Window       int    `json:"window" form:"window" comment:"统计窗口, 单位s" example:"10" validate:"min=0"`                     //错误率统计窗口, 单位s
+	OpenTimeout  int    `json:"open_timeout" form:"open_timeout" comment:"熔断持续时间, 单位s" example:"30" validate:"min=0"`       //熔断持续时间, 单位s
+	HalfOpen     int    `json:"half_open" form:"half_open" comment:"半开探测请求数" example:"1" validate:"min=0"`                  //半开状态放行的探测请求数
+	Fallback     string `json:"fallback" form:"fallback" comment:"降级响应内容" example:"" validate:"max=4096"`                   //熔断时返回的降级响应内容 为空时直接返回错误
+}
+
+func (param *ServiceCircuitUpdateInput) BindValidParam(c *gin.Context) error {
+	return public.DefaultGetValidParams(c, param)
+}
+
+type ServiceCircuitStateInput struct {
+	ID    int64  `json:"id" form:"id" comment:"服务id" example:"63" validate:"required"`                              //服务id
+	State string `json:"state" form:"state" comment:"熔断器状态" example:"closed" validate:"required,oneof=closed open"` //熔断器状态 closed=恢复 open=手动熔断
+}
+
+func (param *ServiceCircuitStateInput) BindValidParam(c *gin.Context) error {
+	return public.DefaultGetValidParams(c, param)
+}
+
+type ServiceHealthCheckInput struct {
+	ID int64 `json:"id" form:"id" comment:"服务id" example:"63" validate:"required"` //服务id
+}
+
+func (param *ServiceHealthCheckInput) BindValidParam(c *gin.Context) error {
+	return public.DefaultGetValidParams(c, param)
+}
+
+type ServiceHealthCheckOutput struct {
+	Method    int    `json:"method" form:"method"`       //检查方法 0=tcp 1=http 2=grpc
+	Timeout   int    `json:"timeout" form:"timeout"`     //检查超时时间, 单位s
+	Interval  int    `json:"interval" form:"interval"`   //检查间隔, 单位s
+	Path      string `json:"path" form:"path"`           //http检查路径
+	Host      string `json:"host" form:"host"`           //http检查使用的Host请求头
+	Status    string `json:"status" form:"status"`       //http检查正常状态码范围
+	Body      string `json:"body" form:"body"`           //http检查响应体需包含的内容
+	Service   string `json:"service" form:"service"`     //grpc检查的服务名称
+	Healthy   int    `json:"healthy" form:"healthy"`     //不可用节点连续检查成功达到该次数时恢复
+	Unhealthy int    `json:"unhealthy" form:"unhealthy"` //可用节点连续检查失败达到该次数时摘除
+}
+
+type ServiceHealthCheckUpdateInput struct {
+	ID        int64  `json:"id" form:"id" comment:"服务id" example:"63" validate:"required"`                                 //服务id
+	Method    int    `json:"method" form:"method" comment:"检查方法" example:"1" validate:"max=2,min=0"`                       //检查方法 0=tcp 1=http 2=grpc 仅grpc服务可使用grpc检查
+	Timeout   int    `json:"timeout" form:"timeout" comment:"检查超时时间, 单位s" example:"2" validate:"max=60,min=0"`             //检查超时时间, 单位s 0表示使用默认值
+	Interval  int    `json:"interval" form:"interval" comment:"检查间隔, 单位s" example:"5" validate:"max=3600,min=0"`           //检查间隔, 单位s 0表示使用默认值
+	Path      string `json:"path" form:"path" comment:"http检查路径" example:"/health" validate:"max=255"`                     //http检查路径 为空时为/
+	Host      string `json:"host" form:"host" comment:"http检查Host请求头" example:"" validate:"max=255"`                       //http检查使用的Host请求头 为空时使用节点地址
+	Status    string `json:"status" form:"status" comment:"http检查正常状态码范围" example:"200-399" validate:"valid_status_range"` //http检查正常状态码范围 如200-399 为空时为200-399
+	Body      string `json:"body" form:"body" comment:"http检查响应体需包含的内容" example:"" validate:"max=255"`                     //http检查响应体需包含的内容 为空表示不检查
+	Service   string `json:"service" form:"service" comment:"grpc检查的服务名称" example:"" validate:"max=255"`                   //grpc检查的服务名称 为空表示检查节点整体状态
+	Healthy   int    `json:"healthy" form:"healthy" comment:"恢复节点的连续成功次数" example:"1" validate:"min=0"`                    //不可用节点连续检查成功达到该次数时恢复 0表示使用默认值
+	Unhealthy int    `json:"unhealthy" form:"unhealthy" comment:"摘除节点的连续失败次数" example:"2" validate:"min=0"`                //可用节点连续检查失败达到该次数时摘除 0表示使用默认值
+}
+
+func (param *ServiceHealthCheckUpdateInput) BindValidParam(c *gin.Context) error {
+	return public.DefaultGetValidParams(c, param)
+}
+
+type ServiceNodeInput struct {
+	ID   int64  `json:"id" form:"id" comment:"服务id" example:"63" validate:"required"`                 //服务id
+	Node string `json:"node" form:"node" comment:"节点地址" example:"127.0.0.1:2003" validate:"required"` //节点地址 需在服务的ip列表中
+}
+
+func (param *ServiceNodeInput) BindValidParam(c *gin.Context) error {
+	return public.DefaultGetValidParams(c, param)
+}
+
+type ServiceBalanceScoreInput struct {
+	ID int64 `json:"id" form:"id" comment:"服务id" example:"63" validate:"required"` //服务id
+}
+
+func (param *ServiceBalanceScoreInput) BindValidParam(c *gin.Context) error {
+	return public.DefaultGetValidParams(c, param)
+}
+
+type ServiceBalanceScoreItem struct {
+	Addr     string  `json:"addr" form:"addr"`           //节点地址
+	Latency  float64 `json:"latency" form:"latency"`     //ewma耗时, 单位ms
+	Inflight int     `json:"inflight" form:"inflight"`   //进行中的请求数
+	Score    float64 `json:"score" form:"score"`         //评分 越小越优先
+	UpdateAt int64   `json:"update_at" form:"update_at"` //评分上报时间
+}
+
+type ServiceBalanceScoreOutput struct {
+	RoundType int                       `json:"round_type" form:"round_type"` //轮询方式 只有p2c策略上报节点评分
+	Nodes     []ServiceBalanceScoreItem `json:"nodes" form:"nodes"`           //节点评分
+}
+
+type ServiceHealthInput struct {
+	ID int64 `json:"id" form:"id" comment:"服务id" example:"63" validate:"required"` //服务id
+}
+
+func (param *ServiceHealthInput) BindValidParam(c *gin.Context) error {
+	return public.DefaultGetValidParams(c, param)
+}
+
+type ServiceHealthItem struct {
+	Addr                string `json:"addr" form:"addr"`                                 //节点地址
+	State               string `json:"state" form:"state"`                               //节点状态 healthy=可用 unhealthy=主动探活摘除 ejected=被动健康检查摘除 drained=手动摘除 unknown=未上报
+	LastProbeTime       int64  `json:"last_probe_time" form:"last_probe_time"`           //最近一次主动探活时间
+	LastError           string `json:"last_error" form:"last_error"`                     //最近一次主动探活的错误
+	ConsecutiveFailures int    `json:"consecutive_failures" form:"consecutive_failures"` //主动探活连续失败次数
+	StateChangeTime     int64  `json:"state_change_time" form:"state_change_time"`       //状态最近一次变化的时间
+	Proxy               string `json:"proxy" form:"proxy"`                               //上报该状态的代理节点 多个代理节点状态不一致时展示最严重的状态
+	UpdateAt            int64  `json:"update_at" form:"update_at"`                       //状态上报时间
+}
+
+type ServiceHealthOutput struct {
+	Nodes []ServiceHealthItem `json:"nodes" form:"nodes"` //节点状态
+}
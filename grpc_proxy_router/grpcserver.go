@@ -2,74 +2,265 @@ package grpc_proxy_router
 
 import (
 	"fmt"
+	"github.com/e421083458/golang_common/lib"
 	"github.com/e421083458/grpc-proxy/proxy"
 	"github.com/starMoonZhao/go_gateway/dao"
 	"github.com/starMoonZhao/go_gateway/grpc_proxy_middleware"
+	"github.com/starMoonZhao/go_gateway/public"
 	"github.com/starMoonZhao/go_gateway/reverse_proxy"
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
 	"log"
 	"net"
+	"sync"
+	"time"
 )
 
-// grpc服务列表
-var grpcServerList = []*WarpGrpcServer{}
+// grpc服务列表 serviceName->WarpGrpcServer
+var (
+	grpcServerMap     = map[string]*WarpGrpcServer{}
+	grpcServerRetry   = map[string]int{} //监听失败的服务及连续失败次数 按退避间隔重新同步
+	grpcRetryPending  bool               //是否已安排重新同步
+	grpcServerStopped bool
+	grpcServerLocker  sync.Mutex
+)
 
 // 映射代理地址与服务器的关系
 type WarpGrpcServer struct {
 	Addr string
 	*grpc.Server
+	listener net.Listener
+	service  *dao.ServiceDetail
+	locker   sync.RWMutex
+}
+
+func (s *WarpGrpcServer) getService() *dao.ServiceDetail {
+	s.locker.RLock()
+	defer s.locker.RUnlock()
+	return s.service
+}
+
+func (s *WarpGrpcServer) setService(service *dao.ServiceDetail) {
+	s.locker.Lock()
+	defer s.locker.Unlock()
+	s.service = service
+}
+
+// 按服务的最新配置构建中间件链并执行
+func (s *WarpGrpcServer) streamInterceptor(srv interface{}, stream grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+	serviceDetail := s.getService()
+	interceptors := []grpc.StreamServerInterceptor{
+		grpc_proxy_middleware.GrpcFlowCountMiddleware(serviceDetail),
+		grpc_proxy_middleware.GrpcFlowLimitMiddleware(serviceDetail),
+		grpc_proxy_middleware.GrpcJwtAuthTokenMiddleware(serviceDetail),
+		grpc_proxy_middleware.GrpcJwtFlowCountMiddleware(serviceDetail),
+		grpc_proxy_middleware.GrpcJwtFlowLimitMiddleware(serviceDetail),
+		grpc_proxy_middleware.GrpcWhiteListMiddleware(serviceDetail),
+		grpc_proxy_middleware.GrpcBlackListMiddleware(serviceDetail),
+		grpc_proxy_middleware.GrpcHeaderTransferMiddleware(serviceDetail),
+	}
+	return chainStreamHandler(interceptors, 0, info, handler)(srv, stream)
+}
+
+// 按服务的最新配置获取负载均衡器并转发请求
+func (s *WarpGrpcServer) streamHandler(srv interface{}, stream grpc.ServerStream) error {
+	serviceDetail := s.getService()
+	//服务熔断：熔断中直接返回不可用错误 错误信息为降级内容
+	breaker := dao.ServiceCircuitBreaker(serviceDetail)
+	if breaker != nil && !breaker.Allow() {
+		message := serviceDetail.LoadBalance.CircuitFallback
+		if message == "" {
+			message = "service circuit breaker is open"
+		}
+		return status.Error(codes.Unavailable, message)
+	}
+
+	loadBalance, err := dao.LoadBalancerHandler.GetLoadBalance(serviceDetail)
+	if err != nil {
+		return err
+	}
+	//被动健康检查：按真实请求结果摘除连续失败的节点
+	nodeReporter, err := dao.LoadBalancerHandler.GetNodeReporter(serviceDetail)
+	if err != nil {
+		return err
+	}
+	err = reverse_proxy.NewGrpcLoadBalanceHandler(loadBalance, grpcBalanceKey(serviceDetail, stream), dao.NodeCircuitBreaker(serviceDetail), nodeReporter)(srv, stream)
+	if breaker != nil {
+		breaker.Report(!reverse_proxy.GrpcUpstreamFailure(err))
+	}
+	return err
+}
+
+// 一致性hash选择节点使用的key 按服务配置从metadata中读取 默认使用客户端ip
+func grpcBalanceKey(serviceDetail *dao.ServiceDetail, stream grpc.ServerStream) string {
+	clientIp := ""
+	if peerCtx, ok := peer.FromContext(stream.Context()); ok {
+		if host, _, err := net.SplitHostPort(peerCtx.Addr.String()); err == nil {
+			clientIp = host
+		}
+	}
+	hashKey := serviceDetail.LoadBalance.HashKeyConf()
+	if hashKey == nil {
+		return clientIp
+	}
+	md, _ := metadata.FromIncomingContext(stream.Context())
+	return hashKey.GRPCValue(func(name string) string {
+		if values := md.Get(name); len(values) > 0 {
+			return values[0]
+		}
+		return ""
+	}, clientIp)
+}
+
+// 将中间件列表串联为一个handler
+func chainStreamHandler(interceptors []grpc.StreamServerInterceptor, index int, info *grpc.StreamServerInfo, handler grpc.StreamHandler) grpc.StreamHandler {
+	if index == len(interceptors) {
+		return handler
+	}
+	return func(srv interface{}, stream grpc.ServerStream) error {
+		return interceptors[index](srv, stream, info, chainStreamHandler(interceptors, index+1, info, handler))
+	}
+}
+
+// grpc服务变更观察者：服务信息重新加载后同步grpc服务器
+type grpcServiceObserver struct{}
+
+func (o *grpcServiceObserver) Update() {
+	syncGrpcServer()
 }
 
 func GrpcServerRun() {
-	//遍历启动grpc服务器
-	grpcServiceList := dao.ServiceManegerHandler.GetGRPCServiceList()
-	for _, grpcService := range grpcServiceList {
-		tempItem := grpcService
-		go func(serviceDetail *dao.ServiceDetail) {
-			//获取监听地址
-			addr := fmt.Sprintf(":%d", serviceDetail.GRPCRule.Port)
-			//获取负载均衡器
-			loadBalance, err := dao.LoadBalancerHandler.GetLoadBalance(serviceDetail)
-			if err != nil {
-				log.Fatalf("grpc server load balance error:%v\n", err)
-				return
-			}
-			//监听代理地址
-			listen, err := net.Listen("tcp", addr)
-			if err != nil {
-				log.Fatalf("grpc server listen port error:%v\n", err)
-				return
-			}
-			//获取负载均衡handler
-			streamHandler := reverse_proxy.NewGrpcLoadBalanceHandler(loadBalance)
-			//创建grpc服务器
-			server := grpc.NewServer(grpc.ChainStreamInterceptor(
-				grpc_proxy_middleware.GrpcFlowCountMiddleware(serviceDetail),
-				grpc_proxy_middleware.GrpcFlowLimitMiddleware(serviceDetail),
-				grpc_proxy_middleware.GrpcJwtAuthTokenMiddleware(serviceDetail),
-				grpc_proxy_middleware.GrpcJwtFlowCountMiddleware(serviceDetail),
-				grpc_proxy_middleware.GrpcJwtFlowLimitMiddleware(serviceDetail),
-				grpc_proxy_middleware.GrpcWhiteListMiddleware(serviceDetail),
-				grpc_proxy_middleware.GrpcBlackListMiddleware(serviceDetail),
-				grpc_proxy_middleware.GrpcHeaderTransferMiddleware(serviceDetail),
-			),
-				grpc.CustomCodec(proxy.Codec()),
-				grpc.UnknownServiceHandler(streamHandler))
-
-			grpcServerList = append(grpcServerList, &WarpGrpcServer{addr, server})
-			log.Printf("grpc server run:%d\n", addr)
-
-			//启动grpc服务器
-			if err := server.Serve(listen); err != nil {
-				log.Fatalf("grpc server start error:%v\n", err)
+	//注册服务变更观察者 服务新增、删除或端口变化时动态启停grpc服务器
+	dao.ServiceManegerHandler.Attach(&grpcServiceObserver{})
+	syncGrpcServer()
+}
+
+// 根据当前的grpc服务列表同步grpc服务器
+// 新增的服务启动监听、删除的服务优雅关闭、端口发生变化的服务重新绑定端口 其余服务仅更新配置
+func syncGrpcServer() {
+	grpcServerLocker.Lock()
+	defer grpcServerLocker.Unlock()
+	if grpcServerStopped {
+		return
+	}
+
+	serviceMap := map[string]*dao.ServiceDetail{}
+	for _, grpcService := range dao.ServiceManegerHandler.GetGRPCServiceList() {
+		serviceMap[grpcService.Info.ServiceName] = grpcService
+	}
+	//已删除的服务不再重试监听
+	for serviceName := range grpcServerRetry {
+		if _, ok := serviceMap[serviceName]; !ok {
+			delete(grpcServerRetry, serviceName)
+		}
+	}
+
+	//关闭已删除或端口发生变化的服务 先释放端口再启动新的监听
+	for serviceName, grpcServer := range grpcServerMap {
+		serviceDetail, ok := serviceMap[serviceName]
+		if ok && serviceDetail.GRPCRule.Port == grpcServer.getService().GRPCRule.Port {
+			continue
+		}
+		delete(grpcServerMap, serviceName)
+		shutdownGrpcServer(grpcServer)
+	}
+
+	//启动新增的服务 更新已有服务的配置 监听失败时按退避间隔重新同步 直到监听成功或服务被删除
+	retry := false
+	for serviceName, serviceDetail := range serviceMap {
+		if grpcServer, ok := grpcServerMap[serviceName]; ok {
+			grpcServer.setService(serviceDetail)
+			continue
+		}
+		grpcServer, err := startGrpcServer(serviceDetail)
+		if err != nil {
+			log.Printf(" [ERROR] grpc server %s start error:%v\n", serviceName, err)
+			grpcServerRetry[serviceName]++
+			retry = true
+			continue
+		}
+		delete(grpcServerRetry, serviceName)
+		grpcServerMap[serviceName] = grpcServer
+	}
+	if retry && !grpcRetryPending {
+		attempts := 0
+		for _, num := range grpcServerRetry {
+			if num > attempts {
+				attempts = num
 			}
-		}(tempItem)
+		}
+		grpcRetryPending = true
+		time.AfterFunc(public.ListenRetryBackoff(attempts), func() {
+			grpcServerLocker.Lock()
+			grpcRetryPending = false
+			grpcServerLocker.Unlock()
+			syncGrpcServer()
+		})
 	}
 }
 
+// 为服务创建grpc服务器并启动
+func startGrpcServer(serviceDetail *dao.ServiceDetail) (*WarpGrpcServer, error) {
+	//获取监听地址
+	addr := fmt.Sprintf(":%d", serviceDetail.GRPCRule.Port)
+	//监听代理地址
+	listen, err := net.Listen("tcp", addr)
+	if err != nil {
+		return nil, err
+	}
+	grpcServer := &WarpGrpcServer{
+		Addr:     addr,
+		listener: listen,
+		service:  serviceDetail,
+	}
+	//创建grpc服务器
+	grpcServer.Server = grpc.NewServer(grpc.StreamInterceptor(grpcServer.streamInterceptor),
+		grpc.CustomCodec(proxy.Codec()),
+		grpc.UnknownServiceHandler(grpcServer.streamHandler))
+
+	go func() {
+		log.Printf("grpc server run:%s\n", addr)
+		//启动grpc服务器 监听器被关闭时正常退出
+		if err := grpcServer.Serve(listen); err != nil {
+			log.Printf("grpc server %s stop serving:%v\n", addr, err)
+		}
+	}()
+	return grpcServer, nil
+}
+
+// 优雅关闭grpc服务器：立即释放监听端口 后台等待进行中的请求处理完毕
+func shutdownGrpcServer(grpcServer *WarpGrpcServer) {
+	grpcServer.listener.Close()
+	go func() {
+		drainTimeout := time.Duration(lib.GetIntConf("proxy.reload.drain_timeout")) * time.Second
+		if drainTimeout <= 0 {
+			drainTimeout = public.DefaultDrainTimeout * time.Second
+		}
+		stopped := make(chan struct{})
+		go func() {
+			grpcServer.GracefulStop()
+			close(stopped)
+		}()
+		select {
+		case <-stopped:
+		case <-time.After(drainTimeout):
+			//超时后强制关闭剩余的请求
+			grpcServer.Stop()
+			log.Printf(" [WARN] grpc server %s drain timeout\n", grpcServer.Addr)
+		}
+		log.Printf("grpc server stop:%s\n", grpcServer.Addr)
+	}()
+}
+
 func GrpcServerStop() {
-	for _, grpcServer := range grpcServerList {
+	grpcServerLocker.Lock()
+	defer grpcServerLocker.Unlock()
+	grpcServerStopped = true
+	for _, grpcServer := range grpcServerMap {
 		grpcServer.GracefulStop()
-		log.Printf("grpc server stop:%d\n", grpcServer.Addr)
+		log.Printf("grpc server stop:%s\n", grpcServer.Addr)
 	}
 }
@@ -0,0 +1,108 @@
+package http_proxy_middleware
+
+import (
+	"bytes"
+	"fmt"
+	"github.com/gin-gonic/gin"
+	"github.com/starMoonZhao/go_gateway/circuit_rate"
+	"github.com/starMoonZhao/go_gateway/dao"
+	"github.com/starMoonZhao/go_gateway/public"
+	"github.com/starMoonZhao/go_gateway/reverse_proxy"
+	"io"
+	"io/ioutil"
+	"log"
+	"math/rand"
+	"net/http"
+	"time"
+)
+
+// 流量镜像：按比例将请求复制一份发往影子服务 在请求转发前调用
+// 返回的函数在主请求完成后以主请求的响应状态码调用 影子请求在独立协程中执行 不影响主请求的响应及耗时
+// 未命中镜像比例时返回nil
+func startHTTPMirror(c *gin.Context, serviceDetail *dao.ServiceDetail) func(primaryStatus int) {
+	httpRule := serviceDetail.HTTPRule
+	if httpRule.MirrorService == "" || httpRule.MirrorPercent <= 0 || rand.Intn(100) >= httpRule.MirrorPercent {
+		return nil
+	}
+	mirrorDetail, ok := dao.ServiceManegerHandler.GetService(httpRule.MirrorService)
+	if !ok || mirrorDetail.Info.LoadType != public.LoadTypeHTTP {
+		return nil
+	}
+	serviceName := serviceDetail.Info.ServiceName
+	increaseMirrorCount(serviceName, public.MirrorResultTotal)
+
+	//请求体超过上限时不镜像 预读的请求体需要重新写回主请求
+	bodyLimit := int64(httpRule.MirrorBodyLimit)
+	if bodyLimit <= 0 {
+		bodyLimit = public.MirrorDefaultBodyLimit
+	}
+	if c.Request.ContentLength > bodyLimit {
+		increaseMirrorCount(serviceName, public.MirrorResultSkip)
+		return nil
+	}
+	body := []byte{}
+	if c.Request.Body != nil && c.Request.Body != http.NoBody {
+		var err error
+		body, err = ioutil.ReadAll(io.LimitReader(c.Request.Body, bodyLimit+1))
+		c.Request.Body = readCloser{io.MultiReader(bytes.NewReader(body), c.Request.Body), c.Request.Body}
+		if err != nil || int64(len(body)) > bodyLimit {
+			increaseMirrorCount(serviceName, public.MirrorResultSkip)
+			return nil
+		}
+	}
+
+	loadBalance, err := dao.LoadBalancerHandler.GetLoadBalance(mirrorDetail)
+	if err != nil {
+		increaseMirrorCount(serviceName, public.MirrorResultError)
+		return nil
+	}
+	trans, err := dao.TransportorHandler.GetTrans(mirrorDetail)
+	if err != nil {
+		increaseMirrorCount(serviceName, public.MirrorResultError)
+		return nil
+	}
+	timeout := time.Duration(httpRule.MirrorTimeout) * time.Millisecond
+	if timeout <= 0 {
+		timeout = public.MirrorDefaultTimeout
+	}
+
+	//复制请求时主请求尚未被反向代理修改
+	mirrorReq := c.Request.Clone(c.Request.Context())
+	primaryStatusChan := make(chan int, 1)
+	go func() {
+		defer func() {
+			if err := recover(); err != nil {
+				log.Println("http mirror err:", err)
+			}
+		}()
+		mirrorStatus, err := reverse_proxy.MirrorRequest(mirrorReq, body, loadBalance, trans, timeout)
+		if err != nil {
+			increaseMirrorCount(serviceName, public.MirrorResultError)
+			return
+		}
+		//对比影子服务与主服务的响应状态码
+		if mirrorStatus == <-primaryStatusChan {
+			increaseMirrorCount(serviceName, public.MirrorResultMatch)
+		} else {
+			increaseMirrorCount(serviceName, public.MirrorResultMismatch)
+		}
+	}()
+	return func(primaryStatus int) {
+		primaryStatusChan <- primaryStatus
+	}
+}
+
+// 流量镜像计数 按服务及镜像结果统计
+func increaseMirrorCount(serviceName, result string) {
+	mirrorFlowCount, err := circuit_rate.FlowCounterHandler.GetFlowCounter(fmt.Sprintf("%s_%s_%s", public.FlowMirror, serviceName, result))
+	if err != nil {
+		return
+	}
+	mirrorFlowCount.Increase()
+}
+
+// 重新组装的请求体 读取预读内容及剩余内容 关闭原请求体
+type readCloser struct {
+	io.Reader
+	io.Closer
+}
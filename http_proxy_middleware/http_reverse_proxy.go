@@ -1,11 +1,16 @@
 package http_proxy_middleware
 
 import (
+	"encoding/json"
+	"fmt"
 	"github.com/gin-gonic/gin"
 	"github.com/pkg/errors"
+	"github.com/starMoonZhao/go_gateway/circuit_rate"
 	"github.com/starMoonZhao/go_gateway/dao"
 	"github.com/starMoonZhao/go_gateway/middleware"
+	"github.com/starMoonZhao/go_gateway/public"
 	"github.com/starMoonZhao/go_gateway/reverse_proxy"
+	"net/http"
 )
 
 // 反向代理匹配
@@ -22,27 +27,132 @@ func HTTPReverseProxyMiddleware() gin.HandlerFunc {
 		//类型转换
 		serviceDetail := serviceInterface.(*dao.ServiceDetail)
 
-		//根据serviceDetail创建负载均衡器
-		loadBalance, err := dao.LoadBalancerHandler.GetLoadBalance(serviceDetail)
+		//灰度路由：按顺序匹配灰度规则 命中时使用目标服务的负载均衡器及连接池 或本服务的目标上游分组
+		upstreamDetail := serviceDetail
+		canaryGroup := ""
+		for _, canaryRule := range serviceDetail.HTTPRule.CanaryRules() {
+			if !canaryRule.Match(c.Request) {
+				continue
+			}
+			if canaryRule.Group != "" {
+				canaryGroup = canaryRule.Group
+				break
+			}
+			targetDetail, ok := dao.ServiceManegerHandler.GetService(canaryRule.Target)
+			if ok && targetDetail.Info.LoadType == public.LoadTypeHTTP {
+				upstreamDetail = targetDetail
+				break
+			}
+		}
+
+		//服务熔断：熔断中直接返回降级响应或错误 请求结束后按转发结果更新熔断器
+		if breaker := dao.ServiceCircuitBreaker(upstreamDetail); breaker != nil {
+			if !breaker.Allow() {
+				if fallback := upstreamDetail.LoadBalance.CircuitFallback; fallback != "" {
+					contentType := "text/plain; charset=utf-8"
+					if json.Valid([]byte(fallback)) {
+						contentType = "application/json; charset=utf-8"
+					}
+					c.Data(http.StatusOK, contentType, []byte(fallback))
+				} else {
+					middleware.ResponseError(c, 9004, errors.New("service circuit breaker is open"))
+				}
+				//中断中间件传递链
+				c.Abort()
+				return
+			}
+			defer func() {
+				_, proxyFailed := c.Get("proxy_error")
+				breaker.Report(!proxyFailed && c.Writer.Status() < http.StatusInternalServerError)
+			}()
+		}
+
+		//根据serviceDetail创建负载均衡器 服务配置了上游分组时按分组流量比例或灰度路由的目标分组选择分组
+		groupName, loadBalance, err := dao.LoadBalancerHandler.GetGroupLoadBalance(upstreamDetail, c.ClientIP(), canaryGroup)
 		if err != nil {
 			middleware.ResponseError(c, 9002, err)
 			//中断中间件传递链
 			c.Abort()
 			return
 		}
+		if groupName != "" {
+			//统计各分组的流量
+			groupFlowCount, err := circuit_rate.FlowCounterHandler.GetFlowCounter(fmt.Sprintf("%s_%s_%s", public.FlowGroup, upstreamDetail.Info.ServiceName, groupName))
+			if err == nil {
+				groupFlowCount.Increase()
+			}
+		}
+
+		//一致性hash：按服务配置的key选择节点 如用户id、会话cookie
+		if hashKey := upstreamDetail.LoadBalance.HashKeyConf(); hashKey != nil {
+			c.Set("balance_key", hashKey.HTTPValue(c.Request, c.ClientIP()))
+		}
+
+		//会话保持：携带会话cookie的请求转发到所选分组内的同一节点
+		stickySession, err := dao.LoadBalancerHandler.GetStickySession(upstreamDetail, groupName)
+		if err != nil {
+			middleware.ResponseError(c, 9002, err)
+			//中断中间件传递链
+			c.Abort()
+			return
+		}
+		if stickySession != nil {
+			c.Set("sticky_session", stickySession)
+		}
 
 		//根据serviceDetail创建连接池
-		trans, err := dao.TransportorHandler.GetTrans(serviceDetail)
+		trans, err := dao.TransportorHandler.GetTrans(upstreamDetail)
 		if err != nil {
 			middleware.ResponseError(c, 9003, err)
 			//中断中间件传递链
 			c.Abort()
 			return
 		}
+		//流量镜像：影子请求异步执行 主请求完成后对比响应状态码
+		if mirrorDone := startHTTPMirror(c, serviceDetail); mirrorDone != nil {
+			defer func() {
+				mirrorDone(c.Writer.Status())
+			}()
+		}
+
+		//失败重试策略 每次重试计入服务的重试统计
+		retryPolicy, err := dao.LoadBalancerHandler.GetRetryPolicy(upstreamDetail, func() {
+			retryFlowCount, err := circuit_rate.FlowCounterHandler.GetFlowCounter(fmt.Sprintf("%s_%s", public.FlowRetry, upstreamDetail.Info.ServiceName))
+			if err == nil {
+				retryFlowCount.Increase()
+			}
+		})
+		if err != nil {
+			middleware.ResponseError(c, 9002, err)
+			//中断中间件传递链
+			c.Abort()
+			return
+		}
+
+		//被动健康检查：按真实请求结果摘除连续失败的节点
+		nodeReporter, err := dao.LoadBalancerHandler.GetNodeReporter(upstreamDetail)
+		if err != nil {
+			middleware.ResponseError(c, 9002, err)
+			//中断中间件传递链
+			c.Abort()
+			return
+		}
+
 		//创建reverseproxy
-		proxy := reverse_proxy.NewLoadBalanceReverseProxy(c, loadBalance, trans)
+		proxy := reverse_proxy.NewLoadBalanceReverseProxy(c, loadBalance, trans, retryPolicy, dao.NodeCircuitBreaker(upstreamDetail), nodeReporter)
 		//使用reverseproxy.ServerHTTP(c.Request,c.Response)
 		proxy.ServeHTTP(c.Writer, c.Request)
+		//释放请求使用的上游节点
+		reverse_proxy.ReleaseUpstream(c, loadBalance)
+
+		//开启响应体捕获时记录上游响应 用于排查问题
+		if payload, ok := c.Get("payload"); ok {
+			public.ComLogNotice(c, "_com_http_proxy_response", map[string]interface{}{
+				"service":     upstreamDetail.Info.ServiceName,
+				"status_code": c.Writer.Status(),
+				"payload":     string(payload.([]byte)),
+			})
+		}
 		c.Abort()
 		return
 	}
@@ -5,7 +5,6 @@ import (
 	"github.com/pkg/errors"
 	"github.com/starMoonZhao/go_gateway/dao"
 	"github.com/starMoonZhao/go_gateway/middleware"
-	"github.com/starMoonZhao/go_gateway/public"
 	"strings"
 )
 
@@ -23,10 +22,10 @@ func HTTPStripUriMiddleware() gin.HandlerFunc {
 		//类型转换
 		serviceDetail := serviceInterface.(*dao.ServiceDetail)
 
-		//如果需要StripUri并且HTTPRule规则是前缀匹配 才需要StripUri
-		if serviceDetail.HTTPRule.NeedStripUri == 1 && serviceDetail.HTTPRule.RuleType == public.HTTPRuleTypePrefixURL {
+		//如果需要StripUri并且HTTPRule规则包含url前缀(前缀匹配或域名+前缀匹配) 才需要StripUri
+		if pathPrefix := serviceDetail.HTTPRule.PathPrefix(); serviceDetail.HTTPRule.NeedStripUri == 1 && pathPrefix != "" {
 			//将请求path中的接入规则去除
-			c.Request.URL.Path = strings.Replace(c.Request.URL.Path, serviceDetail.HTTPRule.Rule, "", 1)
+			c.Request.URL.Path = strings.Replace(c.Request.URL.Path, pathPrefix, "", 1)
 		}
 
 		//传递到下一中间件
@@ -8,9 +8,11 @@ import (
 	"github.com/starMoonZhao/go_gateway/http_proxy_router"
 	"github.com/starMoonZhao/go_gateway/router"
 	"github.com/starMoonZhao/go_gateway/tcp_server"
+	"log"
 	"os"
 	"os/signal"
 	"syscall"
+	"time"
 )
 
 var (
@@ -35,7 +37,7 @@ func main() {
 		defer lib.Destroy()
 		router.HttpServerRun()
 
-		quit := make(chan os.Signal)
+		quit := make(chan os.Signal, 1)
 		signal.Notify(quit, syscall.SIGKILL, syscall.SIGQUIT, syscall.SIGINT, syscall.SIGTERM)
 		<-quit
 
@@ -44,12 +46,41 @@ func main() {
 		lib.InitModule(*config, []string{"base", "mysql", "redis"})
 		defer lib.Destroy()
 
+		//根据配置选择服务及租户信息的来源 mysql或配置文件目录
+		if err := dao.InitConfigSource(); err != nil {
+			log.Fatalf(" [ERROR] InitConfigSource err:%v\n", err)
+		}
+
 		//系统启动 加载服务信息
 		dao.ServiceManegerHandler.LoadOnce()
 
 		//系统启动 加载租户信息
 		dao.AppManegerHandler.LoadOnce()
 
+		//定时检查服务及租户信息版本 发生变化时热加载
+		reloadInterval := time.Duration(lib.GetIntConf("proxy.reload.interval")) * time.Second
+		dao.ServiceManegerHandler.WatchReLoad(reloadInterval)
+		dao.AppManegerHandler.WatchReLoad(reloadInterval)
+
+		//订阅管理后台发布的配置变更事件 实时同步单个服务或租户 配置文件来源由定时版本检查感知文件变化
+		if dao.IsMysqlConfigSource() {
+			dao.SubscribeConfigChange()
+		}
+
+		//收到SIGHUP信号时立即热加载服务及租户信息
+		go func() {
+			reload := make(chan os.Signal, 1)
+			signal.Notify(reload, syscall.SIGHUP)
+			for range reload {
+				if err := dao.ServiceManegerHandler.ReLoad(); err != nil {
+					log.Printf(" [ERROR] ServiceManger ReLoad err:%v\n", err)
+				}
+				if err := dao.AppManegerHandler.ReLoad(); err != nil {
+					log.Printf(" [ERROR] AppManger ReLoad err:%v\n", err)
+				}
+			}
+		}()
+
 		//启动http代理服务器
 		go func() {
 			http_proxy_router.HttpServerRun()
@@ -67,7 +98,7 @@ func main() {
 			grpc_proxy_router.GrpcServerRun()
 		}()
 
-		quit := make(chan os.Signal)
+		quit := make(chan os.Signal, 1)
 		signal.Notify(quit, syscall.SIGKILL, syscall.SIGQUIT, syscall.SIGINT, syscall.SIGTERM)
 		<-quit
 
@@ -87,7 +118,7 @@ func main() {
 		defer lib.Destroy()
 		router.HttpServerRun()
 
-		quit := make(chan os.Signal)
+		quit := make(chan os.Signal, 1)
 		signal.Notify(quit, syscall.SIGKILL, syscall.SIGQUIT, syscall.SIGINT, syscall.SIGTERM)
 		<-quit
 
@@ -57,9 +57,46 @@ func TranslationMiddleware() gin.HandlerFunc {
 				return matched
 			})
 			val.RegisterValidation("valid_rule", func(fl validator.FieldLevel) bool {
-				matched, _ := regexp.Match(`^\S+$`, []byte(fl.Field().String()))
+				//按同一结构体中的接入类型校验规则格式
+				ruleType := reflect.Indirect(fl.Parent()).FieldByName("RuleType")
+				if !ruleType.IsValid() {
+					matched, _ := regexp.Match(`^\S+$`, []byte(fl.Field().String()))
+					return matched
+				}
+				return public.ValidHTTPRule(int(ruleType.Int()), fl.Field().String())
+			})
+			val.RegisterValidation("valid_http_methods", func(fl validator.FieldLevel) bool {
+				return public.ValidHTTPMethods(fl.Field().String())
+			})
+			val.RegisterValidation("valid_group_weight", func(fl validator.FieldLevel) bool {
+				//同一结构体中包含ip列表、权重列表及分组列表时 一并校验分组与节点的对应关系
+				parent := reflect.Indirect(fl.Parent())
+				ipList, weightList, groupList := parent.FieldByName("IpList"), parent.FieldByName("WeightList"), parent.FieldByName("GroupList")
+				if !ipList.IsValid() || !weightList.IsValid() || !groupList.IsValid() {
+					_, err := public.ParseUpstreamGroupWeight(fl.Field().String())
+					return err == nil
+				}
+				return public.ValidUpstreamGroup(ipList.String(), weightList.String(), groupList.String(), fl.Field().String())
+			})
+			val.RegisterValidation("valid_status_range", func(fl validator.FieldLevel) bool {
+				_, _, err := public.ParseStatusRange(fl.Field().String())
+				return err == nil
+			})
+			val.RegisterValidation("valid_hash_key", func(fl validator.FieldLevel) bool {
+				_, err := public.ParseHashKey(fl.Field().String())
+				return err == nil
+			})
+			val.RegisterValidation("valid_cookie_name", func(fl validator.FieldLevel) bool {
+				if fl.Field().String() == "" {
+					return true
+				}
+				matched, _ := regexp.Match(`^[a-zA-Z0-9_\-]{1,64}$`, []byte(fl.Field().String()))
 				return matched
 			})
+			val.RegisterValidation("valid_canary_rule", func(fl validator.FieldLevel) bool {
+				_, err := public.ParseCanaryRules(fl.Field().String())
+				return err == nil
+			})
 			val.RegisterValidation("valid_url_rewrite", func(fl validator.FieldLevel) bool {
 				if fl.Field().String() == "" {
 					return true
@@ -133,6 +170,42 @@ func TranslationMiddleware() gin.HandlerFunc {
 				t, _ := ut.T("valid_rule", fe.Field())
 				return t
 			})
+			val.RegisterTranslation("valid_http_methods", trans, func(ut ut.Translator) error {
+				return ut.Add("valid_http_methods", "{0} 不符合输入格式", true)
+			}, func(ut ut.Translator, fe validator.FieldError) string {
+				t, _ := ut.T("valid_http_methods", fe.Field())
+				return t
+			})
+			val.RegisterTranslation("valid_group_weight", trans, func(ut ut.Translator) error {
+				return ut.Add("valid_group_weight", "{0} 不符合输入格式或与节点分组不匹配", true)
+			}, func(ut ut.Translator, fe validator.FieldError) string {
+				t, _ := ut.T("valid_group_weight", fe.Field())
+				return t
+			})
+			val.RegisterTranslation("valid_status_range", trans, func(ut ut.Translator) error {
+				return ut.Add("valid_status_range", "{0} 不符合输入格式", true)
+			}, func(ut ut.Translator, fe validator.FieldError) string {
+				t, _ := ut.T("valid_status_range", fe.Field())
+				return t
+			})
+			val.RegisterTranslation("valid_hash_key", trans, func(ut ut.Translator) error {
+				return ut.Add("valid_hash_key", "{0} 不符合输入格式", true)
+			}, func(ut ut.Translator, fe validator.FieldError) string {
+				t, _ := ut.T("valid_hash_key", fe.Field())
+				return t
+			})
+			val.RegisterTranslation("valid_cookie_name", trans, func(ut ut.Translator) error {
+				return ut.Add("valid_cookie_name", "{0} 不符合输入格式", true)
+			}, func(ut ut.Translator, fe validator.FieldError) string {
+				t, _ := ut.T("valid_cookie_name", fe.Field())
+				return t
+			})
+			val.RegisterTranslation("valid_canary_rule", trans, func(ut ut.Translator) error {
+				return ut.Add("valid_canary_rule", "{0} 不符合输入格式", true)
+			}, func(ut ut.Translator, fe validator.FieldError) string {
+				t, _ := ut.T("valid_canary_rule", fe.Field())
+				return t
+			})
 			val.RegisterTranslation("valid_url_rewrite", trans, func(ut ut.Translator) error {
 				return ut.Add("valid_url_rewrite", "{0} 不符合输入格式", true)
 			}, func(ut ut.Translator, fe validator.FieldError) string {
@@ -0,0 +1,77 @@
+-- 网关数据库升级脚本
+-- 在已有的网关数据库上按顺序执行 新增字段的默认值均保持升级前的行为
+-- 新增字段与dao中对应结构体的gorm column一一对应
+
+-- 服务配置版本快照 dao/service_version.go
+CREATE TABLE IF NOT EXISTS `gateway_service_version` (
+  `id` bigint(20) NOT NULL AUTO_INCREMENT COMMENT '自增主键',
+  `service_id` bigint(20) NOT NULL DEFAULT '0' COMMENT '服务id',
+  `version` int(11) NOT NULL DEFAULT '0' COMMENT '版本号 同一服务内递增',
+  `detail` mediumtext NOT NULL COMMENT '服务详情快照 json',
+  `author` varchar(255) NOT NULL DEFAULT '' COMMENT '操作人',
+  `remark` varchar(255) NOT NULL DEFAULT '' COMMENT '备注',
+  `create_at` datetime NOT NULL DEFAULT '1971-01-01 00:00:00' COMMENT '创建时间',
+  PRIMARY KEY (`id`),
+  UNIQUE KEY `idx_service_version` (`service_id`,`version`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8 COMMENT='网关服务配置版本';
+
+-- 服务配置修订号 dao/service_revision.go
+CREATE TABLE IF NOT EXISTS `gateway_service_revision` (
+  `id` bigint(20) NOT NULL COMMENT '主键 固定为1',
+  `revision` bigint(20) NOT NULL DEFAULT '0' COMMENT '服务配置修订号 每次保存递增',
+  PRIMARY KEY (`id`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8 COMMENT='网关服务配置修订号';
+
+-- http服务接入规则 dao/service_http_rule.go
+ALTER TABLE `gateway_service_http_rule`
+  -- 路由优先级
+  ADD COLUMN `priority` int(11) NOT NULL DEFAULT '0' COMMENT '路由优先级 规则精确程度相同时数值越大越优先',
+  -- 请求方法限制
+  ADD COLUMN `methods` varchar(255) NOT NULL DEFAULT '' COMMENT '允许的请求方法 多个以逗号分隔 为空表示不限制',
+  -- 灰度路由
+  ADD COLUMN `canary_rule` varchar(2000) NOT NULL DEFAULT '' COMMENT '灰度路由规则 为空表示不开启',
+  -- 流量镜像
+  ADD COLUMN `mirror_service` varchar(255) NOT NULL DEFAULT '' COMMENT '流量镜像的影子服务名称 为空表示不镜像',
+  ADD COLUMN `mirror_percent` int(11) NOT NULL DEFAULT '0' COMMENT '流量镜像比例 0-100',
+  ADD COLUMN `mirror_timeout` int(11) NOT NULL DEFAULT '0' COMMENT '影子请求超时时间, 单位ms 为0时使用默认值',
+  ADD COLUMN `mirror_body_limit` int(11) NOT NULL DEFAULT '0' COMMENT '镜像请求体上限, 单位byte 为0时使用默认值';
+
+-- 服务负载均衡 dao/service_load_balance.go
+ALTER TABLE `gateway_service_load_balance`
+  -- 上游分组
+  ADD COLUMN `group_list` varchar(2000) NOT NULL DEFAULT '' COMMENT '节点分组列表 与ip列表一一对应',
+  ADD COLUMN `group_weight` varchar(255) NOT NULL DEFAULT '' COMMENT '分组流量比例 如v1:90,v2:10 为空表示不分组',
+  ADD COLUMN `group_sticky` tinyint(4) NOT NULL DEFAULT '0' COMMENT '分组选择是否按客户端保持 1=是',
+  -- 失败重试
+  ADD COLUMN `retry_count` int(11) NOT NULL DEFAULT '0' COMMENT '请求失败时换节点重试的最大次数 0表示不重试',
+  ADD COLUMN `retry_backoff` int(11) NOT NULL DEFAULT '0' COMMENT '首次重试前的等待时间, 单位ms 之后每次翻倍',
+  ADD COLUMN `retry_budget` int(11) NOT NULL DEFAULT '0' COMMENT '重试预算 重试请求数占请求数的百分比上限 0表示不限制',
+  -- 熔断
+  ADD COLUMN `circuit_error_percent` int(11) NOT NULL DEFAULT '0' COMMENT '熔断错误率阈值 百分比 0表示不按错误率熔断',
+  ADD COLUMN `circuit_failures` int(11) NOT NULL DEFAULT '0' COMMENT '熔断连续失败次数阈值 0表示不按连续失败熔断',
+  ADD COLUMN `circuit_min_requests` int(11) NOT NULL DEFAULT '0' COMMENT '统计窗口内按错误率熔断的最小请求数',
+  ADD COLUMN `circuit_window` int(11) NOT NULL DEFAULT '0' COMMENT '错误率统计窗口, 单位s',
+  ADD COLUMN `circuit_open_timeout` int(11) NOT NULL DEFAULT '0' COMMENT '熔断持续时间, 单位s 之后进入半开状态',
+  ADD COLUMN `circuit_half_open` int(11) NOT NULL DEFAULT '0' COMMENT '半开状态放行的探测请求数',
+  ADD COLUMN `circuit_fallback` varchar(2000) NOT NULL DEFAULT '' COMMENT '熔断时返回的降级响应内容 为空时直接返回错误',
+  -- 被动健康检查
+  ADD COLUMN `outlier_failures` int(11) NOT NULL DEFAULT '0' COMMENT '被动健康检查 节点连续失败达到该次数时摘除 0表示不开启',
+  ADD COLUMN `outlier_eject_time` int(11) NOT NULL DEFAULT '0' COMMENT '节点基础摘除时间, 单位s 重复摘除时翻倍',
+  ADD COLUMN `outlier_max_percent` int(11) NOT NULL DEFAULT '0' COMMENT '最多摘除节点的百分比',
+  -- 主动健康检查 check_method沿用原有字段 1=http检查 2=grpc检查
+  ADD COLUMN `check_path` varchar(255) NOT NULL DEFAULT '' COMMENT 'http检查路径',
+  ADD COLUMN `check_host` varchar(255) NOT NULL DEFAULT '' COMMENT 'http检查使用的Host请求头 为空时使用节点地址',
+  ADD COLUMN `check_status` varchar(255) NOT NULL DEFAULT '' COMMENT 'http检查正常状态码范围 如200-399 为空时使用默认值',
+  ADD COLUMN `check_body` varchar(255) NOT NULL DEFAULT '' COMMENT 'http检查响应体需包含的内容 为空表示不检查',
+  ADD COLUMN `check_service` varchar(255) NOT NULL DEFAULT '' COMMENT 'grpc检查的服务名称 为空表示检查节点整体状态',
+  ADD COLUMN `check_healthy` int(11) NOT NULL DEFAULT '0' COMMENT '不可用节点连续检查成功达到该次数时恢复',
+  ADD COLUMN `check_unhealthy` int(11) NOT NULL DEFAULT '0' COMMENT '可用节点连续检查失败达到该次数时摘除',
+  -- 一致性hash
+  ADD COLUMN `hash_key` varchar(255) NOT NULL DEFAULT '' COMMENT '一致性hash的key 为空时http使用请求url tcp及grpc使用客户端ip',
+  ADD COLUMN `hash_replicas` int(11) NOT NULL DEFAULT '0' COMMENT '一致性hash每个节点的虚拟节点数 0表示使用默认值',
+  ADD COLUMN `hash_load_factor` int(11) NOT NULL DEFAULT '0' COMMENT '一致性hash有界负载 0表示不限制',
+  -- 会话保持
+  ADD COLUMN `sticky_cookie` varchar(255) NOT NULL DEFAULT '' COMMENT '会话保持cookie名称 仅http服务 为空表示不开启',
+  ADD COLUMN `sticky_max_age` int(11) NOT NULL DEFAULT '0' COMMENT '会话保持cookie有效期, 单位s 0表示浏览器关闭前有效',
+  -- 慢启动
+  ADD COLUMN `slow_start` int(11) NOT NULL DEFAULT '0' COMMENT '慢启动时间, 单位s 0表示不开启';
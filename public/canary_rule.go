@@ -0,0 +1,126 @@
+package public
+
+import (
+	"errors"
+	"hash/crc32"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// 灰度路由规则的取值来源
+const (
+	CanarySourceHeader = "header"
+	CanarySourceCookie = "cookie"
+	CanarySourceQuery  = "query"
+)
+
+// 灰度路由规则的匹配方式
+const (
+	CanaryOpEq    = "eq"    //值相等
+	CanaryOpRegex = "regex" //值匹配正则
+	CanaryOpMod   = "mod"   //值对除数取模小于阈值 值不是整数时取crc32 如100:5表示%100<5
+)
+
+// 灰度路由规则的目标为本服务的上游分组时 目标以该前缀开头 如group:v2
+const CanaryTargetGroupPrefix = "group:"
+
+// 灰度路由规则：命中时请求转发至目标服务或本服务的目标上游分组
+// 格式：来源 名称 匹配方式 值 目标 多条规则以逗号分隔 按顺序匹配
+// 如：header X-Canary eq 1 user_service_v2,query uid mod 100:5 group:v2
+type CanaryRule struct {
+	Source  string
+	Name    string
+	Op      string
+	Value   string
+	Target  string //目标服务名称 目标为上游分组时为空
+	Group   string //目标上游分组名称 目标为服务时为空
+	regexp  *regexp.Regexp
+	divisor uint64
+	bound   uint64
+}
+
+// 解析灰度路由规则
+func ParseCanaryRules(text string) ([]*CanaryRule, error) {
+	rules := []*CanaryRule{}
+	if text == "" {
+		return rules, nil
+	}
+	for _, item := range strings.Split(text, ",") {
+		fields := strings.Fields(item)
+		if len(fields) != 5 {
+			return nil, errors.New("canary rule format error: " + item)
+		}
+		rule := &CanaryRule{Source: fields[0], Name: fields[1], Op: fields[2], Value: fields[3], Target: fields[4]}
+		if strings.HasPrefix(rule.Target, CanaryTargetGroupPrefix) {
+			rule.Target, rule.Group = "", strings.TrimPrefix(rule.Target, CanaryTargetGroupPrefix)
+			if rule.Group == "" {
+				return nil, errors.New("canary rule target group error: " + item)
+			}
+		}
+		switch rule.Source {
+		case CanarySourceHeader, CanarySourceCookie, CanarySourceQuery:
+		default:
+			return nil, errors.New("canary rule source error: " + rule.Source)
+		}
+		switch rule.Op {
+		case CanaryOpEq:
+		case CanaryOpRegex:
+			regex, err := regexp.Compile(rule.Value)
+			if err != nil {
+				return nil, err
+			}
+			rule.regexp = regex
+		case CanaryOpMod:
+			parts := strings.Split(rule.Value, ":")
+			if len(parts) != 2 {
+				return nil, errors.New("canary rule mod value error: " + rule.Value)
+			}
+			divisor, err := strconv.ParseUint(parts[0], 10, 64)
+			if err != nil || divisor == 0 {
+				return nil, errors.New("canary rule mod value error: " + rule.Value)
+			}
+			bound, err := strconv.ParseUint(parts[1], 10, 64)
+			if err != nil {
+				return nil, errors.New("canary rule mod value error: " + rule.Value)
+			}
+			rule.divisor, rule.bound = divisor, bound
+		default:
+			return nil, errors.New("canary rule op error: " + rule.Op)
+		}
+		rules = append(rules, rule)
+	}
+	return rules, nil
+}
+
+// 请求是否命中灰度路由规则 取不到值时不命中
+func (rule *CanaryRule) Match(req *http.Request) bool {
+	var value string
+	switch rule.Source {
+	case CanarySourceHeader:
+		value = req.Header.Get(rule.Name)
+	case CanarySourceCookie:
+		if cookie, err := req.Cookie(rule.Name); err == nil {
+			value = cookie.Value
+		}
+	case CanarySourceQuery:
+		value = req.URL.Query().Get(rule.Name)
+	}
+	if value == "" {
+		return false
+	}
+	switch rule.Op {
+	case CanaryOpEq:
+		return value == rule.Value
+	case CanaryOpRegex:
+		return rule.regexp.MatchString(value)
+	case CanaryOpMod:
+		number, err := strconv.ParseUint(value, 10, 64)
+		if err != nil {
+			number = uint64(crc32.ChecksumIEEE([]byte(value)))
+		}
+		return number%rule.divisor < rule.bound
+	}
+	return false
+}
@@ -1,5 +1,7 @@
 package public
 
+import "time"
+
 const (
 	ValidatorKey        = "ValidatorKey"
 	TranslatorKey       = "TranslatorKey"
@@ -11,8 +13,11 @@ const (
 	LoadTypeGRPC = 2
 
 	//http服务的规则类型
-	HTTPRuleTypePrefixURL = 0
-	HTTPRuleTypeDomain    = 1
+	HTTPRuleTypePrefixURL      = 0 //url前缀 /abc
+	HTTPRuleTypeDomain         = 1 //域名 www.test.com
+	HTTPRuleTypeHostPrefix     = 2 //域名+url前缀 api.test.com/v2
+	HTTPRuleTypeWildcardDomain = 3 //泛域名 *.test.com
+	HTTPRuleTypeRegexURL       = 4 //url正则 ^/user/[0-9]+$
 
 	//http服务是否使用https
 	HTTPDontNeedHttps = 0
@@ -25,11 +30,27 @@ const (
 	//流量统计器ID前缀
 	FlowTotal   = "flow_total"   //全站流量
 	FlowService = "flow_service" //服务流量
+	FlowGroup   = "flow_group"   //服务上游分组流量
+	FlowMirror  = "flow_mirror"  //服务流量镜像
+	FlowRetry   = "flow_retry"   //服务失败重试
 	FlowApp     = "flow_app"     //租户流量
 
 	//jwt校验
 	JwtSignKey = "jwt_sign_key"
 	JwtExpires = 60 * 60
+
+	//服务下线时等待连接处理完毕的默认超时时间 单位s
+	DefaultDrainTimeout = 30
+
+	//tcp及grpc服务监听端口失败后的重试间隔 从最小间隔开始每次翻倍 不超过最大间隔
+	ListenRetryMinBackoff = time.Second
+	ListenRetryMaxBackoff = 30 * time.Second
+
+	//配置变更通知的redis频道及变更类型
+	RedisConfigChangeChannel = "gateway_config_change"
+	ConfigChangeTypeService  = "service"
+	ConfigChangeTypeApp      = "app"
+	ConfigChangeTypeCircuit  = "circuit"
 )
 
 var (
@@ -40,3 +61,32 @@ var (
 		LoadTypeGRPC: "GRPC",
 	}
 )
+
+// 流量镜像
+const (
+	MirrorDefaultBodyLimit = 1 << 20                 //默认镜像请求体上限 1MB
+	MirrorDefaultTimeout   = 1000 * time.Millisecond //默认影子请求超时时间
+
+	//镜像结果计数项
+	MirrorResultTotal    = "total"    //命中镜像比例的请求
+	MirrorResultSkip     = "skip"     //请求体超过上限未镜像
+	MirrorResultError    = "error"    //影子请求失败或超时
+	MirrorResultMatch    = "match"    //影子与主服务状态码一致
+	MirrorResultMismatch = "mismatch" //影子与主服务状态码不一致
+)
+
+// 熔断
+const (
+	RedisCircuitBreakerKey     = "circuit_breaker" //熔断器状态快照
+	CircuitBreakerServiceField = "service"         //服务级熔断器在状态快照中的字段名
+	RedisCircuitForcedKey      = "circuit_forced"  //手动熔断的服务 hash字段为服务名称 代理节点启动及定时同步时恢复
+
+	CircuitDefaultWindow      = 10 * time.Second //默认错误率统计窗口
+	CircuitDefaultOpenTimeout = 30 * time.Second //默认熔断持续时间
+)
+
+// 负载均衡
+const (
+	RedisBalanceScoreKey = "balance_score" //按耗时选择节点的负载均衡器的节点评分
+	RedisNodeStatusKey   = "node_status"   //上游节点健康状态
+)
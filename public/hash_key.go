@@ -0,0 +1,116 @@
+package public
+
+import (
+	"errors"
+	"github.com/dgrijalva/jwt-go"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// 一致性hash的key来源
+const (
+	HashKeyClientIP = "client_ip" //客户端ip
+	HashKeyHeader   = "header"    //请求头 grpc为metadata
+	HashKeyCookie   = "cookie"    //cookie
+	HashKeyJwt      = "jwt"       //Authorization请求头中jwt的claim 不校验签名
+	HashKeyPath     = "path"      //url路径的第n段 从1开始
+)
+
+// 一致性hash的key：来源及名称
+// 格式：client_ip 或 来源:名称 如header:X-User-Id、cookie:session_id、jwt:sub、path:2
+// 取不到值时使用客户端ip
+type HashKey struct {
+	Source string
+	Name   string
+	index  int
+}
+
+// 解析一致性hash的key 为空时返回nil
+func ParseHashKey(text string) (*HashKey, error) {
+	if text == "" {
+		return nil, nil
+	}
+	parts := strings.SplitN(text, ":", 2)
+	hashKey := &HashKey{Source: parts[0]}
+	if len(parts) == 2 {
+		hashKey.Name = parts[1]
+	}
+	switch hashKey.Source {
+	case HashKeyClientIP:
+		if hashKey.Name != "" {
+			return nil, errors.New("hash key format error: " + text)
+		}
+	case HashKeyHeader, HashKeyCookie, HashKeyJwt:
+		if hashKey.Name == "" {
+			return nil, errors.New("hash key name empty: " + text)
+		}
+	case HashKeyPath:
+		index, err := strconv.Atoi(hashKey.Name)
+		if err != nil || index < 1 {
+			return nil, errors.New("hash key path index error: " + text)
+		}
+		hashKey.index = index
+	default:
+		return nil, errors.New("hash key source error: " + hashKey.Source)
+	}
+	return hashKey, nil
+}
+
+// 从http请求中取一致性hash的key
+func (hashKey *HashKey) HTTPValue(req *http.Request, clientIP string) string {
+	value := ""
+	switch hashKey.Source {
+	case HashKeyHeader:
+		value = req.Header.Get(hashKey.Name)
+	case HashKeyCookie:
+		if cookie, err := req.Cookie(hashKey.Name); err == nil {
+			value = cookie.Value
+		}
+	case HashKeyJwt:
+		value = jwtClaim(req.Header.Get("Authorization"), hashKey.Name)
+	case HashKeyPath:
+		segments := strings.Split(strings.Trim(req.URL.Path, "/"), "/")
+		if hashKey.index <= len(segments) {
+			value = segments[hashKey.index-1]
+		}
+	}
+	if value == "" {
+		return clientIP
+	}
+	return value
+}
+
+// 从grpc请求中取一致性hash的key getMetadata为读取metadata的函数 cookie及path来源使用客户端ip
+func (hashKey *HashKey) GRPCValue(getMetadata func(name string) string, clientIP string) string {
+	value := ""
+	switch hashKey.Source {
+	case HashKeyHeader:
+		value = getMetadata(strings.ToLower(hashKey.Name))
+	case HashKeyJwt:
+		value = jwtClaim(getMetadata("authorization"), hashKey.Name)
+	}
+	if value == "" {
+		return clientIP
+	}
+	return value
+}
+
+// 读取Bearer token中的claim 只用于选择节点 不校验签名
+func jwtClaim(authorization, name string) string {
+	token := strings.TrimSpace(strings.TrimPrefix(authorization, "Bearer "))
+	if token == "" {
+		return ""
+	}
+	claims := jwt.MapClaims{}
+	if _, _, err := new(jwt.Parser).ParseUnverified(token, claims); err != nil {
+		return ""
+	}
+	switch value := claims[name].(type) {
+	case string:
+		return value
+	case float64:
+		return strconv.FormatFloat(value, 'f', -1, 64)
+	}
+	return ""
+}
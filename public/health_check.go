@@ -0,0 +1,39 @@
+package public
+
+import (
+	"errors"
+	"strconv"
+	"strings"
+)
+
+// http健康检查默认的正常状态码范围
+const (
+	HealthCheckDefaultStatusMin = 200
+	HealthCheckDefaultStatusMax = 399
+)
+
+// 解析http健康检查的正常状态码范围
+// 格式：最小值-最大值 或单个状态码 如200-399、200 为空时使用默认范围
+func ParseStatusRange(text string) (int, int, error) {
+	if text == "" {
+		return HealthCheckDefaultStatusMin, HealthCheckDefaultStatusMax, nil
+	}
+	parts := strings.Split(text, "-")
+	if len(parts) > 2 {
+		return 0, 0, errors.New("status range format error: " + text)
+	}
+	min, err := strconv.Atoi(strings.TrimSpace(parts[0]))
+	if err != nil {
+		return 0, 0, errors.New("status range format error: " + text)
+	}
+	max := min
+	if len(parts) == 2 {
+		if max, err = strconv.Atoi(strings.TrimSpace(parts[1])); err != nil {
+			return 0, 0, errors.New("status range format error: " + text)
+		}
+	}
+	if min < 100 || max > 599 || min > max {
+		return 0, 0, errors.New("status range error: " + text)
+	}
+	return min, max, nil
+}
@@ -0,0 +1,50 @@
+package public
+
+import (
+	"net/http"
+	"regexp"
+	"strings"
+)
+
+// http服务接入规则格式校验
+// 域名+url前缀：域名与前缀以第一个/分隔 如api.test.com/v2
+// 泛域名：以*.开头 如*.test.com
+// url正则：合法的正则表达式
+func ValidHTTPRule(ruleType int, rule string) bool {
+	switch ruleType {
+	case HTTPRuleTypePrefixURL, HTTPRuleTypeDomain:
+		matched, _ := regexp.MatchString(`^\S+$`, rule)
+		return matched
+	case HTTPRuleTypeHostPrefix:
+		matched, _ := regexp.MatchString(`^[^/\s]+/\S*$`, rule)
+		return matched
+	case HTTPRuleTypeWildcardDomain:
+		matched, _ := regexp.MatchString(`^\*\.[^*/\s]+$`, rule)
+		return matched
+	case HTTPRuleTypeRegexURL:
+		if rule == "" {
+			return false
+		}
+		_, err := regexp.Compile(rule)
+		return err == nil
+	}
+	return false
+}
+
+// http请求方法限制格式校验 多个方法以逗号分隔 为空表示不限制
+func ValidHTTPMethods(methods string) bool {
+	if methods == "" {
+		return true
+	}
+	for _, method := range strings.Split(methods, ",") {
+		if !InStringSlice(httpMethods, method) {
+			return false
+		}
+	}
+	return true
+}
+
+var httpMethods = []string{
+	http.MethodGet, http.MethodHead, http.MethodPost, http.MethodPut, http.MethodPatch,
+	http.MethodDelete, http.MethodConnect, http.MethodOptions, http.MethodTrace,
+}
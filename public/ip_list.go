@@ -0,0 +1,27 @@
+package public
+
+import (
+	"regexp"
+	"strings"
+)
+
+// 校验ip列表与权重列表：ip列表格式为ip:port 权重为数字 两者数量一一对应
+// 与控制器参数校验valid_ipportlist、valid_weightlist保持一致
+func ValidIPWeightList(ipList, weightList string) bool {
+	ips := strings.Split(ipList, ",")
+	weights := strings.Split(weightList, ",")
+	if len(ips) != len(weights) {
+		return false
+	}
+	for _, ip := range ips {
+		if matched, _ := regexp.MatchString(`^\S+\:\d+$`, ip); !matched {
+			return false
+		}
+	}
+	for _, weight := range weights {
+		if matched, _ := regexp.MatchString(`^\d+$`, weight); !matched {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,88 @@
+package public
+
+import (
+	"errors"
+	"hash/crc32"
+	"math/rand"
+	"strconv"
+	"strings"
+)
+
+// 上游分组流量比例：分组名称及流量百分比
+// 格式：分组:百分比 多个分组以逗号分隔 百分比之和为100 如v1:90,v2:10
+type UpstreamGroupWeight struct {
+	Name    string
+	Percent int
+}
+
+// 解析上游分组流量比例
+func ParseUpstreamGroupWeight(text string) ([]UpstreamGroupWeight, error) {
+	groupWeights := []UpstreamGroupWeight{}
+	if text == "" {
+		return groupWeights, nil
+	}
+	total := 0
+	for _, item := range strings.Split(text, ",") {
+		parts := strings.Split(item, ":")
+		if len(parts) != 2 || parts[0] == "" {
+			return nil, errors.New("upstream group weight format error: " + item)
+		}
+		percent, err := strconv.Atoi(parts[1])
+		if err != nil || percent < 0 || percent > 100 {
+			return nil, errors.New("upstream group weight percent error: " + item)
+		}
+		for _, groupWeight := range groupWeights {
+			if groupWeight.Name == parts[0] {
+				return nil, errors.New("upstream group weight duplicate group: " + parts[0])
+			}
+		}
+		total += percent
+		groupWeights = append(groupWeights, UpstreamGroupWeight{Name: parts[0], Percent: percent})
+	}
+	if total != 100 {
+		return nil, errors.New("upstream group weight percent sum must be 100")
+	}
+	return groupWeights, nil
+}
+
+// 校验节点分组列表与流量比例：分组列表与ip列表、权重列表一一对应 流量比例中的分组在分组列表中均存在
+// 未配置流量比例时不启用分组
+func ValidUpstreamGroup(ipList, weightList, groupList, groupWeight string) bool {
+	groupWeights, err := ParseUpstreamGroupWeight(groupWeight)
+	if err != nil {
+		return false
+	}
+	if len(groupWeights) == 0 {
+		return true
+	}
+	groups := strings.Split(groupList, ",")
+	if len(groups) != len(strings.Split(ipList, ",")) || len(groups) != len(strings.Split(weightList, ",")) {
+		return false
+	}
+	for _, groupWeight := range groupWeights {
+		if !InStringSlice(groups, groupWeight.Name) {
+			return false
+		}
+	}
+	return true
+}
+
+// 按流量比例选择上游分组 stickyKey不为空时同一个key总是选中同一个分组
+func SelectUpstreamGroup(groupWeights []UpstreamGroupWeight, stickyKey string) string {
+	if len(groupWeights) == 0 {
+		return ""
+	}
+	var point int
+	if stickyKey != "" {
+		point = int(crc32.ChecksumIEEE([]byte(stickyKey)) % 100)
+	} else {
+		point = rand.Intn(100)
+	}
+	for _, groupWeight := range groupWeights {
+		if point < groupWeight.Percent {
+			return groupWeight.Name
+		}
+		point -= groupWeight.Percent
+	}
+	return groupWeights[len(groupWeights)-1].Name
+}
@@ -6,6 +6,7 @@ import (
 	"encoding/json"
 	"fmt"
 	"io"
+	"time"
 )
 
 // 生成加盐密码
@@ -42,3 +43,15 @@ func InStringSlice(slice []string, str string) bool {
 	}
 	return false
 }
+
+// 第attempts次监听失败后的重试间隔
+func ListenRetryBackoff(attempts int) time.Duration {
+	backoff := ListenRetryMinBackoff
+	for i := 1; i < attempts && backoff < ListenRetryMaxBackoff; i++ {
+		backoff *= 2
+	}
+	if backoff > ListenRetryMaxBackoff {
+		backoff = ListenRetryMaxBackoff
+	}
+	return backoff
+}
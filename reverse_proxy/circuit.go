@@ -0,0 +1,93 @@
+package reverse_proxy
+
+import (
+	"fmt"
+	"github.com/pkg/errors"
+	"github.com/starMoonZhao/go_gateway/circuit_rate"
+	"github.com/starMoonZhao/go_gateway/reverse_proxy/load_balance"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"net/http"
+	"strings"
+)
+
+// 上游节点熔断器：按节点地址获取熔断器 为nil表示不开启节点熔断
+type NodeCircuitBreaker func(node string) *circuit_rate.CircuitBreaker
+
+// 选择节点时最多尝试的次数
+const circuitMaxSelect = 10
+
+// 跳过熔断中节点的负载均衡器
+type circuitLoadBalance struct {
+	load_balance.LoadBalance
+	nodeBreaker NodeCircuitBreaker
+}
+
+// 包装负载均衡器 选择节点时跳过熔断中的节点 nodeBreaker为nil时直接返回原负载均衡器
+func NewCircuitLoadBalance(lb load_balance.LoadBalance, nodeBreaker NodeCircuitBreaker) load_balance.LoadBalance {
+	if nodeBreaker == nil {
+		return lb
+	}
+	if circuitLB, ok := lb.(*circuitLoadBalance); ok {
+		return circuitLB
+	}
+	return &circuitLoadBalance{LoadBalance: lb, nodeBreaker: nodeBreaker}
+}
+
+// 一致性hash等策略对同一个key总是返回同一节点 因此每次取值时变换key
+func (lb *circuitLoadBalance) Get(key string) (string, error) {
+	for i := 0; i < circuitMaxSelect; i++ {
+		selectKey := key
+		if i > 0 {
+			selectKey = fmt.Sprintf("%s#circuit-%d", key, i)
+		}
+		nextAddr, err := lb.LoadBalance.Get(selectKey)
+		if err != nil {
+			return "", err
+		}
+		if lb.nodeBreaker(CircuitNode(nextAddr)).Allow() {
+			return nextAddr, nil
+		}
+		lb.LoadBalance.Done(nextAddr)
+	}
+	return "", errors.New("all upstream nodes are circuit open")
+}
+
+// 直接使用指定节点 节点熔断中时返回错误
+func (lb *circuitLoadBalance) GetNode(addr string) error {
+	if !lb.nodeBreaker(CircuitNode(addr)).Allow() {
+		return errors.New("upstream node is circuit open")
+	}
+	return lb.LoadBalance.GetNode(addr)
+}
+
+// 节点熔断器使用的节点地址 去除负载均衡器返回地址中的协议
+func CircuitNode(addr string) string {
+	if index := strings.Index(addr, "://"); index >= 0 {
+		return addr[index+3:]
+	}
+	return addr
+}
+
+// 按http请求结果上报上游节点 连接失败及5xx响应视为失败
+type nodeResultTransport struct {
+	transport http.RoundTripper
+	report    NodeReporter
+}
+
+func (t *nodeResultTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	res, err := t.transport.RoundTrip(req)
+	if req.URL.Host != "" {
+		t.report(req.URL.Host, err == nil && res.StatusCode < http.StatusInternalServerError)
+	}
+	return res, err
+}
+
+// grpc请求是否为上游故障 上游不可用、超时及内部错误视为失败 其他错误码由业务返回
+func GrpcUpstreamFailure(err error) bool {
+	switch status.Code(err) {
+	case codes.Unavailable, codes.DeadlineExceeded, codes.Internal:
+		return true
+	}
+	return false
+}
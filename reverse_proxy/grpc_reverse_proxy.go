@@ -7,26 +7,53 @@ import (
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/metadata"
 	"log"
+	"time"
 )
 
-func NewGrpcLoadBalanceHandler(lb load_balance.LoadBalance) grpc.StreamHandler {
+// balanceKey为一致性hash选择节点使用的key nodeBreaker为nil时不开启上游节点熔断 nodeReporter为nil时不开启被动健康检查
+func NewGrpcLoadBalanceHandler(lb load_balance.LoadBalance, balanceKey string, nodeBreaker NodeCircuitBreaker, nodeReporter NodeReporter) grpc.StreamHandler {
+	//选择节点时跳过熔断中的节点
+	lb = NewCircuitLoadBalance(lb, nodeBreaker)
+	report := NodeResultReporter(nodeBreaker, nodeReporter)
 	//闭包
 	return func() grpc.StreamHandler {
+		var nextAddr string
 		//请求协调者
 		director := func(ctx context.Context, fullMethodName string) (context.Context, *grpc.ClientConn, error) {
-			nextAddr, err := lb.Get("")
+			var err error
+			nextAddr, err = lb.Get(balanceKey)
 			if err != nil {
-				log.Fatalf("get next addr err:", err)
+				log.Printf("get next addr err:%v\n", err)
+				return nil, nil, err
 			}
 			//拨号
 			conn, err := grpc.DialContext(ctx, nextAddr, grpc.WithCodec(proxy.Codec()), grpc.WithInsecure())
 			//加载输入内容
 			md, _ := metadata.FromIncomingContext(ctx)
 			//加载输出上下文
-			outCtx, _ := context.WithCancel(ctx)
-			outCtx = metadata.NewOutgoingContext(ctx, md.Copy())
+			outCtx := metadata.NewOutgoingContext(ctx, md.Copy())
 			return outCtx, conn, err
 		}
-		return proxy.TransparentHandler(director)
+		handler := proxy.TransparentHandler(director)
+		return func(srv interface{}, stream grpc.ServerStream) error {
+			start := time.Now()
+			err := handler(srv, stream)
+			if nextAddr != "" {
+				//按耗时选择节点的负载均衡器 以调用耗时作为节点耗时
+				if reporter := latencyReporter(lb); reporter != nil {
+					var reportErr error
+					if GrpcUpstreamFailure(err) {
+						reportErr = err
+					}
+					reporter.ReportLatency(nextAddr, time.Since(start), reportErr)
+				}
+				lb.Done(nextAddr)
+			}
+			//按请求结果更新节点熔断器及节点摘除状态
+			if report != nil && nextAddr != "" {
+				report(nextAddr, !GrpcUpstreamFailure(err))
+			}
+			return err
+		}
 	}()
 }
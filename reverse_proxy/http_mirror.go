@@ -0,0 +1,58 @@
+package reverse_proxy
+
+import (
+	"bytes"
+	"context"
+	"github.com/pkg/errors"
+	"github.com/starMoonZhao/go_gateway/reverse_proxy/load_balance"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"time"
+)
+
+// 将请求复制一份发往影子服务 返回影子服务的响应状态码 响应内容直接丢弃
+// body为预读的请求体 timeout为整个影子请求的超时时间
+func MirrorRequest(req *http.Request, body []byte, lb load_balance.LoadBalance, transport *http.Transport, timeout time.Duration) (int, error) {
+	//根据负载均衡器获取影子服务地址
+	nextAddr, err := lb.Get(req.URL.String())
+	if err != nil || nextAddr == "" {
+		return 0, errors.New("get mirror addr error")
+	}
+	defer lb.Done(nextAddr)
+	target, err := url.Parse(nextAddr)
+	if err != nil {
+		return 0, err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	//复制请求 请求路径及参数的拼接方式与反向代理一致
+	mirrorReq := req.Clone(ctx)
+	mirrorReq.RequestURI = ""
+	mirrorReq.URL.Scheme = target.Scheme
+	mirrorReq.URL.Host = target.Host
+	mirrorReq.URL.Path = singleJoiningSlash(target.Path, req.URL.Path)
+	mirrorReq.Host = target.Host
+	if target.RawQuery != "" {
+		if mirrorReq.URL.RawQuery == "" {
+			mirrorReq.URL.RawQuery = target.RawQuery
+		} else {
+			mirrorReq.URL.RawQuery = target.RawQuery + "&" + mirrorReq.URL.RawQuery
+		}
+	}
+	mirrorReq.Body = ioutil.NopCloser(bytes.NewReader(body))
+	mirrorReq.ContentLength = int64(len(body))
+	mirrorReq.Header.Set("X-Gateway-Mirror", "1")
+
+	res, err := transport.RoundTrip(mirrorReq)
+	if err != nil {
+		return 0, err
+	}
+	defer res.Body.Close()
+	//读完响应体以便连接复用
+	io.Copy(ioutil.Discard, res.Body)
+	return res.StatusCode, nil
+}
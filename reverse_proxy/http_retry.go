@@ -0,0 +1,198 @@
+package reverse_proxy
+
+import (
+	"fmt"
+	"github.com/starMoonZhao/go_gateway/reverse_proxy/load_balance"
+	"io"
+	"net/http"
+	"net/http/httptrace"
+	"net/url"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// 失败重试策略：上游连接失败、连接重置、超时等transport错误时换一个节点重试
+type RetryPolicy struct {
+	Count   int           //最大重试次数
+	Backoff time.Duration //首次重试前的等待时间 之后每次翻倍
+	Budget  *RetryBudget  //重试预算 为nil表示不限制
+	OnRetry func()        //每次重试时回调 用于统计
+}
+
+// 重试预算：限制重试请求数占正常请求数的比例 避免上游故障时重试放大流量
+// 每个请求存入percent/100个令牌 每次重试消耗一个令牌 令牌数有上限
+type RetryBudget struct {
+	percent float64
+	tokens  float64
+	locker  sync.Mutex
+}
+
+// 重试预算的令牌上限 同时作为初始令牌数 保证低流量时也能重试
+const retryBudgetMaxTokens = 10
+
+// 新建重试预算 percent<=0时返回nil 表示不限制
+func NewRetryBudget(percent int) *RetryBudget {
+	if percent <= 0 {
+		return nil
+	}
+	return &RetryBudget{percent: float64(percent) / 100, tokens: retryBudgetMaxTokens}
+}
+
+// 每个请求存入令牌
+func (budget *RetryBudget) Deposit() {
+	if budget == nil {
+		return
+	}
+	budget.locker.Lock()
+	defer budget.locker.Unlock()
+	budget.tokens += budget.percent
+	if budget.tokens > retryBudgetMaxTokens {
+		budget.tokens = retryBudgetMaxTokens
+	}
+}
+
+// 重试前取出令牌 令牌不足时不允许重试
+func (budget *RetryBudget) Withdraw() bool {
+	if budget == nil {
+		return true
+	}
+	budget.locker.Lock()
+	defer budget.locker.Unlock()
+	if budget.tokens < 1 {
+		return false
+	}
+	budget.tokens--
+	return true
+}
+
+// 带失败重试的连接池：请求失败时按重试策略从负载均衡器中选择另一个节点重试
+// 幂等请求在请求体未被读取或为空时重试 非幂等请求只在请求尚未发出(如连接建立失败)时重试
+type retryTransport struct {
+	transport http.RoundTripper
+	lb        load_balance.LoadBalance
+	policy    *RetryPolicy
+	onSwitch  func(addr string) //切换到新节点时回调 用于释放上一次尝试的节点
+}
+
+func (t *retryTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	t.policy.Budget.Deposit()
+
+	//连接池在请求失败时会关闭请求体 重试时需要继续使用 原请求体由http server在请求结束时关闭
+	var body *retryBody
+	if req.Body != nil {
+		body = &retryBody{ReadCloser: req.Body}
+		req.Body = body
+	}
+
+	tried := []string{req.URL.Host}
+	backoff := t.policy.Backoff
+	for attempt := 0; ; attempt++ {
+		var wroteHeaders int32
+		trace := &httptrace.ClientTrace{
+			WroteHeaders: func() {
+				atomic.StoreInt32(&wroteHeaders, 1)
+			},
+		}
+		res, err := t.transport.RoundTrip(req.WithContext(httptrace.WithClientTrace(req.Context(), trace)))
+		if err == nil || attempt >= t.policy.Count || req.Context().Err() != nil {
+			return res, err
+		}
+		bodyRead := body != nil && body.read()
+		sent := atomic.LoadInt32(&wroteHeaders) == 1
+		if bodyRead || (sent && !idempotentMethod(req.Method)) {
+			return res, err
+		}
+
+		//选择一个未尝试过的节点 没有其他可用节点时不再重试
+		nextAddr, target := t.nextTarget(req, tried, attempt)
+		if target == nil {
+			return res, err
+		}
+		if !t.policy.Budget.Withdraw() {
+			t.lb.Done(nextAddr)
+			return res, err
+		}
+		tried = append(tried, target.Host)
+		if t.onSwitch != nil {
+			t.onSwitch(nextAddr)
+		}
+
+		if backoff > 0 {
+			timer := time.NewTimer(backoff)
+			select {
+			case <-req.Context().Done():
+				timer.Stop()
+				return nil, req.Context().Err()
+			case <-timer.C:
+			}
+			backoff *= 2
+		}
+		if t.policy.OnRetry != nil {
+			t.policy.OnRetry()
+		}
+
+		retryReq := req.Clone(req.Context())
+		retryReq.URL.Scheme = target.Scheme
+		retryReq.URL.Host = target.Host
+		retryReq.Host = target.Host
+		req = retryReq
+	}
+}
+
+// 从负载均衡器中选择一个未尝试过的节点 一致性hash等策略对同一个key总是返回同一节点 因此每次取值时变换key
+// 返回负载均衡器中的节点地址及解析后的地址 未选中的节点立即释放
+func (t *retryTransport) nextTarget(req *http.Request, tried []string, attempt int) (string, *url.URL) {
+	for i := 0; i < len(tried)+t.policy.Count*2; i++ {
+		nextAddr, err := t.lb.Get(fmt.Sprintf("%s#%d-%d", req.URL.String(), attempt, i))
+		if err != nil || nextAddr == "" {
+			return "", nil
+		}
+		target, err := url.Parse(nextAddr)
+		if err != nil {
+			t.lb.Done(nextAddr)
+			return "", nil
+		}
+		usedAddr := false
+		for _, host := range tried {
+			if host == target.Host {
+				usedAddr = true
+				break
+			}
+		}
+		if !usedAddr {
+			return nextAddr, target
+		}
+		t.lb.Done(nextAddr)
+	}
+	return "", nil
+}
+
+// 幂等的请求方法
+func idempotentMethod(method string) bool {
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodTrace, http.MethodPut, http.MethodDelete:
+		return true
+	}
+	return false
+}
+
+// 记录是否已被读取的请求体 忽略连接池的关闭操作
+type retryBody struct {
+	io.ReadCloser
+	readBytes int64
+}
+
+func (body *retryBody) Read(p []byte) (int, error) {
+	n, err := body.ReadCloser.Read(p)
+	atomic.AddInt64(&body.readBytes, int64(n))
+	return n, err
+}
+
+func (body *retryBody) Close() error {
+	return nil
+}
+
+func (body *retryBody) read() bool {
+	return atomic.LoadInt64(&body.readBytes) > 0
+}
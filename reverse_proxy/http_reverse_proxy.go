@@ -2,28 +2,57 @@ package reverse_proxy
 
 import (
 	"bytes"
-	"compress/gzip"
+	"github.com/e421083458/golang_common/lib"
 	"github.com/gin-gonic/gin"
+	"github.com/pkg/errors"
 	"github.com/starMoonZhao/go_gateway/middleware"
 	"github.com/starMoonZhao/go_gateway/reverse_proxy/load_balance"
-	"io/ioutil"
+	"io"
 	"log"
 	"net/http"
 	"net/http/httputil"
 	"net/url"
-	"strconv"
 	"strings"
+	"time"
 )
 
 // 根据http上下文、负载均衡器LoadBalance和连接池构建反向代理
-func NewLoadBalanceReverseProxy(c *gin.Context, lb load_balance.LoadBalance, transport *http.Transport) *httputil.ReverseProxy {
+// retryPolicy为nil时请求失败不重试 nodeBreaker为nil时不开启上游节点熔断 nodeReporter为nil时不开启被动健康检查
+// 转发失败时在上下文中设置proxy_error 选择节点失败但重试成功时不设置
+func NewLoadBalanceReverseProxy(c *gin.Context, lb load_balance.LoadBalance, transport *http.Transport, retryPolicy *RetryPolicy, nodeBreaker NodeCircuitBreaker, nodeReporter NodeReporter) *httputil.ReverseProxy {
+	//选择节点时跳过熔断中的节点
+	lb = NewCircuitLoadBalance(lb, nodeBreaker)
+
 	//构建请求协调者：将请求进行参数配置、服务节点选择、请求转发
 	director := func(req *http.Request) {
-		//根据负载均衡器获取下一可用服务地址
-		nextAddr, err := lb.Get(req.URL.String())
-		if err != nil || nextAddr == "" {
-			panic("get next addr error")
+		//会话保持：cookie对应的节点可用且未熔断时直接转发到该节点 不经过负载均衡器选择 但计入负载均衡器的统计
+		nextAddr := ""
+		if sticky := stickySession(c); sticky != nil {
+			if node := sticky.Node(req); node != "" && lb.GetNode(node) == nil {
+				nextAddr = node
+			}
+		}
+		if nextAddr == "" {
+			//根据负载均衡器获取下一可用服务地址 服务配置了一致性hash的key时按该key选择节点
+			balanceKey := c.GetString("balance_key")
+			if balanceKey == "" {
+				balanceKey = req.URL.String()
+			}
+			var err error
+			nextAddr, err = lb.Get(balanceKey)
+			if err != nil || nextAddr == "" {
+				//没有可用节点时清空请求地址 由连接池返回错误并交由errFunc处理
+				//选择节点的错误单独记录 重试换节点成功时不视为转发失败 最终失败时由errFunc转为proxy_error
+				if err == nil {
+					err = errors.New("get next addr error")
+				}
+				c.Set("director_error", err)
+				req.URL.Host = ""
+				return
+			}
 		}
+		//记录使用中的节点 请求结束后由ReleaseUpstream释放
+		c.Set("upstream_addr", nextAddr)
 		//解析可用服务地址
 		target, err := url.Parse(nextAddr)
 		if err != nil {
@@ -50,57 +79,106 @@ func NewLoadBalanceReverseProxy(c *gin.Context, lb load_balance.LoadBalance, tra
 			req.Header.Set("User-Agent", "user-agent")
 		}
 	}
-	//构建返回体输出：将代理的请求收到的内容写入的原请求中
+	//构建返回体输出：响应体默认以流式转发 不在网关中缓存整个响应体 以支持大文件下载、事件流及chunked响应
 	modifier := func(res *http.Response) error {
+		c.Set("status_code", res.StatusCode)
+		//会话保持：下发处理该请求的节点对应的cookie 节点变化时重新下发
+		if sticky := stickySession(c); sticky != nil {
+			sticky.SetCookie(res)
+		}
+
 		//兼容websocket
 		if strings.Contains(res.Header.Get("Connection"), "Upgrade") {
 			return nil
 		}
 
-		var payLoad []byte
-		var readErr error
-
-		//兼容gzip
-		if strings.Contains(res.Header.Get("Content-Encoding"), "gzip") {
-			//读取内容
-			reader, err := gzip.NewReader(res.Body)
-			if err != nil {
-				return err
-			}
-			payLoad, readErr = ioutil.ReadAll(reader)
-			//删除请求头参数
-			res.Header.Del("Content-Encoding")
-		} else {
-			//直接读取内容
-			payLoad, readErr = ioutil.ReadAll(res.Body)
-		}
-
-		if readErr != nil {
-			return readErr
+		//按配置在转发的同时捕获响应体的前若干字节 仅用于日志 压缩的响应体不捕获
+		if captureLimit := lib.GetIntConf("proxy.response.capture_body"); captureLimit > 0 && res.Header.Get("Content-Encoding") == "" {
+			res.Body = &captureBody{ReadCloser: res.Body, c: c, limit: captureLimit}
 		}
-
-		//将预读的数据重新写回
-		c.Set("status_code", res.StatusCode)
-		c.Set("payload", payLoad)
-		res.Body = ioutil.NopCloser(bytes.NewBuffer(payLoad))
-		res.ContentLength = int64(len(payLoad))
-		res.Header.Set("Content-Length", strconv.FormatInt(res.ContentLength, 10))
-
 		return nil
 	}
 
 	//错误回调函数 范围：transport.RoundTrip发生的错误、以及ModifyResponse发生的错误
 	errFunc := func(res http.ResponseWriter, req *http.Request, err error) {
+		if directorErr, ok := c.Get("director_error"); ok {
+			err = directorErr.(error)
+		}
+		c.Set("proxy_error", err)
 		log.Printf("Reverse Proxy Err:%v\n", err)
 		middleware.ResponseError(c, 9999, err)
 	}
 
+	//开启节点熔断或被动健康检查时 按每个节点的请求结果更新节点熔断器及节点摘除状态
+	//开启失败重试时 由retryTransport在请求失败后换节点重试 每次重试的结果同样按节点上报
+	var roundTripper http.RoundTripper = transport
+	//按耗时选择节点的负载均衡器 上报每个节点的请求耗时
+	if reporter := latencyReporter(lb); reporter != nil {
+		roundTripper = &latencyTransport{transport: roundTripper, reporter: reporter}
+	}
+	if report := NodeResultReporter(nodeBreaker, nodeReporter); report != nil {
+		roundTripper = &nodeResultTransport{transport: roundTripper, report: report}
+	}
+	if retryPolicy != nil && retryPolicy.Count > 0 {
+		roundTripper = &retryTransport{transport: roundTripper, lb: lb, policy: retryPolicy, onSwitch: func(addr string) {
+			//重试切换节点时释放上一次尝试的节点
+			ReleaseUpstream(c, lb)
+			c.Set("upstream_addr", addr)
+		}}
+	}
+
 	return &httputil.ReverseProxy{
 		Director:       director,
 		ModifyResponse: modifier,
-		Transport:      transport,
+		Transport:      roundTripper,
 		ErrorHandler:   errFunc,
+		//响应体刷新间隔 text/event-stream及未知长度的响应总是每次写入后立即刷新
+		FlushInterval: time.Duration(lib.GetIntConf("proxy.response.flush_interval")) * time.Millisecond,
+	}
+}
+
+// 释放请求使用的上游节点 在反向代理请求结束(包括websocket连接关闭)后调用
+func ReleaseUpstream(c *gin.Context, lb load_balance.LoadBalance) {
+	if addr := c.GetString("upstream_addr"); addr != "" {
+		lb.Done(addr)
+		c.Set("upstream_addr", "")
+	}
+}
+
+// 流式转发时捕获响应体 最多捕获limit字节 读取完毕或关闭时存入上下文payload
+type captureBody struct {
+	io.ReadCloser
+	c      *gin.Context
+	limit  int
+	buffer bytes.Buffer
+	done   bool
+}
+
+func (body *captureBody) Read(p []byte) (int, error) {
+	n, err := body.ReadCloser.Read(p)
+	if remain := body.limit - body.buffer.Len(); remain > 0 && n > 0 {
+		if n < remain {
+			remain = n
+		}
+		body.buffer.Write(p[:remain])
+	}
+	if err != nil {
+		body.save()
+	}
+	return n, err
+}
+
+func (body *captureBody) Close() error {
+	body.save()
+	return body.ReadCloser.Close()
+}
+
+func (body *captureBody) save() {
+	if body.done {
+		return
 	}
+	body.done = true
+	body.c.Set("payload", body.buffer.Bytes())
 }
 
 // 使用/连接可用服务路径和原始请求的路径
@@ -0,0 +1,64 @@
+package reverse_proxy
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"github.com/gin-gonic/gin"
+	"net/http"
+)
+
+// 基于cookie的会话保持：首次响应时下发标识上游节点的cookie 之后携带该cookie的请求转发到同一节点
+// cookie的值为节点地址的hash 不暴露上游地址
+type StickySession struct {
+	Name       string          //cookie名称
+	MaxAge     int             //cookie有效期, 单位s 0表示浏览器关闭前有效
+	Salt       string          //计算cookie值使用的盐 避免不同服务的cookie互相命中
+	ActiveList func() []string //当前可用的节点地址列表
+}
+
+// 节点对应的cookie值
+func (s *StickySession) value(addr string) string {
+	sum := sha256.Sum256([]byte(s.Salt + "|" + CircuitNode(addr)))
+	return hex.EncodeToString(sum[:8])
+}
+
+// 请求cookie对应的可用节点地址 未携带cookie或节点已不可用时返回空
+func (s *StickySession) Node(req *http.Request) string {
+	cookie, err := req.Cookie(s.Name)
+	if err != nil || cookie.Value == "" {
+		return ""
+	}
+	for _, addr := range s.ActiveList() {
+		if s.value(addr) == cookie.Value {
+			return addr
+		}
+	}
+	return ""
+}
+
+// 在响应中下发处理该请求的节点对应的cookie 请求携带的cookie已对应该节点时不重复下发
+func (s *StickySession) SetCookie(res *http.Response) {
+	if res.Request == nil || res.Request.URL.Host == "" {
+		return
+	}
+	value := s.value(res.Request.URL.Host)
+	if cookie, err := res.Request.Cookie(s.Name); err == nil && cookie.Value == value {
+		return
+	}
+	cookie := &http.Cookie{
+		Name:     s.Name,
+		Value:    value,
+		Path:     "/",
+		MaxAge:   s.MaxAge,
+		HttpOnly: true,
+	}
+	res.Header.Add("Set-Cookie", cookie.String())
+}
+
+// 获取上下文中的会话保持配置 服务未开启时返回nil
+func stickySession(c *gin.Context) *StickySession {
+	if sticky, ok := c.Get("sticky_session"); ok {
+		return sticky.(*StickySession)
+	}
+	return nil
+}
@@ -0,0 +1,38 @@
+package reverse_proxy
+
+import (
+	"fmt"
+	"github.com/starMoonZhao/go_gateway/reverse_proxy/load_balance"
+	"net/http"
+	"time"
+)
+
+// 获取负载均衡器的耗时上报接口 负载均衡器不按耗时选择节点时返回nil
+func latencyReporter(lb load_balance.LoadBalance) load_balance.LatencyReporter {
+	if circuitLB, ok := lb.(*circuitLoadBalance); ok {
+		lb = circuitLB.LoadBalance
+	}
+	if reporter, ok := lb.(load_balance.LatencyReporter); ok {
+		return reporter
+	}
+	return nil
+}
+
+// 按http请求耗时上报节点 耗时为请求发出到收到响应头 5xx响应视为失败
+type latencyTransport struct {
+	transport http.RoundTripper
+	reporter  load_balance.LatencyReporter
+}
+
+func (t *latencyTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	start := time.Now()
+	res, err := t.transport.RoundTrip(req)
+	if req.URL.Host != "" {
+		reportErr := err
+		if err == nil && res.StatusCode >= http.StatusInternalServerError {
+			reportErr = fmt.Errorf("upstream status %d", res.StatusCode)
+		}
+		t.reporter.ReportLatency(req.URL.Scheme+"://"+req.URL.Host, time.Since(start), reportErr)
+	}
+	return res, err
+}
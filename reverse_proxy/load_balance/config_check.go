@@ -2,9 +2,11 @@ package load_balance
 
 import (
 	"fmt"
-	"net"
 	"reflect"
 	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
 	"time"
 )
 
@@ -19,8 +21,20 @@ const (
 type LoadBalanceConfigCheck struct {
 	observers    []Observer        //观察者列表
 	confIPWeight map[string]string //权重列表 原始服务列表
-	activeList   []string          //活跃服务列表
+	checkedList  []string          //主动探活可用的服务列表
+	activeList   []string          //活跃服务列表 主动探活可用且未被被动健康检查摘除
 	format       string            //服务格式化字符串
+	closeChan    chan struct{}     //停止探活通道
+	closeOnce    sync.Once
+	checker      *healthChecker            //主动健康检查器
+	outlier      *outlierDetector          //被动健康检查 为nil表示不开启
+	forbidList   []string                  //手动禁用的服务列表 禁用的节点不再分配新请求
+	slowStart    atomic.Int64              //慢启动时间, 单位ns 0表示不开启 原子读写 未开启时选择节点无需加锁
+	activeSince  map[string]time.Time      //慢启动中的节点及其进入活跃服务列表的时间
+	probes       map[string]nodeProbe      //节点最近一次主动探活结果
+	nodeStates   map[string]nodeState      //节点状态及其变化时间
+	groups       []*LoadBalanceConfigCheck //共享本配置主动健康检查的分组配置
+	locker       sync.Mutex
 }
 
 // 向负载均衡配置中注册观察者对象
@@ -28,7 +42,7 @@ func (l *LoadBalanceConfigCheck) Attach(o Observer) {
 	l.observers = append(l.observers, o)
 }
 
-// 返回可用服务列表
+// 返回可用服务列表 由观察者在收到更新通知时调用
 func (l *LoadBalanceConfigCheck) GetConf() []string {
 	confList := []string{}
 	for _, ip := range l.activeList {
@@ -41,72 +55,236 @@ func (l *LoadBalanceConfigCheck) GetConf() []string {
 	return confList
 }
 
+// 当前活跃的服务地址列表 不含权重 可在观察者之外并发调用
+func (l *LoadBalanceConfigCheck) ActiveList() []string {
+	l.locker.Lock()
+	defer l.locker.Unlock()
+	activeList := make([]string, 0, len(l.activeList))
+	for _, ip := range l.activeList {
+		activeList = append(activeList, fmt.Sprintf(l.format, ip))
+	}
+	return activeList
+}
+
 // 监听服务可用性
 func (l *LoadBalanceConfigCheck) WatchConf() {
 	//使用协程不间断的查询服务可用性
 	go func() {
 		confIpErrNum := map[string]int{}
+		confIpSucNum := map[string]int{}
+		unhealthy := map[string]bool{}
 		for {
+			//并发探测所有节点
+			checkResult := map[string]error{}
+			resultLocker := sync.Mutex{}
+			wg := sync.WaitGroup{}
+			for item, _ := range l.confIPWeight {
+				wg.Add(1)
+				go func(item string) {
+					defer wg.Done()
+					err := l.checker.check(item)
+					resultLocker.Lock()
+					checkResult[item] = err
+					resultLocker.Unlock()
+				}(item)
+			}
+			wg.Wait()
+
 			//新的可用服务列表
 			newActiveList := []string{}
 			//遍历原始服务列表
 			for item, _ := range l.confIPWeight {
-				//使用tcp连接探活
-				conn, err := net.DialTimeout("tcp", item, time.Duration(DefaultCheckTimeout)*time.Second)
-				if err != nil {
-					//探测失败 为该服务的错误次数+1
-					if _, ok := confIpErrNum[item]; !ok {
-						confIpErrNum[item] = 1
-					} else {
-						confIpErrNum[item] += 1
+				if checkResult[item] != nil {
+					//探测失败 为该服务的错误次数+1 连续失败达到阈值时摘除
+					confIpErrNum[item]++
+					confIpSucNum[item] = 0
+					if confIpErrNum[item] >= l.checker.conf.UnhealthyThreshold {
+						unhealthy[item] = true
 					}
 				} else {
-					conn.Close()
-					//探测成功 将失败次重置
-					if _, ok := confIpErrNum[item]; ok {
-						confIpErrNum[item] = 0
+					//探测成功 将失败次重置 连续成功达到阈值时恢复
+					confIpErrNum[item] = 0
+					confIpSucNum[item]++
+					if confIpSucNum[item] >= l.checker.conf.HealthyThreshold {
+						unhealthy[item] = false
 					}
 				}
-				//如果错误次数小于最大错误次数 将其添加到可用服务列表中
-				if confIpErrNum[item] < DefaultCheckMaxErrNum {
+				//未被摘除的服务添加到可用服务列表中
+				if !unhealthy[item] {
 					newActiveList = append(newActiveList, item)
 				}
 			}
-			//查看可用服务列表是否发生变化 如发生变化将其更新
-			sort.Strings(l.activeList)
+			//查看可用服务列表是否发生变化 如发生变化将其更新 同时记录各节点的探活结果
+			now := time.Now()
+			probes := map[string]nodeProbe{}
+			for item, err := range checkResult {
+				probes[item] = nodeProbe{time: now, err: err, failures: confIpErrNum[item]}
+			}
+			l.locker.Lock()
+			for item, probe := range probes {
+				l.probes[item] = probe
+			}
+			checkedList := l.checkedList
+			groups := l.groups
+			l.locker.Unlock()
 			sort.Strings(newActiveList)
-			if !reflect.DeepEqual(newActiveList, l.activeList) {
+			if !reflect.DeepEqual(newActiveList, checkedList) {
 				l.UpdateConf(newActiveList)
 			}
+			//分组不单独探活 同步本轮探活结果
+			for _, group := range groups {
+				group.syncCheck(newActiveList, probes)
+			}
 
-			//间隔DefaultCheckInterval时间后继续探活
-			time.Sleep(time.Duration(DefaultCheckInterval) * time.Second)
+			//间隔检查间隔时间后继续探活 收到停止信号时退出
+			select {
+			case <-l.closeChan:
+				return
+			case <-time.After(l.checker.conf.Interval):
+			}
 		}
 	}()
 }
 
+// 停止服务探活
+func (l *LoadBalanceConfigCheck) CloseWatch() {
+	l.closeOnce.Do(func() {
+		close(l.closeChan)
+	})
+}
+
 // 更新配置列表
 func (l *LoadBalanceConfigCheck) UpdateConf(conf []string) {
-	l.activeList = conf
+	l.locker.Lock()
+	defer l.locker.Unlock()
+	l.checkedList = conf
+	l.refresh(true)
+}
+
+// 按主动探活结果及被动健康检查的摘除情况计算活跃服务列表 列表变化或force时通知观察者 调用方需持有locker
+func (l *LoadBalanceConfigCheck) refresh(force bool) {
+	activeList := []string{}
+	now := time.Now()
+	l.updateNodeStates(now)
+	for _, item := range l.checkedList {
+		if NodeForbidden(l.forbidList, item) {
+			continue
+		}
+		if l.outlier == nil || !l.outlier.ejected(item, now) {
+			activeList = append(activeList, item)
+		}
+	}
+	if !force && reflect.DeepEqual(activeList, l.activeList) {
+		return
+	}
+	l.markSlowStart(activeList, now)
+	l.activeList = activeList
 	for _, obverse := range l.observers {
 		//同时通知观察者更新服务
 		obverse.Update()
 	}
 }
 
-// 默认构造器
-func NewLoadBalanceConfigCheck(conf map[string]string, format string) (*LoadBalanceConfigCheck, error) {
+// 设置手动禁用的服务列表 列表项为ip:port或ip 禁用ip时该ip的所有节点都不再分配新请求
+// 进行中的请求及已建立的tcp连接不受影响
+func (l *LoadBalanceConfigCheck) SetForbidList(forbidList []string) {
+	l.locker.Lock()
+	defer l.locker.Unlock()
+	l.forbidList = forbidList
+	l.refresh(false)
+}
+
+// 节点是否在禁用列表中 禁用列表项为ip:port或ip
+func NodeForbidden(forbidList []string, node string) bool {
+	for _, forbid := range forbidList {
+		if forbid == node || (!strings.Contains(forbid, ":") && strings.HasPrefix(node, forbid+":")) {
+			return true
+		}
+	}
+	return false
+}
+
+// 默认构造器 按check配置主动探测服务活性
+func NewLoadBalanceConfigCheck(conf map[string]string, format string, check CheckConf) (*LoadBalanceConfigCheck, error) {
 	//将原始服务列表直接设置为可用服务列表
 	activeList := []string{}
 	for item, _ := range conf {
 		activeList = append(activeList, item)
 	}
+	sort.Strings(activeList)
 	loadBalanceConfig := &LoadBalanceConfigCheck{
 		format:       format,
 		confIPWeight: conf,
+		checkedList:  activeList,
 		activeList:   activeList,
+		activeSince:  map[string]time.Time{},
+		probes:       map[string]nodeProbe{},
+		nodeStates:   map[string]nodeState{},
+		closeChan:    make(chan struct{}),
+	}
+	//http检查按服务格式化字符串中的协议发送请求
+	scheme := "http"
+	if strings.HasPrefix(format, "https://") {
+		scheme = "https"
 	}
+	loadBalanceConfig.checker = newHealthChecker(check, scheme)
+	loadBalanceConfig.updateNodeStates(time.Now())
 	//开启负载均衡配置的服务探活
 	loadBalanceConfig.WatchConf()
 	return loadBalanceConfig, nil
 }
+
+// 生成分组的负载均衡配置：分组节点为服务节点的子集 与服务共享同一个主动健康检查 不单独探活
+// 被动健康检查、禁用列表及慢启动仍由分组配置各自维护
+func (l *LoadBalanceConfigCheck) NewGroupConfigCheck(conf map[string]string) *LoadBalanceConfigCheck {
+	l.locker.Lock()
+	defer l.locker.Unlock()
+	checkedList := []string{}
+	probes := map[string]nodeProbe{}
+	for _, item := range l.checkedList {
+		if _, ok := conf[item]; ok {
+			checkedList = append(checkedList, item)
+		}
+	}
+	for item := range conf {
+		if probe, ok := l.probes[item]; ok {
+			probes[item] = probe
+		}
+	}
+	group := &LoadBalanceConfigCheck{
+		format:       l.format,
+		confIPWeight: conf,
+		checkedList:  checkedList,
+		activeList:   checkedList,
+		activeSince:  map[string]time.Time{},
+		probes:       probes,
+		nodeStates:   map[string]nodeState{},
+		closeChan:    make(chan struct{}),
+		checker:      l.checker,
+	}
+	group.updateNodeStates(time.Now())
+	l.groups = append(l.groups, group)
+	return group
+}
+
+// 同步服务的主动探活结果 checkedList为服务探活可用的节点 probes为服务各节点的探活结果
+func (l *LoadBalanceConfigCheck) syncCheck(checkedList []string, probes map[string]nodeProbe) {
+	groupCheckedList := []string{}
+	for _, item := range checkedList {
+		if _, ok := l.confIPWeight[item]; ok {
+			groupCheckedList = append(groupCheckedList, item)
+		}
+	}
+	l.locker.Lock()
+	defer l.locker.Unlock()
+	for item := range l.confIPWeight {
+		if probe, ok := probes[item]; ok {
+			l.probes[item] = probe
+		}
+	}
+	if reflect.DeepEqual(groupCheckedList, l.checkedList) {
+		return
+	}
+	l.checkedList = groupCheckedList
+	l.refresh(true)
+}
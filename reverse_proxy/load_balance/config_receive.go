@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"github.com/starMoonZhao/go_gateway/reverse_proxy/zookeeper"
 	"log"
+	"sync"
 )
 
 // 负载均衡可用服务配置：用于zk服务注册查看服务活性
@@ -15,6 +16,8 @@ type LoadBalanceConfigZk struct {
 	confIPWeight map[string]string
 	activeList   []string
 	format       string
+	closeChan    chan struct{} //停止监听通道
+	closeOnce    sync.Once
 }
 
 // 向负载均衡配置中注册观察者对象
@@ -48,6 +51,8 @@ func (l *LoadBalanceConfigZk) WatchConf() {
 		for {
 			//读取通道中的更新列表或错误信息
 			select {
+			case <-l.closeChan:
+				return
 			case err := <-chanErr:
 				log.Printf("zk change err:%v\n", err)
 			case changeList := <-chanList:
@@ -58,6 +63,13 @@ func (l *LoadBalanceConfigZk) WatchConf() {
 	}()
 }
 
+// 停止监听zk服务列表
+func (l *LoadBalanceConfigZk) CloseWatch() {
+	l.closeOnce.Do(func() {
+		close(l.closeChan)
+	})
+}
+
 // 更新配置列表
 func (l *LoadBalanceConfigZk) UpdateConf(conf []string) {
 	l.activeList = conf
@@ -83,6 +95,7 @@ func NewLoadBalanceConfigZk(format, path string, zkHosts []string, conf map[stri
 		zkHosts:      zkHosts,
 		confIPWeight: conf,
 		activeList:   activeList,
+		closeChan:    make(chan struct{}),
 	}
 	//开启负载均衡配置的服务探活
 	loadBalanceConfigZk.WatchConf()
@@ -8,6 +8,8 @@ const (
 	LbRoundRobin
 	LbWeightRoundRobin
 	LbConsistentHash
+	LbLeastConn
+	LbP2C
 )
 
 // 获取指定策略的负载均衡器
@@ -20,14 +22,29 @@ func LoadBalanceFactory(lbType LbType) LoadBalance {
 	case LbWeightRoundRobin:
 		return &WeightRoundRobinBalance{}
 	case LbConsistentHash:
-		return &ConsistentHashBalance{}
+		return NewConsistentHashBalance(DefaultHashReplicas, nil)
+	case LbLeastConn:
+		return NewLeastConnBalance()
+	case LbP2C:
+		return NewP2CBalance()
 	default:
 		return &RandomBalance{}
 	}
 }
 
+// 负载均衡器的可选参数
+type LoadBalanceOptions struct {
+	HashReplicas   int     //一致性hash每个节点的虚拟节点数 0表示使用默认值
+	HashLoadFactor float64 //一致性hash有界负载系数 0表示不限制节点负载
+}
+
 // 获取指定策略的负载均衡器 同时根据传入配置初始化负载均衡器
 func LoadBalanceFactoryWithConf(lbType LbType, conf LoadBalanceConf) LoadBalance {
+	return LoadBalanceFactoryWithOptions(lbType, conf, LoadBalanceOptions{})
+}
+
+// 获取指定策略的负载均衡器 根据传入配置及可选参数初始化负载均衡器
+func LoadBalanceFactoryWithOptions(lbType LbType, conf LoadBalanceConf, options LoadBalanceOptions) LoadBalance {
 	switch lbType {
 	case LbRandom:
 		lb := &RandomBalance{}
@@ -51,7 +68,20 @@ func LoadBalanceFactoryWithConf(lbType LbType, conf LoadBalanceConf) LoadBalance
 		lb.Update()
 		return lb
 	case LbConsistentHash:
-		lb := NewConsistentHashBalance(10, nil)
+		lb := NewConsistentHashBalance(options.HashReplicas, nil)
+		lb.SetLoadFactor(options.HashLoadFactor)
+		lb.SetConf(conf)
+		conf.Attach(lb)
+		lb.Update()
+		return lb
+	case LbLeastConn:
+		lb := NewLeastConnBalance()
+		lb.SetConf(conf)
+		conf.Attach(lb)
+		lb.Update()
+		return lb
+	case LbP2C:
+		lb := NewP2CBalance()
 		lb.SetConf(conf)
 		conf.Attach(lb)
 		lb.Update()
@@ -0,0 +1,162 @@
+package load_balance
+
+import (
+	"context"
+	"errors"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/health/grpc_health_v1"
+	"io"
+	"net"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// 主动健康检查方式
+const (
+	CheckMethodTCP  = 0 //检测端口是否握手成功
+	CheckMethodHTTP = 1 //发送http请求 检测响应状态码及响应体
+	CheckMethodGRPC = 2 //调用grpc.health.v1.Health/Check 仅用于grpc服务
+)
+
+// http健康检查最多读取的响应体大小
+const checkMaxBodySize = 64 << 10
+
+// 主动健康检查配置
+type CheckConf struct {
+	Method             int           //检查方式
+	Timeout            time.Duration //单次检查超时时间
+	Interval           time.Duration //检查间隔
+	HealthyThreshold   int           //不可用节点连续检查成功达到该次数时恢复
+	UnhealthyThreshold int           //可用节点连续检查失败达到该次数时摘除
+	HTTPPath           string        //http检查路径
+	HTTPHost           string        //http检查使用的Host请求头 为空时使用节点地址
+	StatusMin          int           //http检查正常状态码的最小值
+	StatusMax          int           //http检查正常状态码的最大值
+	BodyMatch          string        //http检查响应体需包含的内容 为空表示不检查响应体
+	GRPCService        string        //grpc检查的服务名称 为空表示检查节点整体状态
+}
+
+// 填充健康检查配置默认值
+func fillCheckConf(conf CheckConf) CheckConf {
+	if conf.Timeout <= 0 {
+		conf.Timeout = time.Duration(DefaultCheckTimeout) * time.Second
+	}
+	if conf.Interval <= 0 {
+		conf.Interval = time.Duration(DefaultCheckInterval) * time.Second
+	}
+	if conf.HealthyThreshold <= 0 {
+		conf.HealthyThreshold = 1
+	}
+	if conf.UnhealthyThreshold <= 0 {
+		conf.UnhealthyThreshold = DefaultCheckMaxErrNum
+	}
+	if conf.HTTPPath == "" {
+		conf.HTTPPath = "/"
+	}
+	if conf.StatusMin <= 0 || conf.StatusMax < conf.StatusMin {
+		conf.StatusMin, conf.StatusMax = http.StatusOK, 399
+	}
+	return conf
+}
+
+// 主动健康检查器：按配置的检查方式探测单个节点
+type healthChecker struct {
+	conf   CheckConf
+	scheme string
+	client *http.Client
+}
+
+// scheme为http检查使用的协议 http或https
+func newHealthChecker(conf CheckConf, scheme string) *healthChecker {
+	conf = fillCheckConf(conf)
+	return &healthChecker{
+		conf:   conf,
+		scheme: scheme,
+		client: &http.Client{
+			Timeout: conf.Timeout,
+			//不跟随跳转 以节点自身的响应状态码判断
+			CheckRedirect: func(req *http.Request, via []*http.Request) error {
+				return http.ErrUseLastResponse
+			},
+			Transport: &http.Transport{DisableKeepAlives: true},
+		},
+	}
+}
+
+// 探测节点 返回nil表示节点可用
+func (checker *healthChecker) check(node string) error {
+	switch checker.conf.Method {
+	case CheckMethodHTTP:
+		return checker.checkHTTP(node)
+	case CheckMethodGRPC:
+		return checker.checkGRPC(node)
+	default:
+		return checker.checkTCP(node)
+	}
+}
+
+// 使用tcp连接探活
+func (checker *healthChecker) checkTCP(node string) error {
+	conn, err := net.DialTimeout("tcp", node, checker.conf.Timeout)
+	if err != nil {
+		return err
+	}
+	return conn.Close()
+}
+
+// 发送http请求探活 响应状态码在正常范围内且响应体包含指定内容时视为可用
+func (checker *healthChecker) checkHTTP(node string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), checker.conf.Timeout)
+	defer cancel()
+	path := checker.conf.HTTPPath
+	if !strings.HasPrefix(path, "/") {
+		path = "/" + path
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, checker.scheme+"://"+node+path, nil)
+	if err != nil {
+		return err
+	}
+	if checker.conf.HTTPHost != "" {
+		req.Host = checker.conf.HTTPHost
+	}
+	req.Header.Set("User-Agent", "go_gateway-health-check")
+	res, err := checker.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+	if res.StatusCode < checker.conf.StatusMin || res.StatusCode > checker.conf.StatusMax {
+		return errors.New("unexpected status: " + res.Status)
+	}
+	if checker.conf.BodyMatch == "" {
+		return nil
+	}
+	body, err := io.ReadAll(io.LimitReader(res.Body, checkMaxBodySize))
+	if err != nil {
+		return err
+	}
+	if !strings.Contains(string(body), checker.conf.BodyMatch) {
+		return errors.New("response body not match")
+	}
+	return nil
+}
+
+// 按grpc健康检查协议探活 只有SERVING视为可用 NOT_SERVING表示节点在线但不再接收流量(如下线排空中)
+func (checker *healthChecker) checkGRPC(node string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), checker.conf.Timeout)
+	defer cancel()
+	conn, err := grpc.DialContext(ctx, node, grpc.WithInsecure(), grpc.WithBlock())
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	res, err := grpc_health_v1.NewHealthClient(conn).Check(ctx, &grpc_health_v1.HealthCheckRequest{Service: checker.conf.GRPCService})
+	if err != nil {
+		return err
+	}
+	if res.Status != grpc_health_v1.HealthCheckResponse_SERVING {
+		return errors.New("unexpected grpc health status: " + res.Status.String())
+	}
+	return nil
+}
@@ -1,5 +1,7 @@
 package load_balance
 
+import "time"
+
 // 观察者接口:负载均衡器会实现该接口中的Update方法
 // LoadBalance作为观察者嵌入LoadBalanceConf对象中，当LoadBalanceConf配置发生变化时调用Update方法使LoadBalance同步配置
 type Observer interface {
@@ -12,11 +14,19 @@ type LoadBalanceConf interface {
 	GetConf() []string   //读取该负载均衡配置对象的配置列表
 	WatchConf()          //监听服务列表的可用情况 实时更新负载均衡配置
 	UpdateConf([]string) //更新负载均衡配置
+	CloseWatch()         //停止监听服务列表
 }
 
 // 负载均衡器
 type LoadBalance interface {
 	Add(params ...string) error   //添加服务
 	Get(string) (string, error)   //获取服务配置列表
+	GetNode(addr string) error    //直接使用指定的服务(如会话保持) 服务不在当前列表中时返回错误 成功时与Done成对调用
+	Done(addr string)             //通过Get获取的服务使用结束(请求完成或连接关闭) 与Get成对调用
 	SetConf(conf LoadBalanceConf) //设置负载均衡配置
 }
+
+// 按节点请求耗时选择节点的负载均衡器实现该接口 由反向代理上报每次请求的耗时
+type LatencyReporter interface {
+	ReportLatency(addr string, latency time.Duration, err error)
+}
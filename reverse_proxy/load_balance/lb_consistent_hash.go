@@ -4,12 +4,16 @@ import (
 	"github.com/pkg/errors"
 	"hash/crc32"
 	"log"
+	"math"
 	"sort"
 	"strconv"
 	"strings"
 	"sync"
 )
 
+// 默认复制因子 每个节点在hash环上的虚拟节点数
+const DefaultHashReplicas = 10
+
 // hash函数
 type Hash func(data []byte) uint32
 
@@ -29,12 +33,16 @@ func (s UInt32Slice) Less(i, j int) bool {
 
 // 哈希连续的负载均衡器
 type ConsistentHashBalance struct {
-	conf     LoadBalanceConf   //被观察主体
-	mutex    sync.RWMutex      //读写锁
-	hash     Hash              //hash函数
-	replicas int               //复制因子
-	keys     UInt32Slice       //已排序的节点hash切片
-	hashMap  map[uint32]string //节点hash和key的map，键是hash值，值是key
+	conf       LoadBalanceConf   //被观察主体
+	mutex      sync.RWMutex      //读写锁
+	hash       Hash              //hash函数
+	replicas   int               //复制因子
+	keys       UInt32Slice       //已排序的节点hash切片
+	hashMap    map[uint32]string //节点hash和key的map，键是hash值，值是key
+	nodeNum    int               //节点数
+	loadFactor float64           //有界负载系数 节点负载上限为平均负载*(1+loadFactor) 0表示不限制
+	loads      map[string]int    //节点进行中的请求数 开启有界负载时统计
+	totalLoad  int               //所有节点进行中的请求数
 }
 
 // 哈希负载均衡器生成
@@ -43,14 +51,25 @@ func NewConsistentHashBalance(replicas int, fn Hash) *ConsistentHashBalance {
 		//键最多32位 保证是一个2^32-1的环
 		fn = crc32.ChecksumIEEE
 	}
+	if replicas <= 0 {
+		replicas = DefaultHashReplicas
+	}
 	c := &ConsistentHashBalance{
 		hash:     fn,
 		replicas: replicas,
 		hashMap:  make(map[uint32]string),
+		loads:    make(map[string]int),
 	}
 	return c
 }
 
+// 开启有界负载：节点进行中的请求数达到上限时顺着hash环选择下一个节点 避免热点key压垮单个节点
+func (c *ConsistentHashBalance) SetLoadFactor(loadFactor float64) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	c.loadFactor = loadFactor
+}
+
 // 用来添加缓存节点，参数位节点key，比如使用ip
 func (c *ConsistentHashBalance) Add(params ...string) error {
 	if len(params) == 0 {
@@ -59,6 +78,7 @@ func (c *ConsistentHashBalance) Add(params ...string) error {
 	addr := params[0]
 	c.mutex.Lock()
 	defer c.mutex.Unlock()
+	c.nodeNum++
 	//结合复制因子计算所有虚拟节点的hash值，并存入c.keys中，同时在c.hashMap中保存hash值和key的映射
 	for i := 0; i < c.replicas; i++ {
 		hash := c.hash([]byte(strconv.Itoa(i) + addr))
@@ -73,6 +93,8 @@ func (c *ConsistentHashBalance) Add(params ...string) error {
 
 // 根据给定的对象获取最靠近它的那个节点
 func (c *ConsistentHashBalance) Get(key string) (string, error) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
 	if len(c.keys) == 0 {
 		return "", errors.New("keys is empty")
 	}
@@ -84,20 +106,75 @@ func (c *ConsistentHashBalance) Get(key string) (string, error) {
 	if index >= len(c.keys) {
 		index = 0
 	}
-	c.mutex.RLock()
-	defer c.mutex.RUnlock()
-	return c.hashMap[c.keys[index]], nil
+	if c.loadFactor <= 0 {
+		return c.hashMap[c.keys[index]], nil
+	}
+
+	//有界负载：跳过进行中的请求数达到上限的节点
+	capacity := int(math.Ceil(float64(c.totalLoad+1) * (1 + c.loadFactor) / float64(c.nodeNum)))
+	addr := c.hashMap[c.keys[index]]
+	for i := 0; i < len(c.keys); i++ {
+		nextAddr := c.hashMap[c.keys[(index+i)%len(c.keys)]]
+		if c.loads[nextAddr] < capacity {
+			addr = nextAddr
+			break
+		}
+	}
+	c.loads[addr]++
+	c.totalLoad++
+	return addr, nil
+}
+
+// 直接使用指定节点 开启有界负载时节点进行中的请求数+1
+func (c *ConsistentHashBalance) GetNode(addr string) error {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	for _, item := range c.hashMap {
+		if item == addr {
+			if c.loadFactor > 0 {
+				c.loads[addr]++
+				c.totalLoad++
+			}
+			return nil
+		}
+	}
+	return errors.New("node not found")
+}
+
+// 开启有界负载时 节点进行中的请求数-1
+func (c *ConsistentHashBalance) Done(addr string) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	if c.loads[addr] > 0 {
+		c.loads[addr]--
+		c.totalLoad--
+	}
 }
 
 func (c *ConsistentHashBalance) SetConf(conf LoadBalanceConf) {
 	c.conf = conf
 }
 
+// 按负载均衡配置重建hash环 新的hash环在锁外生成并只排序一次 再加锁整体替换 更新期间Get不会取到空的或不完整的hash环
+// 开启有界负载时 保留的节点沿用原有的进行中的请求数
 func (c *ConsistentHashBalance) Update() {
-	log.Printf("Update get conf:%v\n", c.conf)
-	c.keys = nil
-	c.hashMap = make(map[uint32]string)
-	for _, ip := range c.conf.GetConf() {
-		c.Add(strings.Split(ip, ",")...)
+	conf := c.conf.GetConf()
+	log.Printf("Update get conf:%v\n", conf)
+	keys := UInt32Slice{}
+	hashMap := make(map[uint32]string)
+	for _, ip := range conf {
+		addr := strings.Split(ip, ",")[0]
+		//hash函数及复制因子创建后不再变化 可在锁外计算
+		for i := 0; i < c.replicas; i++ {
+			hash := c.hash([]byte(strconv.Itoa(i) + addr))
+			keys = append(keys, hash)
+			hashMap[hash] = addr
+		}
 	}
+	sort.Sort(keys)
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	c.keys = keys
+	c.hashMap = hashMap
+	c.nodeNum = len(conf)
 }
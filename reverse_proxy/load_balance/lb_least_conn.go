@@ -0,0 +1,136 @@
+package load_balance
+
+import (
+	"github.com/pkg/errors"
+	"log"
+	"math/rand"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// 最少活跃连接的负载均衡器：选择活跃连接数与权重之比最小的节点
+// Get时节点活跃连接数+1 请求或连接结束调用Done时-1 适用于websocket、tcp及grpc流等长连接
+type LeastConnBalance struct {
+	conf   LoadBalanceConf  //被观察主体
+	mutex  sync.Mutex       //锁
+	nodes  []*LeastConnNode //当前负载均衡器可用的服务列表
+	active map[string]int   //节点活跃连接数 服务列表更新后保留
+	next   int              //活跃连接数相同时轮流选择的起始下标
+}
+
+// 最少连接节点
+type LeastConnNode struct {
+	addr   string //服务地址
+	weight int    //服务权重
+}
+
+func NewLeastConnBalance() *LeastConnBalance {
+	return &LeastConnBalance{
+		active: map[string]int{},
+	}
+}
+
+// 手动添加可用服务
+// param1:地址；param2：权重 不传时权重为1
+func (l *LeastConnBalance) Add(params ...string) error {
+	node, err := newLeastConnNode(params...)
+	if err != nil {
+		return err
+	}
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	l.nodes = append(l.nodes, node)
+	return nil
+}
+
+// 按地址及权重生成节点 权重不传或不大于0时为1
+func newLeastConnNode(params ...string) (*LeastConnNode, error) {
+	if len(params) == 0 {
+		return nil, errors.New("params len 1 at least")
+	}
+	weight := 1
+	if len(params) > 1 {
+		w, err := strconv.Atoi(params[1])
+		if err != nil {
+			return nil, err
+		}
+		if w > 0 {
+			weight = w
+		}
+	}
+	return &LeastConnNode{addr: params[0], weight: weight}, nil
+}
+
+// 比较active/weight 使用交叉相乘避免浮点运算
+// 慢启动中的节点按权重系数的概率参与选择 空闲时同样只分到部分流量
+func (l *LeastConnBalance) Get(key string) (string, error) {
+	//在加锁前获取慢启动系数 避免与更新服务列表时的锁顺序相反
+	factors := slowStartFactors(l.conf)
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	if len(l.nodes) == 0 {
+		return "", errors.New("nodes is empty")
+	}
+	var bestNode *LeastConnNode
+	for i := 0; i < len(l.nodes); i++ {
+		node := l.nodes[(l.next+i)%len(l.nodes)]
+		if factor := slowStartFactor(factors, node.addr); factor < 1 && rand.Float64() >= factor {
+			continue
+		}
+		if bestNode == nil || l.active[node.addr]*bestNode.weight < l.active[bestNode.addr]*node.weight {
+			bestNode = node
+		}
+	}
+	//所有节点都在慢启动中且均未参与选择时 按下标轮流选择
+	if bestNode == nil {
+		bestNode = l.nodes[l.next%len(l.nodes)]
+	}
+	l.next = (l.next + 1) % len(l.nodes)
+	l.active[bestNode.addr]++
+	return bestNode.addr, nil
+}
+
+// 直接使用指定节点 节点活跃连接数+1
+func (l *LeastConnBalance) GetNode(addr string) error {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	for _, node := range l.nodes {
+		if node.addr == addr {
+			l.active[addr]++
+			return nil
+		}
+	}
+	return errors.New("node not found")
+}
+
+// 请求或连接结束 节点活跃连接数-1
+func (l *LeastConnBalance) Done(addr string) {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	if l.active[addr] > 0 {
+		l.active[addr]--
+	}
+}
+
+func (l *LeastConnBalance) SetConf(conf LoadBalanceConf) {
+	l.conf = conf
+}
+
+// 按负载均衡配置重建服务列表 新列表在锁外生成后整体替换 更新期间Get不会取到空的或不完整的服务列表
+// 节点活跃连接数按地址保存在active中 保留的节点沿用原有的活跃连接数
+func (l *LeastConnBalance) Update() {
+	conf := l.conf.GetConf()
+	log.Printf("Update get conf:%v\n", conf)
+	nodes := []*LeastConnNode{}
+	for _, ip := range conf {
+		node, err := newLeastConnNode(strings.Split(ip, ",")...)
+		if err != nil {
+			continue
+		}
+		nodes = append(nodes, node)
+	}
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	l.nodes = nodes
+}
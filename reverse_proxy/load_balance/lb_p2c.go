@@ -0,0 +1,202 @@
+package load_balance
+
+import (
+	"github.com/pkg/errors"
+	"log"
+	"math"
+	"math/rand"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	p2cDecayTime    = 10 * time.Second //ewma衰减时间 距上次采样越久 新样本的权重越大
+	p2cErrorPenalty = time.Second      //请求失败时按该耗时计入ewma
+	p2cBaseLatency  = time.Millisecond //评分时计入的基础耗时 尚未采样或耗时衰减至0的节点仍按进行中的请求数比较
+)
+
+// 节点评分 用于管理后台展示
+type P2CNodeStat struct {
+	Addr     string  `json:"addr"`
+	Latency  float64 `json:"latency"`  //ewma耗时, 单位ms
+	Inflight int     `json:"inflight"` //进行中的请求数
+	Score    float64 `json:"score"`    //评分 (ewma耗时+基础耗时)*(进行中的请求数+1) 越小越优先
+}
+
+// 基于耗时的两次随机选择负载均衡器(P2C+EWMA)
+// 随机选取两个节点 选择(ewma耗时+基础耗时)*(进行中的请求数+1)较小的节点 耗时由反向代理通过ReportLatency上报
+type P2CBalance struct {
+	conf  LoadBalanceConf     //被观察主体
+	mutex sync.Mutex          //锁
+	addrs []string            //当前负载均衡器可用的服务列表
+	stats map[string]*p2cStat //节点统计 服务列表更新后保留
+}
+
+type p2cStat struct {
+	ewma     float64   //ewma耗时, 单位ns 0表示尚未采样
+	inflight int       //进行中的请求数
+	lastAt   time.Time //上次采样时间
+}
+
+func NewP2CBalance() *P2CBalance {
+	return &P2CBalance{
+		stats: map[string]*p2cStat{},
+	}
+}
+
+// 手动添加可用服务 param1:地址
+func (p *P2CBalance) Add(params ...string) error {
+	if len(params) == 0 {
+		return errors.New("params len 1 at least")
+	}
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	addr := params[0]
+	p.addrs = append(p.addrs, addr)
+	if _, ok := p.stats[addr]; !ok {
+		//新节点使用已有节点的平均耗时 避免尚未采样的节点瞬间承接全部流量
+		p.stats[addr] = &p2cStat{ewma: p.averageEWMA()}
+	}
+	return nil
+}
+
+func (p *P2CBalance) Get(key string) (string, error) {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	if len(p.addrs) == 0 {
+		return "", errors.New("addrs is empty")
+	}
+	best := p.addrs[0]
+	if len(p.addrs) > 1 {
+		i := rand.Intn(len(p.addrs))
+		j := rand.Intn(len(p.addrs) - 1)
+		if j >= i {
+			j++
+		}
+		best = p.addrs[i]
+		if p.score(p.addrs[j]) < p.score(best) {
+			best = p.addrs[j]
+		}
+	}
+	p.stats[best].inflight++
+	return best, nil
+}
+
+// 直接使用指定节点 节点进行中的请求数+1
+func (p *P2CBalance) GetNode(addr string) error {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	for _, item := range p.addrs {
+		if item == addr {
+			p.stats[addr].inflight++
+			return nil
+		}
+	}
+	return errors.New("node not found")
+}
+
+// 请求或连接结束 节点进行中的请求数-1
+func (p *P2CBalance) Done(addr string) {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	if stat, ok := p.stats[addr]; ok && stat.inflight > 0 {
+		stat.inflight--
+	}
+}
+
+// 上报节点的请求耗时 err不为nil时按失败惩罚耗时计入
+func (p *P2CBalance) ReportLatency(addr string, latency time.Duration, err error) {
+	if err != nil && latency < p2cErrorPenalty {
+		latency = p2cErrorPenalty
+	}
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	stat, ok := p.stats[addr]
+	if !ok {
+		return
+	}
+	now := time.Now()
+	if stat.ewma == 0 || stat.lastAt.IsZero() {
+		stat.ewma = float64(latency)
+	} else {
+		//按距上次采样的时间衰减 w越小新样本权重越大
+		w := math.Exp(-float64(now.Sub(stat.lastAt)) / float64(p2cDecayTime))
+		stat.ewma = stat.ewma*w + float64(latency)*(1-w)
+	}
+	stat.lastAt = now
+}
+
+// 各节点的评分
+func (p *P2CBalance) Stats() []P2CNodeStat {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	statList := []P2CNodeStat{}
+	for _, addr := range p.addrs {
+		stat := p.stats[addr]
+		statList = append(statList, P2CNodeStat{
+			Addr:     addr,
+			Latency:  stat.ewma / float64(time.Millisecond),
+			Inflight: stat.inflight,
+			Score:    p.score(addr) / float64(time.Millisecond),
+		})
+	}
+	sort.Slice(statList, func(i, j int) bool {
+		return statList[i].Addr < statList[j].Addr
+	})
+	return statList
+}
+
+// 节点评分 调用方需持有锁
+// 长时间未被选中的节点ewma耗时随时间衰减 使耗时恢复正常的节点能重新获得流量
+func (p *P2CBalance) score(addr string) float64 {
+	stat := p.stats[addr]
+	ewma := stat.ewma
+	if !stat.lastAt.IsZero() {
+		if idle := time.Since(stat.lastAt); idle > p2cDecayTime {
+			ewma *= math.Exp(-float64(idle-p2cDecayTime) / float64(p2cDecayTime))
+		}
+	}
+	return (ewma + float64(p2cBaseLatency)) * float64(stat.inflight+1)
+}
+
+// 已采样节点的平均耗时 调用方需持有锁
+func (p *P2CBalance) averageEWMA() float64 {
+	total, num := 0.0, 0
+	for _, stat := range p.stats {
+		if stat.ewma > 0 {
+			total += stat.ewma
+			num++
+		}
+	}
+	if num == 0 {
+		return 0
+	}
+	return total / float64(num)
+}
+
+func (p *P2CBalance) SetConf(conf LoadBalanceConf) {
+	p.conf = conf
+}
+
+// 按负载均衡配置重建服务列表 新列表在锁外生成后加锁整体替换 更新期间Get不会取到空的或不完整的服务列表
+// 保留的节点沿用原有的ewma耗时及进行中的请求数
+func (p *P2CBalance) Update() {
+	conf := p.conf.GetConf()
+	log.Printf("Update get conf:%v\n", conf)
+	addrs := []string{}
+	for _, ip := range conf {
+		addrs = append(addrs, strings.Split(ip, ",")[0])
+	}
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	//新节点使用已有节点的平均耗时 避免尚未采样的节点瞬间承接全部流量
+	averageEWMA := p.averageEWMA()
+	for _, addr := range addrs {
+		if _, ok := p.stats[addr]; !ok {
+			p.stats[addr] = &p2cStat{ewma: averageEWMA}
+		}
+	}
+	p.addrs = addrs
+}
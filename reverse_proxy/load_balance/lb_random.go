@@ -26,11 +26,38 @@ func (r *RandomBalance) Get(key string) (string, error) {
 	if len(r.css) == 0 {
 		return "", errors.New("css is empty")
 	}
+	//慢启动中的节点按权重系数降低被选中的概率
+	if factors := slowStartFactors(r.conf); factors != nil {
+		total := 0.0
+		for _, addr := range r.css {
+			total += slowStartFactor(factors, addr)
+		}
+		pick := rand.Float64() * total
+		for index, addr := range r.css {
+			pick -= slowStartFactor(factors, addr)
+			if pick < 0 {
+				r.curIndex = index
+				return addr, nil
+			}
+		}
+	}
 	//使用随机数获取可用服务下标
 	r.curIndex = rand.Intn(len(r.css))
 	return r.css[r.curIndex], nil
 }
 
+func (r *RandomBalance) GetNode(addr string) error {
+	for _, item := range r.css {
+		if item == addr {
+			return nil
+		}
+	}
+	return errors.New("node not found")
+}
+
+// 不统计节点的使用情况
+func (r *RandomBalance) Done(addr string) {}
+
 func (r *RandomBalance) SetConf(conf LoadBalanceConf) {
 	r.conf = conf
 }
@@ -39,7 +66,7 @@ func (r *RandomBalance) SetConf(conf LoadBalanceConf) {
 // 当LoadBalanceConf发生变化时，会调用所有观察者的所有Update方法同步变化
 // 负载均衡器将变化同步到服务列表中
 func (r *RandomBalance) Update() {
-	log.Printf("Update get conf:%v\n", r.conf)
+	log.Printf("Update get conf:%v\n", r.conf.GetConf())
 	r.css = []string{}
 	for _, ip := range r.conf.GetConf() {
 		r.Add(strings.Split(ip, ",")...)
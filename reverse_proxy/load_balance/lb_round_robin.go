@@ -33,12 +33,24 @@ func (r *RoundRobinBalance) Get(key string) (string, error) {
 	return curAddr, nil
 }
 
+func (r *RoundRobinBalance) GetNode(addr string) error {
+	for _, item := range r.css {
+		if item == addr {
+			return nil
+		}
+	}
+	return errors.New("node not found")
+}
+
+// 不统计节点的使用情况
+func (r *RoundRobinBalance) Done(addr string) {}
+
 func (r *RoundRobinBalance) SetConf(conf LoadBalanceConf) {
 	r.conf = conf
 }
 
 func (r *RoundRobinBalance) Update() {
-	log.Printf("Update get conf:%v\n", r.conf)
+	log.Printf("Update get conf:%v\n", r.conf.GetConf())
 	r.css = []string{}
 	for _, ip := range r.conf.GetConf() {
 		r.Add(strings.Split(ip, ",")...)
@@ -3,6 +3,7 @@ package load_balance
 import (
 	"github.com/pkg/errors"
 	"log"
+	"math"
 	"strconv"
 	"strings"
 )
@@ -44,21 +45,22 @@ func (w *WeightRoundRobinBalance) Add(params ...string) error {
 
 // 设计思想：根据每个节点的权重值统计出总的权重值->获取当前权重值最大的节点->使用->减去总的权重值->每一巡每个节点加上节点自身的权重
 func (w *WeightRoundRobinBalance) Get(key string) (string, error) {
+	//慢启动中节点的权重系数
+	factors := slowStartFactors(w.conf)
 	//总的权重值
 	totalWeight := 0
 	//当前权重最大节点
 	var bestNode *WeightNode
 	for _, node := range w.rss {
-		//step1: 统计所有权重之和
-		totalWeight += node.weight
+		//step1: 有效权重默认与权重相同，慢启动中的节点按权重系数从较小的权重逐步恢复到weight大小
+		node.effectiveWeight = int(math.Ceil(float64(node.weight) * slowStartFactor(factors, node.addr)))
 
-		//step2: 变更节点临时权重为临时权重+有效权重
+		//step2: 统计所有有效权重之和
+		totalWeight += node.effectiveWeight
+
+		//step3: 变更节点临时权重为临时权重+有效权重
 		node.currentWeight += node.effectiveWeight
 
-		//step3: 有效权重默认与权重相同，通讯异常时-1, 通讯成功+1，直到恢复到weight大小
-		if node.effectiveWeight < node.weight {
-			node.effectiveWeight++
-		}
 		//step4: 找出临时权重最大的节点
 		if bestNode == nil || node.currentWeight > bestNode.currentWeight {
 			bestNode = node
@@ -72,12 +74,24 @@ func (w *WeightRoundRobinBalance) Get(key string) (string, error) {
 	return bestNode.addr, nil
 }
 
+func (w *WeightRoundRobinBalance) GetNode(addr string) error {
+	for _, node := range w.rss {
+		if node.addr == addr {
+			return nil
+		}
+	}
+	return errors.New("node not found")
+}
+
+// 不统计节点的使用情况
+func (w *WeightRoundRobinBalance) Done(addr string) {}
+
 func (w *WeightRoundRobinBalance) SetConf(conf LoadBalanceConf) {
 	w.conf = conf
 }
 
 func (w *WeightRoundRobinBalance) Update() {
-	log.Printf("Update get conf:%v\n", w.conf)
+	log.Printf("Update get conf:%v\n", w.conf.GetConf())
 	w.rss = nil
 	for _, ip := range w.conf.GetConf() {
 		w.Add(strings.Split(ip, ",")...)
@@ -0,0 +1,128 @@
+package load_balance
+
+import (
+	"sort"
+	"time"
+)
+
+// 节点状态 按优先级从高到低：手动摘除、主动探活摘除、被动健康检查摘除、可用
+const (
+	NodeStateDrained   = "drained"   //手动摘除
+	NodeStateUnhealthy = "unhealthy" //主动探活连续失败摘除
+	NodeStateEjected   = "ejected"   //被动健康检查按真实请求结果摘除
+	NodeStateHealthy   = "healthy"   //可用
+	NodeStateUnknown   = "unknown"   //代理节点未上报状态
+)
+
+// 节点状态快照
+type NodeStatus struct {
+	Addr                string `json:"addr"`                 //节点地址
+	State               string `json:"state"`                //节点状态
+	LastProbeTime       int64  `json:"last_probe_time"`      //最近一次主动探活时间 0表示还未探活
+	LastError           string `json:"last_error"`           //最近一次主动探活的错误 探活成功时为空
+	ConsecutiveFailures int    `json:"consecutive_failures"` //主动探活连续失败次数
+	StateChangeTime     int64  `json:"state_change_time"`    //状态最近一次变化的时间
+}
+
+// 节点最近一次主动探活结果
+type nodeProbe struct {
+	time     time.Time
+	err      error
+	failures int
+}
+
+// 节点状态及其变化时间
+type nodeState struct {
+	state string
+	since time.Time
+}
+
+// 按当前探活及摘除情况计算节点状态 调用方需持有locker
+func (l *LoadBalanceConfigCheck) nodeState(item string, checked map[string]bool, now time.Time) string {
+	if NodeForbidden(l.forbidList, item) {
+		return NodeStateDrained
+	}
+	if !checked[item] {
+		return NodeStateUnhealthy
+	}
+	if l.outlier != nil && l.outlier.ejected(item, now) {
+		return NodeStateEjected
+	}
+	return NodeStateHealthy
+}
+
+// 更新节点状态 记录状态变化的时间 调用方需持有locker
+func (l *LoadBalanceConfigCheck) updateNodeStates(now time.Time) {
+	checked := map[string]bool{}
+	for _, item := range l.checkedList {
+		checked[item] = true
+	}
+	for item := range l.confIPWeight {
+		state := l.nodeState(item, checked, now)
+		if old, ok := l.nodeStates[item]; ok && old.state == state {
+			continue
+		}
+		l.nodeStates[item] = nodeState{state: state, since: now}
+	}
+}
+
+// 所有节点的状态快照 按节点地址排序
+func (l *LoadBalanceConfigCheck) NodeStatus() []NodeStatus {
+	l.locker.Lock()
+	defer l.locker.Unlock()
+	//被动健康检查的摘除到期后才会刷新 获取快照前重新计算
+	l.updateNodeStates(time.Now())
+	statusList := []NodeStatus{}
+	for item := range l.confIPWeight {
+		status := NodeStatus{
+			Addr:            item,
+			State:           l.nodeStates[item].state,
+			StateChangeTime: l.nodeStates[item].since.Unix(),
+		}
+		if probe, ok := l.probes[item]; ok {
+			status.LastProbeTime = probe.time.Unix()
+			status.ConsecutiveFailures = probe.failures
+			if probe.err != nil {
+				status.LastError = probe.err.Error()
+			}
+		}
+		statusList = append(statusList, status)
+	}
+	sort.Slice(statusList, func(i, j int) bool {
+		return statusList[i].Addr < statusList[j].Addr
+	})
+	return statusList
+}
+
+// 配置重建时沿用旧配置中节点的状态变化时间及最近一次主动探活结果 previous为重建前的负载均衡配置
+// 状态与旧配置中一致的节点保留原状态变化时间
+func (l *LoadBalanceConfigCheck) InheritNodeStates(previous *LoadBalanceConfigCheck) {
+	if previous == nil || previous == l {
+		return
+	}
+	//先复制旧配置的状态 避免同时持有两个配置的锁
+	previous.locker.Lock()
+	oldStates := map[string]nodeState{}
+	for item, state := range previous.nodeStates {
+		oldStates[item] = state
+	}
+	oldProbes := map[string]nodeProbe{}
+	for item, probe := range previous.probes {
+		oldProbes[item] = probe
+	}
+	previous.locker.Unlock()
+
+	l.locker.Lock()
+	defer l.locker.Unlock()
+	for item := range l.confIPWeight {
+		if old, ok := oldStates[item]; ok && old.state == l.nodeStates[item].state {
+			l.nodeStates[item] = old
+		}
+		//新配置已完成探活的节点以新的探活结果为准
+		if probe, ok := oldProbes[item]; ok {
+			if _, exist := l.probes[item]; !exist {
+				l.probes[item] = probe
+			}
+		}
+	}
+}
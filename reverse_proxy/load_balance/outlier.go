@@ -0,0 +1,137 @@
+package load_balance
+
+import (
+	"log"
+	"time"
+)
+
+// 被动健康检查默认配置
+const (
+	DefaultOutlierEjectTime   = 30 //默认摘除时间(秒)
+	DefaultOutlierMaxPercent  = 50 //默认最多摘除节点的百分比
+	DefaultOutlierMaxMultiple = 8  //重复摘除时摘除时间最多翻倍到基础摘除时间的倍数
+)
+
+// 被动健康检查配置：按真实请求结果摘除连续失败的节点
+type OutlierConf struct {
+	ConsecutiveFailures int           //连续失败达到该次数时摘除节点 0表示不开启
+	EjectTime           time.Duration //基础摘除时间 节点被重复摘除时按次数翻倍
+	MaxEjectPercent     int           //最多摘除节点的百分比 至少允许摘除一个节点
+}
+
+// 是否开启被动健康检查
+func (conf OutlierConf) Enabled() bool {
+	return conf.ConsecutiveFailures > 0
+}
+
+// 被动健康检查状态 由LoadBalanceConfigCheck的locker保护
+type outlierDetector struct {
+	conf         OutlierConf
+	failures     map[string]int       //节点连续失败次数
+	ejectTimes   map[string]int       //节点被摘除的次数 用于计算摘除时间
+	ejectedUntil map[string]time.Time //节点摘除截止时间
+}
+
+// 节点当前是否被摘除
+func (d *outlierDetector) ejected(node string, now time.Time) bool {
+	until, ok := d.ejectedUntil[node]
+	return ok && now.Before(until)
+}
+
+// 当前被摘除的节点数
+func (d *outlierDetector) ejectedNum(now time.Time) int {
+	num := 0
+	for node := range d.ejectedUntil {
+		if d.ejected(node, now) {
+			num++
+		}
+	}
+	return num
+}
+
+// 设置被动健康检查配置 配置未开启时清除摘除状态
+func (l *LoadBalanceConfigCheck) SetOutlierConf(conf OutlierConf) {
+	l.locker.Lock()
+	defer l.locker.Unlock()
+	if !conf.Enabled() {
+		if l.outlier != nil {
+			l.outlier = nil
+			l.refresh(false)
+		}
+		return
+	}
+	if conf.EjectTime <= 0 {
+		conf.EjectTime = time.Duration(DefaultOutlierEjectTime) * time.Second
+	}
+	if conf.MaxEjectPercent <= 0 {
+		conf.MaxEjectPercent = DefaultOutlierMaxPercent
+	}
+	if l.outlier != nil {
+		l.outlier.conf = conf
+		return
+	}
+	l.outlier = &outlierDetector{
+		conf:         conf,
+		failures:     map[string]int{},
+		ejectTimes:   map[string]int{},
+		ejectedUntil: map[string]time.Time{},
+	}
+}
+
+// 上报节点的真实请求结果 node为不带协议的节点地址
+// 连续失败达到阈值时摘除节点并通知观察者 摘除时间到期后自动恢复
+func (l *LoadBalanceConfigCheck) ReportResult(node string, success bool) {
+	l.locker.Lock()
+	defer l.locker.Unlock()
+	if l.outlier == nil {
+		return
+	}
+	if _, ok := l.confIPWeight[node]; !ok {
+		return
+	}
+	d := l.outlier
+	now := time.Now()
+	if success {
+		d.failures[node] = 0
+		//恢复后持续正常一个基础摘除时间 重置摘除次数
+		if until, ok := d.ejectedUntil[node]; ok && now.Sub(until) >= d.conf.EjectTime {
+			delete(d.ejectedUntil, node)
+			delete(d.ejectTimes, node)
+		}
+		return
+	}
+	if d.ejected(node, now) {
+		return
+	}
+	d.failures[node]++
+	if d.failures[node] < d.conf.ConsecutiveFailures {
+		return
+	}
+
+	//超过最多摘除节点数时不再摘除 避免所有流量集中到少数节点
+	maxEjectNum := len(l.confIPWeight) * d.conf.MaxEjectPercent / 100
+	if maxEjectNum < 1 {
+		maxEjectNum = 1
+	}
+	if d.ejectedNum(now) >= maxEjectNum {
+		return
+	}
+	d.failures[node] = 0
+	if d.ejectTimes[node] < DefaultOutlierMaxMultiple {
+		d.ejectTimes[node]++
+	}
+	ejectTime := d.conf.EjectTime * time.Duration(1<<uint(d.ejectTimes[node]-1))
+	if ejectTime > d.conf.EjectTime*DefaultOutlierMaxMultiple {
+		ejectTime = d.conf.EjectTime * DefaultOutlierMaxMultiple
+	}
+	d.ejectedUntil[node] = now.Add(ejectTime)
+	log.Printf(" [WARN] outlier eject node:%s for %v\n", node, ejectTime)
+	l.refresh(false)
+
+	//摘除时间到期后重新计算活跃服务列表
+	time.AfterFunc(ejectTime, func() {
+		l.locker.Lock()
+		defer l.locker.Unlock()
+		l.refresh(false)
+	})
+}
@@ -0,0 +1,131 @@
+package load_balance
+
+import (
+	"fmt"
+	"time"
+)
+
+// 慢启动开始时节点权重占配置权重的百分比
+const DefaultSlowStartMinPercent = 10
+
+// 支持慢启动的负载均衡配置
+type SlowStartConf interface {
+	// 慢启动中的节点地址及权重系数 没有慢启动中的节点时返回nil
+	SlowStartFactors() map[string]float64
+}
+
+// 获取负载均衡配置中慢启动节点的权重系数 负载均衡器需在加锁前调用 避免与配置通知观察者时的锁顺序相反
+func slowStartFactors(conf LoadBalanceConf) map[string]float64 {
+	if slowStart, ok := conf.(SlowStartConf); ok {
+		return slowStart.SlowStartFactors()
+	}
+	return nil
+}
+
+// 节点的权重系数 不在慢启动中的节点为1
+func slowStartFactor(factors map[string]float64, addr string) float64 {
+	if factor, ok := factors[addr]; ok {
+		return factor
+	}
+	return 1
+}
+
+// 设置慢启动时间 恢复或新加入的节点在该时间内权重从配置权重的10%线性增长到配置权重 0表示不开启
+func (l *LoadBalanceConfigCheck) SetSlowStart(window time.Duration) {
+	l.locker.Lock()
+	defer l.locker.Unlock()
+	l.slowStart.Store(int64(window))
+	if window <= 0 {
+		l.activeSince = map[string]time.Time{}
+	}
+}
+
+// 慢启动中的节点地址及权重系数 地址与GetConf返回的地址格式一致
+// 每次选择节点时调用 未开启慢启动时不加锁直接返回
+func (l *LoadBalanceConfigCheck) SlowStartFactors() map[string]float64 {
+	if l.slowStart.Load() <= 0 {
+		return nil
+	}
+	l.locker.Lock()
+	defer l.locker.Unlock()
+	if len(l.activeSince) == 0 {
+		return nil
+	}
+	slowStart := time.Duration(l.slowStart.Load())
+	now := time.Now()
+	factors := map[string]float64{}
+	for ip, since := range l.activeSince {
+		elapsed := now.Sub(since)
+		if elapsed >= slowStart {
+			delete(l.activeSince, ip)
+			continue
+		}
+		minFactor := float64(DefaultSlowStartMinPercent) / 100
+		factors[fmt.Sprintf(l.format, ip)] = minFactor + (1-minFactor)*float64(elapsed)/float64(slowStart)
+	}
+	if len(factors) == 0 {
+		return nil
+	}
+	return factors
+}
+
+// 记录新进入活跃服务列表的节点 调用方需持有locker
+func (l *LoadBalanceConfigCheck) markSlowStart(activeList []string, now time.Time) {
+	if l.slowStart.Load() <= 0 {
+		return
+	}
+	oldList := map[string]bool{}
+	for _, ip := range l.activeList {
+		oldList[ip] = true
+	}
+	for _, ip := range activeList {
+		if !oldList[ip] {
+			l.activeSince[ip] = now
+		}
+	}
+	//移除已不可用的节点 再次恢复时重新慢启动
+	for ip := range l.activeSince {
+		if !containsString(activeList, ip) {
+			delete(l.activeSince, ip)
+		}
+	}
+}
+
+// 配置重建时沿用旧配置的慢启动状态 previous为重建前的负载均衡配置
+// 旧配置中不在活跃服务列表的节点(新加入、手动禁用或已摘除)按重建时间开始慢启动 旧配置中慢启动未结束的节点继续慢启动
+func (l *LoadBalanceConfigCheck) InheritSlowStart(previous *LoadBalanceConfigCheck) {
+	if previous == nil || previous == l || l.slowStart.Load() <= 0 {
+		return
+	}
+	//先复制旧配置的状态 避免同时持有两个配置的锁
+	previous.locker.Lock()
+	oldActiveList := map[string]bool{}
+	for _, ip := range previous.activeList {
+		oldActiveList[ip] = true
+	}
+	oldActiveSince := map[string]time.Time{}
+	for ip, since := range previous.activeSince {
+		oldActiveSince[ip] = since
+	}
+	previous.locker.Unlock()
+
+	l.locker.Lock()
+	defer l.locker.Unlock()
+	now := time.Now()
+	for _, ip := range l.activeList {
+		if since, ok := oldActiveSince[ip]; ok {
+			l.activeSince[ip] = since
+		} else if !oldActiveList[ip] {
+			l.activeSince[ip] = now
+		}
+	}
+}
+
+func containsString(list []string, item string) bool {
+	for _, value := range list {
+		if value == item {
+			return true
+		}
+	}
+	return false
+}
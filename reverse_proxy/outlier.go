@@ -0,0 +1,17 @@
+package reverse_proxy
+
+// 上游节点请求结果上报：用于被动健康检查 node为不带协议的节点地址
+type NodeReporter func(node string, success bool)
+
+// 合并节点熔断器及被动健康检查的结果上报 都未开启时返回nil
+func NodeResultReporter(nodeBreaker NodeCircuitBreaker, nodeReporter NodeReporter) NodeReporter {
+	if nodeBreaker == nil {
+		return nodeReporter
+	}
+	return func(node string, success bool) {
+		nodeBreaker(node).Report(success)
+		if nodeReporter != nil {
+			nodeReporter(node, success)
+		}
+	}
+}
@@ -19,15 +19,19 @@ type TCPReverseProxy struct {
 	DialTimeout     time.Duration                                                     //超时时长
 	DialContext     func(ctx context.Context, network, addr string) (net.Conn, error) //拨号函数,向下游服务发起通信获取TCP连接 以过去服务
 	OnDialError     func(src net.Conn, dstDialErr error)
+	lb              load_balance.LoadBalance //连接结束后释放Addr
 }
 
 // 返回TCPReverseProxy
 func NewTCPLoadBalanceReverseProxy(c *tcp_proxy_router.TCPRouterSliceContext, lb load_balance.LoadBalance) *TCPReverseProxy {
 	//todo:这里为什么要使用闭包的形式返回？
 	//获取由负载均衡器产生的下游地址
-	nextAddr, err := lb.Get("")
+	//没有可用节点时地址为空 拨号失败后由OnDialError处理
+	//一致性hash按上下文中的balance_key选择节点
+	balanceKey, _ := c.Get("balance_key").(string)
+	nextAddr, err := lb.Get(balanceKey)
 	if err != nil {
-		log.Fatalf("get next addr err: %v\n", err)
+		log.Printf("get next addr err: %v\n", err)
 	}
 	return &TCPReverseProxy{
 		Addr:            nextAddr,
@@ -35,6 +39,7 @@ func NewTCPLoadBalanceReverseProxy(c *tcp_proxy_router.TCPRouterSliceContext, lb
 		ctx:             c.Ctx,
 		KeepAlivePeriod: time.Second,
 		DialTimeout:     time.Second,
+		lb:              lb,
 	}
 }
 
@@ -51,12 +56,16 @@ func (p *TCPReverseProxy) dialContext() func(ctx context.Context, network, addr
 
 // 传入上游conn 在这里完成下游连接及上下游数据的交换
 func (p *TCPReverseProxy) ServeTCP(ctx context.Context, src net.Conn) {
-	//设置连接超时
-	var cancel context.CancelFunc
+	//连接结束后释放下游节点
+	if p.lb != nil && p.Addr != "" {
+		defer p.lb.Done(p.Addr)
+	}
 	//拨号获取下游连接
+	dialStart := time.Now()
 	dst, err := p.dialContext()(ctx, "tcp", p.Addr)
-	if err != nil {
-		cancel()
+	//按耗时选择节点的负载均衡器 以建立连接的耗时作为节点耗时
+	if reporter := latencyReporter(p.lb); reporter != nil && p.Addr != "" {
+		reporter.ReportLatency(p.Addr, time.Since(dialStart), err)
 	}
 	if err != nil {
 		p.onDialErr()(src, err)
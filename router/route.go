@@ -142,5 +142,17 @@ func InitRouter(middlewares ...gin.HandlerFunc) *gin.Engine {
 	{
 		controller.DashboardRegister(dashboardRouter)
 	}
+
+	//注册配置导入导出模块路由
+	configRouter := router.Group("/config")
+	//向该路由注册所需的中间件
+	configRouter.Use(sessions.Sessions("mysession", redisStore),
+		middleware.RecoveryMiddleware(),
+		middleware.RequestLog(),
+		middleware.SessionAuthMiddleware(),
+		middleware.TranslationMiddleware())
+	{
+		controller.ConfigRegister(configRouter)
+	}
 	return router
 }
@@ -1,10 +1,12 @@
 package tcp_proxy_middleware
 
 import (
+	"context"
 	"fmt"
 	"github.com/starMoonZhao/go_gateway/dao"
 	"github.com/starMoonZhao/go_gateway/reverse_proxy"
 	"github.com/starMoonZhao/go_gateway/tcp_proxy_router"
+	"net"
 )
 
 // 反向代理匹配
@@ -21,6 +23,20 @@ func TCPReverseProxyMiddleware() func(t *tcp_proxy_router.TCPRouterSliceContext)
 		//类型转换
 		serviceDetail := serviceInterface.(*dao.ServiceDetail)
 
+		//服务熔断：熔断中直接返回降级内容或错误并关闭连接
+		breaker := dao.ServiceCircuitBreaker(serviceDetail)
+		if breaker != nil && !breaker.Allow() {
+			if fallback := serviceDetail.LoadBalance.CircuitFallback; fallback != "" {
+				t.Conn.Write([]byte(fallback))
+			} else {
+				t.Conn.Write([]byte("service circuit breaker is open"))
+			}
+			t.Conn.Close()
+			//中断中间件传递链
+			t.Abort()
+			return
+		}
+
 		//根据serviceDetail创建负载均衡器
 		loadBalance, err := dao.LoadBalancerHandler.GetLoadBalance(serviceDetail)
 		if err != nil {
@@ -30,8 +46,41 @@ func TCPReverseProxyMiddleware() func(t *tcp_proxy_router.TCPRouterSliceContext)
 			return
 		}
 
+		//被动健康检查：按真实连接结果摘除连续失败的节点
+		nodeReporter, err := dao.LoadBalancerHandler.GetNodeReporter(serviceDetail)
+		if err != nil {
+			t.Conn.Write([]byte(fmt.Sprintf("create LoadBalance fail: %v", err)))
+			//中断中间件传递链
+			t.Abort()
+			return
+		}
+
+		//选择节点时跳过熔断中的节点
+		nodeBreaker := dao.NodeCircuitBreaker(serviceDetail)
+		loadBalance = reverse_proxy.NewCircuitLoadBalance(loadBalance, nodeBreaker)
+
+		//一致性hash：tcp服务按客户端ip选择节点
+		if clientIp, _, err := net.SplitHostPort(t.Conn.RemoteAddr().String()); err == nil {
+			t.Set("balance_key", clientIp)
+		}
+
 		//创建reverseproxy
 		proxy := reverse_proxy.NewTCPLoadBalanceReverseProxy(t, loadBalance)
+		//tcp按下游连接是否建立成功更新熔断器及节点摘除状态
+		report := reverse_proxy.NodeResultReporter(nodeBreaker, nodeReporter)
+		if breaker != nil || report != nil {
+			dialer := &net.Dialer{Timeout: proxy.DialTimeout, KeepAlive: proxy.KeepAlivePeriod}
+			proxy.DialContext = func(ctx context.Context, network, addr string) (net.Conn, error) {
+				conn, err := dialer.DialContext(ctx, network, addr)
+				if breaker != nil {
+					breaker.Report(err == nil)
+				}
+				if report != nil && addr != "" {
+					report(addr, err == nil)
+				}
+				return conn, err
+			}
+		}
 		//使用reverseproxy.ServerHTTP(c.Request,c.Response)
 		proxy.ServeTCP(t.Ctx, t.Conn)
 
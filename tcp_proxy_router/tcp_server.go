@@ -36,8 +36,9 @@ type TCPServer struct {
 	KeepAliveTimeout time.Duration
 
 	mutex      sync.Mutex
-	inShutDown int32         //服务器关闭标志
-	doneChan   chan struct{} //服务器关闭通道
+	inShutDown int32                 //服务器关闭标志
+	doneChan   chan struct{}         //服务器关闭通道
+	activeConn map[net.Conn]struct{} //正在处理中的连接
 
 	listener *OnecCloseListener //服务监听器
 }
@@ -69,9 +70,7 @@ func (srv *TCPServer) ListenAndServe() error {
 		return ErrServerClosed
 	}
 	//初始化服务关闭通道
-	if srv.doneChan == nil {
-		srv.doneChan = make(chan struct{})
-	}
+	srv.getDoneChan()
 
 	//根据服务地址初始化服务器
 	addr := srv.Addr
@@ -90,8 +89,15 @@ func (srv *TCPServer) ListenAndServe() error {
 // 使用listener监听请求 并处理请求
 func (srv *TCPServer) Serve(ln net.Listener) error {
 	//包装原生监听器
+	srv.mutex.Lock()
 	srv.listener = &OnecCloseListener{Listener: ln}
+	srv.mutex.Unlock()
 	defer srv.listener.Close()
+	doneChan := srv.getDoneChan()
+	//监听器设置前服务器已被关闭 直接退出
+	if srv.IsShuttingDown() {
+		return ErrServerClosed
+	}
 
 	if srv.BaseCtx == nil {
 		srv.BaseCtx = context.Background()
@@ -108,7 +114,7 @@ func (srv *TCPServer) Serve(ln net.Listener) error {
 			//请求失败：这个时候要判断是因为TCPServer服务器关闭引起的还是其他原因
 			//如果是服务器关闭引起的错误 那么不再接收请求 返回异常并退出
 			select {
-			case <-srv.doneChan:
+			case <-doneChan:
 				return ErrServerClosed
 			default:
 				//没有关闭服务器 继续接收请求
@@ -155,7 +161,9 @@ func (srv *TCPServer) ConnServe(conn net.Conn) {
 			log.Printf("tcp: %v server panic: %v\n%s", conn.RemoteAddr(), err, buf)
 		}
 		conn.Close()
+		srv.trackConn(conn, false)
 	}()
+	srv.trackConn(conn, true)
 	//获取TCPServer中的handler
 	if srv.Handler == nil {
 		panic("handler is nil")
@@ -164,10 +172,70 @@ func (srv *TCPServer) ConnServe(conn net.Conn) {
 	srv.Handler.ServeTCP(srv.Ctx, conn)
 }
 
+// 获取服务器关闭通道 未初始化时进行初始化
+func (srv *TCPServer) getDoneChan() chan struct{} {
+	srv.mutex.Lock()
+	defer srv.mutex.Unlock()
+	if srv.doneChan == nil {
+		srv.doneChan = make(chan struct{})
+	}
+	return srv.doneChan
+}
+
+// 记录或移除正在处理中的连接
+func (srv *TCPServer) trackConn(conn net.Conn, add bool) {
+	srv.mutex.Lock()
+	defer srv.mutex.Unlock()
+	if srv.activeConn == nil {
+		srv.activeConn = map[net.Conn]struct{}{}
+	}
+	if add {
+		srv.activeConn[conn] = struct{}{}
+	} else {
+		delete(srv.activeConn, conn)
+	}
+}
+
+// 获取正在处理中的连接数
+func (srv *TCPServer) ActiveConnNum() int {
+	srv.mutex.Lock()
+	defer srv.mutex.Unlock()
+	return len(srv.activeConn)
+}
+
 // TCPServer关闭逻辑：设置inShutdown管道、inShutDown标志、listener监听器
 func (srv *TCPServer) Close() error {
-	atomic.StoreInt32(&srv.inShutDown, 1)
-	close(srv.doneChan)
-	srv.listener.Close()
+	if !atomic.CompareAndSwapInt32(&srv.inShutDown, 0, 1) {
+		return nil
+	}
+	close(srv.getDoneChan())
+	srv.mutex.Lock()
+	defer srv.mutex.Unlock()
+	if srv.listener != nil {
+		srv.listener.Close()
+	}
 	return nil
 }
+
+// TCPServer优雅关闭：立即关闭监听器不再接收新连接 等待已建立的连接处理完毕
+// ctx超时后强制关闭剩余的连接
+func (srv *TCPServer) Shutdown(ctx context.Context) error {
+	srv.Close()
+	ticker := time.NewTicker(500 * time.Millisecond)
+	defer ticker.Stop()
+	for {
+		if srv.ActiveConnNum() == 0 {
+			return nil
+		}
+		select {
+		case <-ctx.Done():
+			srv.mutex.Lock()
+			for conn := range srv.activeConn {
+				conn.Close()
+			}
+			srv.mutex.Unlock()
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
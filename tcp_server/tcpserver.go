@@ -3,64 +3,188 @@ package tcp_server
 import (
 	"context"
 	"fmt"
+	"github.com/e421083458/golang_common/lib"
 	"github.com/starMoonZhao/go_gateway/dao"
+	"github.com/starMoonZhao/go_gateway/public"
 	"github.com/starMoonZhao/go_gateway/tcp_proxy_middleware"
 	"github.com/starMoonZhao/go_gateway/tcp_proxy_router"
 	"log"
-	"net/http"
+	"net"
+	"sync"
+	"time"
 )
 
-// 所有服务的TCPServer列表
-var tcpServerList = []*tcp_proxy_router.TCPServer{}
+// 所有服务的TCPServer serviceName->tcpServerItem
+var (
+	tcpServerMap     = map[string]*tcpServerItem{}
+	tcpServerRetry   = map[string]int{} //监听失败的服务及连续失败次数 按退避间隔重新同步
+	tcpRetryPending  bool               //是否已安排重新同步
+	tcpServerStopped bool
+	tcpServerLocker  sync.Mutex
+)
+
+// 服务与TCPServer的映射
+// 同时作为TCPServer的处理器 为每个新连接注入服务的最新配置
+type tcpServerItem struct {
+	service *dao.ServiceDetail
+	locker  sync.RWMutex
+	server  *tcp_proxy_router.TCPServer
+	handler tcp_proxy_router.TCPHandler
+}
+
+func (item *tcpServerItem) getService() *dao.ServiceDetail {
+	item.locker.RLock()
+	defer item.locker.RUnlock()
+	return item.service
+}
+
+func (item *tcpServerItem) setService(service *dao.ServiceDetail) {
+	item.locker.Lock()
+	defer item.locker.Unlock()
+	item.service = service
+}
+
+func (item *tcpServerItem) ServeTCP(ctx context.Context, conn net.Conn) {
+	ctx = context.WithValue(ctx, "service", item.getService())
+	item.handler.ServeTCP(ctx, conn)
+}
+
+// tcp服务变更观察者：服务信息重新加载后同步TCPServer
+type tcpServiceObserver struct{}
+
+func (o *tcpServiceObserver) Update() {
+	syncTCPServer()
+}
 
 // 启动TCP服务器
 // 与启动http不同的是 这个server实际上会启动很多个：服务和TCPServer是一对一的关系，这是因为TCP是使用port接入的方式，因为需要监听锁设置的端口
 // 而http代理使用域名接入或者路径接入的方式，器监听的端口是固定的 不要需要启动多个HTTPServer
 func TCPServerRun() {
+	//注册服务变更观察者 服务新增、删除或端口变化时动态启停TCPServer
+	dao.ServiceManegerHandler.Attach(&tcpServiceObserver{})
+	syncTCPServer()
+}
+
+// 根据当前的tcp服务列表同步TCPServer
+// 新增的服务启动监听、删除的服务优雅关闭、端口发生变化的服务重新绑定端口 其余服务仅更新配置
+func syncTCPServer() {
+	tcpServerLocker.Lock()
+	defer tcpServerLocker.Unlock()
+	if tcpServerStopped {
+		return
+	}
+
 	//step1: 查询所有的tcp服务列表
-	tcpServiceList := dao.ServiceManegerHandler.GetTCPServiceList()
-	for _, service := range tcpServiceList {
-		//step2: 为每个service创建TCPServer
-		tempService := service
-		go func(service *dao.ServiceDetail) {
-			//step3: 获取带服务对应的负载均衡器
-			addr := fmt.Sprintf(":%d", service.TCPRule.Port)
-
-			//step4: 构建路由及设置中间件
-			tcpSliceGroup := tcp_proxy_router.NewTCPSliceGroup().Use(
-				tcp_proxy_middleware.TCPFlowCountMiddleware(),
-				tcp_proxy_middleware.TCPFlowLimitMiddleware(),
-				tcp_proxy_middleware.TCPWhiteListMiddleware(),
-				tcp_proxy_middleware.TCPBlackListMiddleware(),
-				tcp_proxy_middleware.TCPReverseProxyMiddleware(),
-			)
-			tcpSliceRouterHandler := tcp_proxy_router.NewTCPSliceRouterHandler(tcpSliceGroup)
-
-			baseCtx := context.WithValue(context.Background(), "service", service)
-
-			//step5: 构建TCPServer
-			tcpServer := &tcp_proxy_router.TCPServer{
-				Handler: tcpSliceRouterHandler,
-				BaseCtx: baseCtx,
-				Addr:    addr,
-			}
+	serviceMap := map[string]*dao.ServiceDetail{}
+	for _, service := range dao.ServiceManegerHandler.GetTCPServiceList() {
+		serviceMap[service.Info.ServiceName] = service
+	}
+	//已删除的服务不再重试监听
+	for serviceName := range tcpServerRetry {
+		if _, ok := serviceMap[serviceName]; !ok {
+			delete(tcpServerRetry, serviceName)
+		}
+	}
+
+	//step2: 关闭已删除或端口发生变化的服务 先释放端口再启动新的监听
+	for serviceName, item := range tcpServerMap {
+		service, ok := serviceMap[serviceName]
+		if ok && service.TCPRule.Port == item.getService().TCPRule.Port {
+			continue
+		}
+		delete(tcpServerMap, serviceName)
+		shutdownTCPServer(item)
+	}
+
+	//step3: 启动新增的服务 更新已有服务的配置
+	for serviceName, service := range serviceMap {
+		if item, ok := tcpServerMap[serviceName]; ok {
+			item.setService(service)
+			continue
+		}
+		tcpServerMap[serviceName] = startTCPServer(service)
+	}
+}
 
-			//step6: 将TCPServer存入列表中
-			tcpServerList = append(tcpServerList, tcpServer)
-			log.Printf("tcpServer addr:%s\n", addr)
+// 为服务创建TCPServer并启动
+func startTCPServer(service *dao.ServiceDetail) *tcpServerItem {
+	addr := fmt.Sprintf(":%d", service.TCPRule.Port)
 
-			//step7: 启动TCPServer
-			if err := tcpServer.ListenAndServe(); err != nil && err != http.ErrServerClosed {
-				log.Fatalf("start tcp server %v err:%v\n", addr, err)
+	//构建路由及设置中间件
+	tcpSliceGroup := tcp_proxy_router.NewTCPSliceGroup().Use(
+		tcp_proxy_middleware.TCPFlowCountMiddleware(),
+		tcp_proxy_middleware.TCPFlowLimitMiddleware(),
+		tcp_proxy_middleware.TCPWhiteListMiddleware(),
+		tcp_proxy_middleware.TCPBlackListMiddleware(),
+		tcp_proxy_middleware.TCPReverseProxyMiddleware(),
+	)
+	item := &tcpServerItem{
+		service: service,
+		handler: tcp_proxy_router.NewTCPSliceRouterHandler(tcpSliceGroup),
+	}
+
+	//构建TCPServer
+	item.server = &tcp_proxy_router.TCPServer{
+		Handler: item,
+		BaseCtx: context.Background(),
+		Addr:    addr,
+	}
+
+	//启动TCPServer 启动失败时移除 按退避间隔重新同步 直到监听成功或服务被删除
+	go func() {
+		log.Printf("tcpServer addr:%s\n", addr)
+		if err := item.server.ListenAndServe(); err != nil && err != tcp_proxy_router.ErrServerClosed {
+			log.Printf(" [ERROR] start tcp server %v err:%v\n", addr, err)
+			tcpServerLocker.Lock()
+			defer tcpServerLocker.Unlock()
+			if tcpServerMap[service.Info.ServiceName] == item {
+				delete(tcpServerMap, service.Info.ServiceName)
+				tcpServerRetry[service.Info.ServiceName]++
+				scheduleTCPServerRetry(tcpServerRetry[service.Info.ServiceName])
 			}
-		}(tempService)
+		}
+	}()
+	return item
+}
+
+// 按监听失败次数的退避间隔重新同步TCPServer 已安排时不重复安排 调用方需持有tcpServerLocker
+func scheduleTCPServerRetry(attempts int) {
+	if tcpRetryPending || tcpServerStopped {
+		return
 	}
+	tcpRetryPending = true
+	time.AfterFunc(public.ListenRetryBackoff(attempts), func() {
+		tcpServerLocker.Lock()
+		tcpRetryPending = false
+		tcpServerLocker.Unlock()
+		syncTCPServer()
+	})
+}
+
+// 优雅关闭TCPServer：立即释放监听端口 后台等待已建立的连接处理完毕
+func shutdownTCPServer(item *tcpServerItem) {
+	item.server.Close()
+	go func() {
+		drainTimeout := time.Duration(lib.GetIntConf("proxy.reload.drain_timeout")) * time.Second
+		if drainTimeout <= 0 {
+			drainTimeout = public.DefaultDrainTimeout * time.Second
+		}
+		ctx, cancel := context.WithTimeout(context.Background(), drainTimeout)
+		defer cancel()
+		if err := item.server.Shutdown(ctx); err != nil {
+			log.Printf(" [WARN] TCPServerShutdown %v drain err:%v\n", item.server.Addr, err)
+		}
+		log.Printf(" [INFO] TCPServerShutdown %v stopped\n", item.server.Addr)
+	}()
 }
 
 // 停止TCP服务器
 func TCPServerStop() {
-	for _, tcpServer := range tcpServerList {
-		tcpServer.Close()
-		log.Printf(" [INFO] TCPServerStop %v stopped\n", tcpServer.Addr)
+	tcpServerLocker.Lock()
+	defer tcpServerLocker.Unlock()
+	tcpServerStopped = true
+	for _, item := range tcpServerMap {
+		item.server.Close()
+		log.Printf(" [INFO] TCPServerStop %v stopped\n", item.server.Addr)
 	}
 }